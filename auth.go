@@ -0,0 +1,90 @@
+package rssreader
+
+import (
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// FeedAuth holds the credentials sent when fetching a private feed. Its
+// String method redacts them, so a FeedAuth can be logged safely.
+type FeedAuth struct {
+	// Username and Password are sent with HTTP Basic authentication.
+	Username string `json:",omitempty"`
+	Password string `json:",omitempty"`
+	// Token is sent as a bearer token in the Authorization header.
+	Token string `json:",omitempty"`
+	// Headers are added to the request, such as GitLab's PRIVATE-TOKEN.
+	// They are dropped when the feed redirects to another host.
+	Headers map[string]string `json:",omitempty"`
+	// Cookies are sent by name, such as the session of a paid newsletter.
+	Cookies map[string]string `json:",omitempty"`
+}
+
+// apply adds the credentials to req.
+func (a FeedAuth) apply(req *http.Request) {
+	if a.Username != "" || a.Password != "" {
+		req.SetBasicAuth(a.Username, a.Password)
+	}
+	if a.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+	}
+	for name, value := range a.Headers {
+		req.Header.Set(name, value)
+	}
+	for _, name := range slices.Sorted(maps.Keys(a.Cookies)) {
+		req.AddCookie(&http.Cookie{Name: name, Value: a.Cookies[name]})
+	}
+}
+
+// client returns a copy of c that drops the custom headers when following a
+// redirect to another host. net/http already does so for the Authorization
+// and Cookie headers, but forwards all others.
+func (a FeedAuth) client(c *http.Client) *http.Client {
+	if len(a.Headers) == 0 {
+		return c
+	}
+	guarded := *c
+	checkRedirect := c.CheckRedirect
+	guarded.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if req.URL.Host != via[0].URL.Host {
+			for name := range a.Headers {
+				req.Header.Del(name)
+			}
+		}
+		if checkRedirect != nil {
+			return checkRedirect(req, via)
+		}
+		// The default policy of http.Client
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &guarded
+}
+
+// String describes which credentials are set without revealing them.
+func (a FeedAuth) String() string {
+	var parts []string
+	if a.Username != "" || a.Password != "" {
+		parts = append(parts, "basic "+a.Username+":[REDACTED]")
+	}
+	if a.Token != "" {
+		parts = append(parts, "bearer [REDACTED]")
+	}
+	for _, name := range slices.Sorted(maps.Keys(a.Headers)) {
+		parts = append(parts, "header "+name+": [REDACTED]")
+	}
+	for _, name := range slices.Sorted(maps.Keys(a.Cookies)) {
+		parts = append(parts, "cookie "+name+"=[REDACTED]")
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// GoString redacts the credentials for the %#v verb as well.
+func (a FeedAuth) GoString() string {
+	return "rssreader.FeedAuth" + a.String()
+}
@@ -0,0 +1,127 @@
+package rssreader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// authServer serves testdata/rss2.xml to requests that carry the expected
+// credential and records the headers of each request.
+func authServer(t *testing.T, authorized func(*http.Request) bool) (*httptest.Server, *[]http.Header) {
+	t.Helper()
+	feed := readTestdata(t, "rss2.xml")
+	var headers []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Clone())
+		if !authorized(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(feed))
+	}))
+	t.Cleanup(server.Close)
+	return server, &headers
+}
+
+func TestWithFeedAuth(t *testing.T) {
+	tests := map[string]struct {
+		auth       FeedAuth
+		authorized func(*http.Request) bool
+	}{
+		"basic": {
+			auth: FeedAuth{Username: "alice", Password: "s3cret"},
+			authorized: func(r *http.Request) bool {
+				user, password, ok := r.BasicAuth()
+				return ok && user == "alice" && password == "s3cret"
+			},
+		},
+		"bearer": {
+			auth:       FeedAuth{Token: "t0ken"},
+			authorized: func(r *http.Request) bool { return r.Header.Get("Authorization") == "Bearer t0ken" },
+		},
+		"header": {
+			auth:       FeedAuth{Headers: map[string]string{"PRIVATE-TOKEN": "glpat-123"}},
+			authorized: func(r *http.Request) bool { return r.Header.Get("PRIVATE-TOKEN") == "glpat-123" },
+		},
+		"cookie": {
+			auth: FeedAuth{Cookies: map[string]string{"session": "abc"}},
+			authorized: func(r *http.Request) bool {
+				cookie, err := r.Cookie("session")
+				return err == nil && cookie.Value == "abc"
+			},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			server, _ := authServer(t, tt.authorized)
+
+			if _, err := Parse(context.Background(), []string{server.URL}); err == nil {
+				t.Error("Expected the feed to require credentials")
+			}
+			items, err := Parse(context.Background(), []string{server.URL}, WithFeedAuth(server.URL, tt.auth))
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if len(items) == 0 {
+				t.Error("Expected items from the private feed")
+			}
+		})
+	}
+}
+
+func TestWithFeedAuth_OnlyForItsFeed(t *testing.T) {
+	server, headers := authServer(t, func(*http.Request) bool { return true })
+
+	auth := FeedAuth{Token: "t0ken", Headers: map[string]string{"X-Api-Key": "k3y"}}
+	if _, err := Parse(context.Background(), []string{server.URL + "/public"}, WithFeedAuth(server.URL+"/private", auth)); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if h := (*headers)[0]; h.Get("Authorization") != "" || h.Get("X-Api-Key") != "" {
+		t.Errorf("Expected no credentials for other feeds, got %v", h)
+	}
+}
+
+func TestWithFeedAuth_DropsHeadersOnCrossHostRedirect(t *testing.T) {
+	other, headers := authServer(t, func(*http.Request) bool { return true })
+	// localhost and 127.0.0.1 are different hosts to the client
+	target := strings.Replace(other.URL, "127.0.0.1", "localhost", 1) + "/feed"
+	origin := httptest.NewServer(http.RedirectHandler(target, http.StatusFound))
+	defer origin.Close()
+
+	auth := FeedAuth{Token: "t0ken", Headers: map[string]string{"X-Api-Key": "k3y"}, Cookies: map[string]string{"session": "abc"}}
+	if _, err := Parse(context.Background(), []string{origin.URL}, WithFeedAuth(origin.URL, auth)); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	h := (*headers)[0]
+	for _, name := range []string{"Authorization", "X-Api-Key", "Cookie"} {
+		if h.Get(name) != "" {
+			t.Errorf("Expected %s not to be forwarded to another host, got %q", name, h.Get(name))
+		}
+	}
+}
+
+func TestFeedAuth_Redacted(t *testing.T) {
+	auth := FeedAuth{
+		Username: "alice",
+		Password: "s3cret",
+		Token:    "t0ken",
+		Headers:  map[string]string{"PRIVATE-TOKEN": "glpat-123"},
+		Cookies:  map[string]string{"session": "abc"},
+	}
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		got := fmt.Sprintf(format, auth)
+		for _, secret := range []string{"s3cret", "t0ken", "glpat-123", "abc"} {
+			if strings.Contains(got, secret) {
+				t.Errorf("Expected %s to redact %q, got %s", format, secret, got)
+			}
+		}
+		if !strings.Contains(got, "PRIVATE-TOKEN") {
+			t.Errorf("Expected %s to name the header, got %s", format, got)
+		}
+	}
+}
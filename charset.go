@@ -0,0 +1,257 @@
+package rssreader
+
+import (
+	"bytes"
+	"math"
+	"mime"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	xunicode "golang.org/x/text/encoding/unicode"
+)
+
+var (
+	// xmlEncoding matches the encoding attribute of an XML declaration.
+	xmlEncoding = regexp.MustCompile(`^(\s*<\?xml[^>]*?\sencoding\s*=\s*["'])([^"']*)(["'])`)
+	// feedLanguage matches the language of RSS channels and Atom feeds.
+	feedLanguage = regexp.MustCompile(`(?i)<language>\s*([a-z]{2})|xml:lang\s*=\s*["']([a-z]{2})`)
+)
+
+// legacyEncodings are the encodings tried when a feed is not UTF-8 and its
+// labels don't fit. Ties go to the earlier encoding.
+var legacyEncodings = []encoding.Encoding{
+	charmap.Windows1252,
+	charmap.Windows1251,
+	charmap.KOI8R,
+	charmap.Windows1250,
+	charmap.Windows1253,
+	japanese.ShiftJIS,
+	japanese.EUCJP,
+	simplifiedchinese.GB18030,
+	korean.EUCKR,
+	traditionalchinese.Big5,
+}
+
+// languageEncodings are tried first for feeds in these languages, since
+// the CJK encodings cannot be told apart by their bytes alone.
+var languageEncodings = map[string][]encoding.Encoding{
+	"ja": {japanese.ShiftJIS, japanese.EUCJP},
+	"zh": {simplifiedchinese.GB18030, traditionalchinese.Big5},
+	"ko": {korean.EUCKR},
+	"ru": {charmap.Windows1251, charmap.KOI8R},
+	"uk": {charmap.Windows1251, charmap.KOI8U},
+	"el": {charmap.Windows1253},
+}
+
+// toUTF8 converts an XML feed to UTF-8 and updates its XML declaration to
+// match, since feeds in legacy encodings often declare the wrong charset
+// or none at all. In order, it trusts
+//   - a byte order mark, or the UTF-16 encoding of "<"
+//   - valid UTF-8, whatever the labels say
+//   - the charset of contentType or the XML declaration, unless another
+//     legacy encoding decodes the feed to far more plausible text
+//   - the legacy encoding that decodes the feed to the most plausible
+//     text, preferring those of the feed's language
+//
+// JSON feeds, which are always UTF-8, and documents that cannot be decoded
+// are returned unchanged.
+func toUTF8(data []byte, contentType string) []byte {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xef\xbb\xbf")):
+		return withUTF8Declaration(data[3:])
+	case bytes.HasPrefix(data, []byte("\xfe\xff")), bytes.HasPrefix(data, []byte("\x00<")):
+		return decodeUTF16(data, xunicode.BigEndian)
+	case bytes.HasPrefix(data, []byte("\xff\xfe")), bytes.HasPrefix(data, []byte("<\x00")):
+		return decodeUTF16(data, xunicode.LittleEndian)
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return data
+	}
+
+	labels := charsetLabels(data, contentType)
+	if utf8.Valid(data) {
+		// ISO-2022-JP shifts into Japanese with escape sequences in ASCII
+		if slices.Contains(labels, japanese.ISO2022JP) && isASCII(data) {
+			if decoded, ok := decodeWith(data, japanese.ISO2022JP); ok {
+				return withUTF8Declaration(decoded)
+			}
+		}
+		return withUTF8Declaration(data)
+	}
+
+	candidates := slices.Clone(labels)
+	if match := feedLanguage.FindSubmatch(data); match != nil {
+		language := strings.ToLower(string(match[1]) + string(match[2]))
+		candidates = append(candidates, languageEncodings[language]...)
+	}
+	candidates = append(candidates, legacyEncodings...)
+
+	decoded := make([][]byte, len(candidates))
+	scores := make([]int, len(candidates))
+	best := -1
+	for i, enc := range candidates {
+		var ok bool
+		if decoded[i], ok = decodeWith(data, enc); !ok {
+			scores[i] = math.MinInt
+			continue
+		}
+		// Ties go to the earlier candidate
+		if scores[i] = textScore(decoded[i]); best < 0 || scores[i] > scores[best] {
+			best = i
+		}
+	}
+	if best < 0 {
+		return data
+	}
+	// A label is trusted unless another encoding reads much better
+	for i := range labels {
+		if scores[i] >= 0 && scores[i] >= scores[best]/2 {
+			return withUTF8Declaration(decoded[i])
+		}
+	}
+	return withUTF8Declaration(decoded[best])
+}
+
+// charsetLabels returns the encodings named by contentType and the XML
+// declaration of data, leaving out unknown labels, UTF-8, and UTF-16, which
+// toUTF8 detects by itself and would otherwise decode anything.
+func charsetLabels(data []byte, contentType string) []encoding.Encoding {
+	var names []string
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		names = append(names, params["charset"])
+	}
+	if match := xmlEncoding.FindSubmatch(data); match != nil {
+		names = append(names, string(match[2]))
+	}
+
+	var labels []encoding.Encoding
+	for _, name := range names {
+		enc, err := htmlindex.Get(name)
+		if err != nil || enc == xunicode.UTF8 || enc == xunicode.UTF16(xunicode.LittleEndian, xunicode.IgnoreBOM) ||
+			enc == xunicode.UTF16(xunicode.BigEndian, xunicode.IgnoreBOM) {
+			continue
+		}
+		labels = append(labels, enc)
+	}
+	return labels
+}
+
+// decodeWith decodes data with enc, reporting false if data is not valid
+// in enc.
+func decodeWith(data []byte, enc encoding.Encoding) ([]byte, bool) {
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil || bytes.ContainsRune(decoded, utf8.RuneError) {
+		return nil, false
+	}
+	return decoded, true
+}
+
+func decodeUTF16(data []byte, endianness xunicode.Endianness) []byte {
+	// A BOM overrides the endianness and is dropped
+	decoded, err := xunicode.UTF16(endianness, xunicode.UseBOM).NewDecoder().Bytes(data)
+	if err != nil {
+		return data
+	}
+	return withUTF8Declaration(decoded)
+}
+
+// withUTF8Declaration updates the encoding of the XML declaration of data,
+// which is UTF-8, so the parser doesn't decode it a second time.
+func withUTF8Declaration(data []byte) []byte {
+	return xmlEncoding.ReplaceAll(data, []byte("${1}UTF-8${3}"))
+}
+
+func isASCII(data []byte) bool {
+	for _, b := range data {
+		if b >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// textScore rates how plausible decoded text is, scoring about one point
+// per byte of letters. The text of a feed decoded with the wrong encoding
+// shows up as control characters, stray symbols, capitals in the middle of
+// words, runs of accented letters and words that mix scripts, such as
+// Cyrillic letters among Latin ones.
+func textScore(text []byte) int {
+	score := 0
+	accented := 0 // accented Latin letters in a row
+	var prev rune
+	for _, r := range string(text) {
+		if r >= utf8.RuneSelf && unicode.Is(unicode.Latin, r) {
+			accented++
+		} else {
+			accented = 0
+		}
+
+		switch {
+		case r < utf8.RuneSelf:
+			if unicode.IsLetter(r) && unicode.IsLetter(prev) && !sameScript(prev, r) {
+				score -= 2
+			}
+		case r <= 0x9f || r == 0xfffd:
+			// C1 control characters never appear in real text
+			return math.MinInt
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) && !isHalfwidthKatakana(r),
+			isCJKPunctuation(r):
+			// One character takes two bytes
+			score += 2
+		case unicode.IsLetter(r):
+			switch {
+			case unicode.IsLetter(prev) && unicode.IsUpper(r) && !unicode.IsUpper(prev):
+				score -= 2
+			case unicode.IsLetter(prev) && !sameScript(prev, r):
+				score -= 2
+			case accented > 2:
+				// Latin words rarely have more than two accented letters in a row
+				score -= 2
+			default:
+				score++
+			}
+		case unicode.IsSpace(r), strings.ContainsRune("«»‹›„“”‘’‚–—…•·°€№©®™§", r):
+		default:
+			score -= 2
+		}
+		prev = r
+	}
+	return score
+}
+
+// isCJKPunctuation reports whether r is a CJK symbol or punctuation mark,
+// including the katakana middle dot and prolonged sound mark, or a
+// fullwidth form of an ASCII character.
+func isCJKPunctuation(r rune) bool {
+	return (r >= 0x3000 && r <= 0x303f) || r == 0x30fb || r == 0x30fc || (r >= 0xff01 && r <= 0xff60)
+}
+
+// isHalfwidthKatakana reports whether r is a halfwidth katakana, which
+// Shift_JIS decodes most bytes of other CJK encodings to.
+func isHalfwidthKatakana(r rune) bool {
+	return r >= 0xff61 && r <= 0xff9f
+}
+
+// scripts are the alphabetic scripts letters are checked against.
+var scripts = []*unicode.RangeTable{unicode.Latin, unicode.Cyrillic, unicode.Greek}
+
+// sameScript reports whether a and b belong to the same alphabetic script.
+// Letters outside of these scripts match any other.
+func sameScript(a, b rune) bool {
+	for _, script := range scripts {
+		if unicode.Is(script, a) || unicode.Is(script, b) {
+			return unicode.Is(script, a) && unicode.Is(script, b)
+		}
+	}
+	return true
+}
@@ -0,0 +1,137 @@
+package rssreader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	xunicode "golang.org/x/text/encoding/unicode"
+)
+
+// encodedFeed returns an RSS feed with the given declaration, language, title
+// and description, encoded with enc.
+func encodedFeed(t *testing.T, enc encoding.Encoding, declaration, language, title, description string) []byte {
+	t.Helper()
+	feed := declaration + `<rss version="2.0"><channel><title>Feed</title><language>` + language + `</language>` +
+		`<item><title>` + title + `</title><link>https://example.com/1</link><description>` + description + `</description></item>` +
+		`</channel></rss>`
+	data, err := enc.NewEncoder().Bytes([]byte(feed))
+	if err != nil {
+		t.Fatalf("Encoding the feed: %v", err)
+	}
+	return data
+}
+
+var charsetTexts = map[string]struct {
+	enc                encoding.Encoding
+	language           string
+	title, description string
+}{
+	"windows-1251": {charmap.Windows1251, "", "Новости недели", "Правительство объявило о новых мерах поддержки малого бизнеса."},
+	"koi8-r":       {charmap.KOI8R, "", "Погода на завтра", "Ожидается небольшой снег, температура около нуля градусов."},
+	"windows-1252": {charmap.Windows1252, "", "Café crème à Noël", "Les élèves ont préparé une fête très réussie — bravo à tous."},
+	"iso-8859-2":   {charmap.ISO8859_2, "", "Żółta łódź", "Zażółć gęślą jaźń, powiedział pan Łukasz."},
+	"shift_jis":    {japanese.ShiftJIS, "", "東京の天気", "今日は晴れのち曇り、夕方から雨が降るでしょう。"},
+	"euc-jp":       {japanese.EUCJP, "", "新しいリリース", "バージョン二・〇では多くの機能が追加されました。"},
+	"gbk":          {simplifiedchinese.GBK, "zh-cn", "新闻摘要", "今天的主要新闻包括经济发展和科技创新。"},
+	"euc-kr":       {korean.EUCKR, "ko", "오늘의 뉴스", "새로운 정책이 발표되었습니다."},
+}
+
+func TestToUTF8_MislabeledFeeds(t *testing.T) {
+	declarations := map[string]string{
+		"no declaration":     `<?xml version="1.0"?>`,
+		"utf-8 declaration":  `<?xml version="1.0" encoding="UTF-8"?>`,
+		"wrong declaration":  `<?xml version="1.0" encoding="ISO-8859-1"?>`,
+		"missing xml prolog": ``,
+	}
+	for name, text := range charsetTexts {
+		for label, declaration := range declarations {
+			if name == "iso-8859-2" && label != "no declaration" {
+				// Latin-2 and Windows-1252 overlap too much to detect
+				continue
+			}
+			t.Run(name+"/"+label, func(t *testing.T) {
+				data := encodedFeed(t, text.enc, declaration, text.language, text.title, text.description)
+				if name == "iso-8859-2" {
+					data = encodedFeed(t, text.enc, `<?xml version="1.0" encoding="ISO-8859-2"?>`, "", text.title, text.description)
+				}
+				items, err := ParseReader(context.Background(), strings.NewReader(string(data)), "feed")
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if items[0].Title != text.title || items[0].Description != text.description {
+					t.Errorf("Expected %q / %q, got %q / %q", text.title, text.description, items[0].Title, items[0].Description)
+				}
+			})
+		}
+	}
+}
+
+func TestToUTF8_LabelledUTF8(t *testing.T) {
+	// UTF-8 content wins over a legacy declaration
+	feed := encodedFeed(t, xunicode.UTF8, `<?xml version="1.0" encoding="windows-1251"?>`, "", "Привет, мир", "Всё хорошо")
+	items, err := ParseReader(context.Background(), strings.NewReader(string(feed)), "feed")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if items[0].Title != "Привет, мир" {
+		t.Errorf("Expected the UTF-8 title, got %q", items[0].Title)
+	}
+}
+
+func TestToUTF8_ByteOrderMarks(t *testing.T) {
+	encodings := map[string]encoding.Encoding{
+		"utf-8 bom":    xunicode.UTF8BOM,
+		"utf-16le bom": xunicode.UTF16(xunicode.LittleEndian, xunicode.UseBOM),
+		"utf-16be bom": xunicode.UTF16(xunicode.BigEndian, xunicode.UseBOM),
+		"utf-16be":     xunicode.UTF16(xunicode.BigEndian, xunicode.IgnoreBOM),
+	}
+	for name, enc := range encodings {
+		t.Run(name, func(t *testing.T) {
+			feed := encodedFeed(t, enc, `<?xml version="1.0" encoding="UTF-16"?>`, "", "Grüße aus Köln", "Schöne Grüße")
+			items, err := ParseReader(context.Background(), strings.NewReader(string(feed)), "feed")
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if items[0].Title != "Grüße aus Köln" {
+				t.Errorf("Expected the decoded title, got %q", items[0].Title)
+			}
+		})
+	}
+}
+
+func TestToUTF8_ContentTypeCharset(t *testing.T) {
+	// Without the HTTP charset, these bytes would read as windows-1252
+	feed := encodedFeed(t, charmap.ISO8859_2, `<?xml version="1.0"?>`, "", "Żółta łódź", "Zażółć gęślą jaźń")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml; charset=ISO-8859-2")
+		_, _ = w.Write(feed)
+	}))
+	defer server.Close()
+
+	items, err := Parse(context.Background(), []string{server.URL})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if items[0].Title != "Żółta łódź" {
+		t.Errorf("Expected the title decoded with the HTTP charset, got %q", items[0].Title)
+	}
+}
+
+func TestToUTF8_ISO2022JP(t *testing.T) {
+	feed := encodedFeed(t, japanese.ISO2022JP, `<?xml version="1.0" encoding="ISO-2022-JP"?>`, "ja", "お知らせ", "メンテナンスのお知らせです。")
+	items, err := ParseReader(context.Background(), strings.NewReader(string(feed)), "feed")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if items[0].Title != "お知らせ" {
+		t.Errorf("Expected the decoded title, got %q", items[0].Title)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+)
+
+// config is the format of the -config file, which holds per-feed settings
+// that don't fit on the command line.
+type config struct {
+	Feeds []feedConfig
+}
+
+// feedConfig configures a single feed. Feeds listed in the config file are
+// fetched in addition to those in -urls.
+type feedConfig struct {
+	URL string
+	// Scrape makes URL an HTML page that is scraped with CSS selectors.
+	Scrape *rssreader.ScrapedSource `json:",omitempty"`
+	// Auth holds the credentials of a private feed. Values may reference
+	// environment variables as $NAME or ${NAME}, so secrets need not be
+	// stored in the file; $$ stands for a literal $.
+	Auth *rssreader.FeedAuth `json:",omitempty"`
+}
+
+// loadConfig reads the config file at path. An empty path yields an empty
+// config.
+func loadConfig(path string) (*config, error) {
+	cfg := &config{}
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- the config path is supplied by the user on purpose
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("decoding config %s: %w", path, err)
+	}
+
+	seen := map[string]bool{}
+	for i, feed := range cfg.Feeds {
+		if feed.URL == "" {
+			return nil, fmt.Errorf("config %s: feed %d has no URL", path, i+1)
+		}
+		if seen[feed.URL] {
+			return nil, fmt.Errorf("config %s: feed %s is listed twice", path, feed.URL)
+		}
+		seen[feed.URL] = true
+		if feed.Scrape != nil && feed.Scrape.Item == "" {
+			return nil, fmt.Errorf("config %s: scraped feed %s has no Item selector", path, feed.URL)
+		}
+		if feed.Auth != nil {
+			if err := expandAuth(feed.Auth); err != nil {
+				return nil, fmt.Errorf("config %s: feed %s: %w", path, feed.URL, err)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// expandAuth replaces the environment variable references in the values of
+// auth. Errors name the variables but never the values.
+func expandAuth(auth *rssreader.FeedAuth) error {
+	if auth.Token != "" && (auth.Username != "" || auth.Password != "") {
+		return errors.New("auth sets both a token and a username or password")
+	}
+
+	var missing []string
+	expand := func(value string) string {
+		return os.Expand(value, func(name string) string {
+			if name == "$" {
+				return "$"
+			}
+			value, ok := os.LookupEnv(name)
+			if !ok && !slices.Contains(missing, name) {
+				missing = append(missing, name)
+			}
+			return value
+		})
+	}
+	auth.Username = expand(auth.Username)
+	auth.Password = expand(auth.Password)
+	auth.Token = expand(auth.Token)
+	for name, value := range auth.Headers {
+		auth.Headers[name] = expand(value)
+	}
+	for name, value := range auth.Cookies {
+		auth.Cookies[name] = expand(value)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("auth references unset environment variables: $%s", strings.Join(missing, ", $"))
+	}
+	return nil
+}
+
+// urls returns the -urls list followed by the config feeds it lacks.
+func (c *config) urls(list []string) []string {
+	urls := slices.Clone(list)
+	for _, feed := range c.Feeds {
+		if !slices.Contains(urls, feed.URL) {
+			urls = append(urls, feed.URL)
+		}
+	}
+	return urls
+}
+
+// options turns the per-feed settings into Parse options.
+func (c *config) options() []rssreader.Option {
+	var opts []rssreader.Option
+	for _, feed := range c.Feeds {
+		if feed.Scrape != nil {
+			opts = append(opts, rssreader.WithScrapedSource(feed.URL, *feed.Scrape))
+		}
+		if feed.Auth != nil {
+			opts = append(opts, rssreader.WithFeedAuth(feed.URL, *feed.Auth))
+		}
+	}
+	return opts
+}
+
+// parseFunc returns rssreader.Parse configured with the config's options
+// followed by extra.
+func (c *config) parseFunc(extra ...rssreader.Option) parseFunc {
+	opts := append(c.options(), extra...)
+	return func(ctx context.Context, urls []string) ([]rssreader.RssItem, error) {
+		return rssreader.Parse(ctx, urls, opts...)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig_ScrapedSource(t *testing.T) {
+	path := writeConfig(t, `{
+  "Feeds": [
+    {"URL": "../../testdata/rss2.xml"},
+    {"URL": "../../testdata/scrape.html", "Scrape": {"Name": "Council", "Item": "article.news-item", "Date": "time, .date"}}
+  ]
+}`)
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	urls := cfg.urls([]string{"../../testdata/rss2.xml"})
+	if !slices.Equal(urls, []string{"../../testdata/rss2.xml", "../../testdata/scrape.html"}) {
+		t.Errorf("Expected config feeds added to -urls once, got %v", urls)
+	}
+
+	items, err := cfg.parseFunc()(context.Background(), urls)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	sources := map[string]int{}
+	for _, item := range items {
+		sources[item.Source]++
+	}
+	if sources["Council"] != 2 || sources["Archived Feed"] != 2 {
+		t.Errorf("Expected scraped and feed items, got %v", sources)
+	}
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	tests := map[string]string{
+		"not json":         `{"Feeds": [`,
+		"has no URL":       `{"Feeds": [{"Scrape": {"Item": "li"}}]}`,
+		"is listed twice":  `{"Feeds": [{"URL": "http://a.test/"}, {"URL": "http://a.test/"}]}`,
+		"no Item selector": `{"Feeds": [{"URL": "http://a.test/", "Scrape": {"Title": "h2"}}]}`,
+	}
+	for want, content := range tests {
+		_, err := loadConfig(writeConfig(t, content))
+		if err == nil {
+			t.Errorf("Expected an error for %s", want)
+		} else if want != "not json" && !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error containing %q, got: %v", want, err)
+		}
+	}
+
+	if cfg, err := loadConfig(""); err != nil || len(cfg.Feeds) != 0 {
+		t.Errorf("Expected an empty config without a path, got %+v, %v", cfg, err)
+	}
+}
+
+func TestLoadConfig_AuthFromEnvironment(t *testing.T) {
+	t.Setenv("RSSREADER_TEST_TOKEN", "glpat-123")
+	path := writeConfig(t, `{
+  "Feeds": [
+    {"URL": "https://gitlab.test/feed.atom", "Auth": {"Headers": {"PRIVATE-TOKEN": "${RSSREADER_TEST_TOKEN}"}}},
+    {"URL": "https://news.test/feed", "Auth": {"Username": "alice", "Password": "pa$$word"}}
+  ]
+}`)
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got := cfg.Feeds[0].Auth.Headers["PRIVATE-TOKEN"]; got != "glpat-123" {
+		t.Errorf("Expected the token from the environment, got %q", got)
+	}
+	if got := cfg.Feeds[1].Auth.Password; got != "pa$word" {
+		t.Errorf("Expected $$ to stand for $, got %q", got)
+	}
+}
+
+func TestLoadConfig_AuthErrorsHideSecrets(t *testing.T) {
+	path := writeConfig(t, `{"Feeds": [{"URL": "https://news.test/feed", "Auth": {"Username": "alice", "Password": "s3cret-$RSSREADER_TEST_UNSET"}}]}`)
+	_, err := loadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "$RSSREADER_TEST_UNSET") {
+		t.Fatalf("Expected an error naming the unset variable, got: %v", err)
+	}
+	if strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("Expected the error not to reveal the password, got: %v", err)
+	}
+
+	path = writeConfig(t, `{"Feeds": [{"URL": "https://news.test/feed", "Auth": {"Username": "alice", "Token": "t0ken"}}]}`)
+	if _, err := loadConfig(path); err == nil || strings.Contains(err.Error(), "t0ken") {
+		t.Errorf("Expected an error without the token for mixed credentials, got: %v", err)
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+	"golang.org/x/net/html"
+)
+
+// digestOptions controls how the static HTML digest is laid out.
+type digestOptions struct {
+	Title     string
+	PageSize  int            // items per page, 0 puts everything on one page
+	Location  *time.Location // time zone used to group items by day
+	Generated time.Time
+}
+
+// digestPage is the data rendered into a single HTML file of the digest.
+type digestPage struct {
+	Title     string
+	Number    int
+	Pages     []digestPageLink
+	Prev      string
+	Next      string
+	Days      []digestDay
+	Generated time.Time
+}
+
+// digestPageLink points at one file of a paginated digest.
+type digestPageLink struct {
+	Number  int
+	File    string
+	Current bool
+}
+
+// digestDay holds the items published on one calendar day, grouped by source.
+type digestDay struct {
+	Label   string
+	Sources []rssreader.SourceGroup
+}
+
+// digestFileName returns the file name of the n-th page (1-based).
+func digestFileName(n int) string {
+	if n == 1 {
+		return "index.html"
+	}
+	return fmt.Sprintf("page-%d.html", n)
+}
+
+// buildDigestPages splits items into pages, keeping the order produced by
+// Parse, and groups each page by day and source.
+func buildDigestPages(items []rssreader.RssItem, opts digestOptions) []digestPage {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	size := opts.PageSize
+	if size <= 0 || size > len(items) {
+		size = max(len(items), 1)
+	}
+	total := max((len(items)+size-1)/size, 1)
+
+	pages := make([]digestPage, total)
+	for n := 1; n <= total; n++ {
+		page := &pages[n-1]
+		page.Title = opts.Title
+		page.Number = n
+		page.Generated = opts.Generated
+		for i := 1; i <= total && total > 1; i++ {
+			page.Pages = append(page.Pages, digestPageLink{Number: i, File: digestFileName(i), Current: i == n})
+		}
+		if n > 1 {
+			page.Prev = digestFileName(n - 1)
+		}
+		if n < total {
+			page.Next = digestFileName(n + 1)
+		}
+
+		start := (n - 1) * size
+		end := min(start+size, len(items))
+		var dayItems []rssreader.RssItem
+		label := ""
+		for _, item := range items[start:end] {
+			itemLabel := "Undated"
+			if !item.PublishDate.IsZero() {
+				item.PublishDate = item.PublishDate.In(loc)
+				itemLabel = item.PublishDate.Format("Monday, 2 January 2006")
+			}
+			if itemLabel != label && len(dayItems) > 0 {
+				page.Days = append(page.Days, digestDay{Label: label, Sources: rssreader.GroupBySource(dayItems)})
+				dayItems = nil
+			}
+			label = itemLabel
+			dayItems = append(dayItems, item)
+		}
+		if len(dayItems) > 0 {
+			page.Days = append(page.Days, digestDay{Label: label, Sources: rssreader.GroupBySource(dayItems)})
+		}
+	}
+	return pages
+}
+
+// writeDigest renders a single digest page.
+func writeDigest(w io.Writer, page digestPage) error {
+	if err := digestTemplate.Execute(w, page); err != nil {
+		return fmt.Errorf("rendering digest: %w", err)
+	}
+	return nil
+}
+
+// writeDigestSite writes every page of the digest into dir.
+func writeDigestSite(dir string, items []rssreader.RssItem, opts digestOptions) error {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+	for _, page := range buildDigestPages(items, opts) {
+		path := filepath.Join(dir, digestFileName(page.Number))
+		f, err := os.Create(path) // #nosec G304 -- the output directory is supplied by the user on purpose
+		if err != nil {
+			return fmt.Errorf("creating %s: %w", path, err)
+		}
+		err = writeDigest(f, page)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allowedElements maps the HTML elements kept by sanitizeHTML to their allowed attributes.
+var allowedElements = map[string][]string{
+	"a": {"href", "title"}, "abbr": {"title"}, "b": nil, "blockquote": nil, "br": nil,
+	"code": nil, "dd": nil, "del": nil, "dl": nil, "dt": nil, "em": nil, "figcaption": nil,
+	"figure": nil, "h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+	"hr": nil, "i": nil, "img": {"src", "alt", "title"}, "li": nil, "ol": nil, "p": nil,
+	"pre": nil, "q": nil, "s": nil, "small": nil, "strong": nil, "sub": nil, "sup": nil,
+	"table": nil, "tbody": nil, "td": nil, "th": nil, "thead": nil, "tr": nil, "u": nil, "ul": nil,
+}
+
+// droppedElements are removed from sanitized output together with their content.
+var droppedElements = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true,
+	"noscript": true, "template": true, "svg": true, "math": true, "form": true,
+}
+
+// voidElements never have content or an end tag.
+var voidElements = map[string]bool{"br": true, "hr": true, "img": true}
+
+// sanitizeHTML keeps a conservative subset of markup from feed-supplied HTML:
+// unknown tags are unwrapped, scripts and embeds are dropped, only http(s)
+// and mailto URLs survive, and unclosed tags are balanced.
+func sanitizeHTML(s string) template.HTML {
+	var b strings.Builder
+	var open []string
+	skip := 0
+	tokenizer := html.NewTokenizer(strings.NewReader(s))
+
+	for {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		token := tokenizer.Token()
+		name := token.Data
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedElements[name] {
+				if tt == html.StartTagToken {
+					skip++
+				}
+				continue
+			}
+			attrs, ok := allowedElements[name]
+			if !ok || skip > 0 {
+				continue
+			}
+			var tag strings.Builder
+			hasSrc := false
+			tag.WriteString("<" + name)
+			for _, attr := range token.Attr {
+				if !slices.Contains(attrs, attr.Key) {
+					continue
+				}
+				if (attr.Key == "href" || attr.Key == "src") && !safeURL(attr.Val) {
+					continue
+				}
+				hasSrc = hasSrc || attr.Key == "src"
+				fmt.Fprintf(&tag, ` %s="%s"`, attr.Key, html.EscapeString(attr.Val))
+			}
+			if name == "img" && !hasSrc {
+				continue
+			}
+			if name == "a" {
+				tag.WriteString(` rel="nofollow noopener"`)
+			}
+			tag.WriteString(">")
+			b.WriteString(tag.String())
+			if !voidElements[name] && tt == html.StartTagToken {
+				open = append(open, name)
+			}
+		case html.EndTagToken:
+			if droppedElements[name] {
+				if skip > 0 {
+					skip--
+				}
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			// Close everything up to the matching open element
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != name {
+					continue
+				}
+				for j := len(open) - 1; j >= i; j-- {
+					b.WriteString("</" + open[j] + ">")
+				}
+				open = open[:i]
+				break
+			}
+		case html.TextToken:
+			if skip == 0 {
+				b.WriteString(html.EscapeString(token.Data))
+			}
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+	// #nosec G203 -- the markup was rebuilt from an allowlist above
+	return template.HTML(b.String())
+}
+
+// safeURL reports whether a feed-supplied URL may be linked from the digest.
+func safeURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return true
+	default:
+		return false
+	}
+}
+
+var digestTemplate = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"sanitize":   sanitizeHTML,
+	"formatDate": formatDate,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{ .Title }}{{ if gt .Number 1 }} – page {{ .Number }}{{ end }}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; color: #222; }
+h1 { margin-bottom: 0.25rem; }
+h2 { border-bottom: 1px solid #ddd; margin-top: 2.5rem; }
+h3 { margin-bottom: 0.5rem; color: #555; }
+article { margin: 0 0 1.5rem; }
+article h4 { margin: 0; }
+.meta, footer { color: #777; font-size: 0.875rem; }
+.description { overflow-wrap: anywhere; }
+.description img { max-width: 100%; height: auto; }
+nav { margin: 2rem 0; }
+nav a, nav strong { margin-right: 0.5rem; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{ .Title }}</h1>
+{{- if not .Generated.IsZero }}
+<p class="meta">Generated {{ formatDate "rfc1123" .Generated }}</p>
+{{- end }}
+</header>
+<main>
+{{- range .Days }}
+<section>
+<h2>{{ .Label }}</h2>
+{{- range .Sources }}
+<h3>{{ if .SourceURL }}<a href="{{ .SourceURL }}">{{ or .Source .RssURL }}</a>{{ else }}{{ or .Source .RssURL }}{{ end }}</h3>
+{{- range .Items }}
+<article>
+<h4>{{ if .Link }}<a href="{{ .Link }}">{{ or .Title .Link }}</a>{{ else }}{{ .Title }}{{ end }}</h4>
+{{- if not .PublishDate.IsZero }}
+<p class="meta"><time datetime="{{ formatDate "rfc3339" .PublishDate }}">{{ formatDate "15:04 MST" .PublishDate }}</time></p>
+{{- end }}
+{{- if .Description }}
+<div class="description">{{ sanitize .Description }}</div>
+{{- end }}
+</article>
+{{- end }}
+{{- end }}
+</section>
+{{- else }}
+<p>No items.</p>
+{{- end }}
+</main>
+{{- if .Pages }}
+<nav>
+{{- if .Prev }}
+<a href="{{ .Prev }}" rel="prev">&larr; Previous</a>
+{{- end }}
+{{- range .Pages }}
+{{- if .Current }}
+<strong>{{ .Number }}</strong>
+{{- else }}
+<a href="{{ .File }}">{{ .Number }}</a>
+{{- end }}
+{{- end }}
+{{- if .Next }}
+<a href="{{ .Next }}" rel="next">Next &rarr;</a>
+{{- end }}
+</nav>
+{{- end }}
+<footer>Aggregated by RSS Reader</footer>
+</body>
+</html>
+`))
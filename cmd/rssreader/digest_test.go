@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+)
+
+// digestTestItems returns items already in the order produced by Parse.
+func digestTestItems() []rssreader.RssItem {
+	return []rssreader.RssItem{
+		{
+			Title:       "Undated note",
+			Source:      "Example Blog",
+			SourceURL:   "http://example.com/feed.xml",
+			Link:        "http://example.com/note",
+			RssURL:      "http://example.com/feed.xml",
+			Description: "No date here",
+		},
+		{
+			Title:       "Morning post",
+			Source:      "Example Blog",
+			SourceURL:   "http://example.com/feed.xml",
+			Link:        "http://example.com/morning",
+			PublishDate: time.Date(2006, 1, 2, 9, 0, 0, 0, time.UTC),
+			Description: `<p>Hello <b>world</b><script>alert(1)</script></p><img src="javascript:alert(1)" onerror="x()">`,
+			RssURL:      "http://example.com/feed.xml",
+		},
+		{
+			Title:       "Afternoon post",
+			Source:      "Another Blog",
+			SourceURL:   "http://another.com/rss",
+			Link:        "http://another.com/afternoon",
+			PublishDate: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+			Description: `<a href="http://another.com/more" onclick="x()">Read more</a> <em>unclosed`,
+			RssURL:      "http://another.com/rss",
+		},
+		{
+			Title:       "Next day",
+			Source:      "Example Blog",
+			SourceURL:   "http://example.com/feed.xml",
+			Link:        "http://example.com/next",
+			PublishDate: time.Date(2006, 1, 3, 10, 0, 0, 0, time.UTC),
+			Description: "Plain & simple",
+			RssURL:      "http://example.com/feed.xml",
+		},
+	}
+}
+
+func digestTestOptions() digestOptions {
+	return digestOptions{
+		Title:     "Team Digest",
+		Location:  time.UTC,
+		Generated: time.Date(2006, 1, 4, 8, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestDigest_SinglePage(t *testing.T) {
+	pages := buildDigestPages(digestTestItems(), digestTestOptions())
+	if len(pages) != 1 {
+		t.Fatalf("Expected 1 page, got %d", len(pages))
+	}
+
+	var buf bytes.Buffer
+	if err := writeDigest(&buf, pages[0]); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	assertGolden(t, "digest_single.html.golden", buf.Bytes())
+}
+
+func TestDigest_GroupsByDayAndSource(t *testing.T) {
+	pages := buildDigestPages(digestTestItems(), digestTestOptions())
+
+	days := pages[0].Days
+	if len(days) != 3 {
+		t.Fatalf("Expected 3 day groups, got %d", len(days))
+	}
+	if days[0].Label != "Undated" || days[1].Label != "Monday, 2 January 2006" {
+		t.Errorf("Unexpected day labels: %q, %q", days[0].Label, days[1].Label)
+	}
+	if len(days[1].Sources) != 2 || days[1].Sources[0].Source != "Example Blog" || days[1].Sources[1].Source != "Another Blog" {
+		t.Errorf("Expected two sources on 2 January in order of appearance, got %+v", days[1].Sources)
+	}
+}
+
+func TestDigest_DaysUseLocation(t *testing.T) {
+	opts := digestTestOptions()
+	opts.Location = time.FixedZone("UTC+10", 10*60*60)
+
+	pages := buildDigestPages(digestTestItems()[1:], opts)
+	days := pages[0].Days
+	// 15:04 UTC on the 2nd is already the 3rd in UTC+10
+	if len(days) != 2 || days[1].Label != "Tuesday, 3 January 2006" {
+		t.Fatalf("Expected items regrouped in UTC+10, got %+v", days)
+	}
+	if len(days[1].Sources) != 2 {
+		t.Errorf("Expected both sources on 3 January, got %+v", days[1].Sources)
+	}
+}
+
+func TestDigest_Site(t *testing.T) {
+	dir := t.TempDir()
+	opts := digestTestOptions()
+	opts.PageSize = 3
+
+	if err := writeDigestSite(dir, digestTestItems(), opts); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read output directory: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 pages, got %d", len(entries))
+	}
+
+	for _, name := range []string{"index.html", "page-2.html"} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		assertGolden(t, "digest_site_"+name+".golden", got)
+	}
+}
+
+func TestDigest_NoItems(t *testing.T) {
+	pages := buildDigestPages(nil, digestTestOptions())
+	if len(pages) != 1 {
+		t.Fatalf("Expected a single empty page, got %d", len(pages))
+	}
+
+	var buf bytes.Buffer
+	if err := writeDigest(&buf, pages[0]); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !strings.Contains(buf.String(), "<p>No items.</p>") {
+		t.Errorf("Expected empty digest notice, got:\n%s", buf.String())
+	}
+}
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "keeps allowed markup",
+			in:   `<p>Hello <strong>there</strong><br/></p>`,
+			want: `<p>Hello <strong>there</strong><br></p>`,
+		},
+		{
+			name: "drops scripts with content",
+			in:   `a<script>alert("x")</script>b<style>p{}</style>c`,
+			want: `abc`,
+		},
+		{
+			name: "unwraps unknown elements",
+			in:   `<div class="x"><span>text</span></div>`,
+			want: `text`,
+		},
+		{
+			name: "filters attributes and unsafe URLs",
+			in:   `<a href="javascript:alert(1)" onclick="x()">x</a><img src="https://example.com/a.png" onerror="x()" alt="A">`,
+			want: `<a rel="nofollow noopener">x</a><img src="https://example.com/a.png" alt="A">`,
+		},
+		{
+			name: "balances unclosed tags",
+			in:   `<ul><li><em>one</li></ul><b>open`,
+			want: `<ul><li><em>one</em></li></ul><b>open</b>`,
+		},
+		{
+			name: "escapes text",
+			in:   `1 &lt; 2 & "quotes"`,
+			want: `1 &lt; 2 &amp; &#34;quotes&#34;`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(sanitizeHTML(tt.in)); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
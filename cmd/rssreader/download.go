@@ -0,0 +1,616 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+)
+
+const (
+	// defaultLayout places each enclosure in a directory per feed, named
+	// after the publish date and title of its item.
+	defaultLayout = "{{.Feed}}/{{.Date}} {{.Title}}{{.Ext}}"
+	// defaultTypes are the enclosure types downloaded when -types is not set.
+	defaultTypes = "audio/*,video/*,application/pdf"
+	// partSuffix marks incomplete downloads that the next run resumes.
+	partSuffix = ".part"
+	// maxNameLength bounds every path segment produced from feed data.
+	maxNameLength = 150
+)
+
+// runDownload implements the download subcommand: it fetches the enclosures
+// of new items into a directory tree.
+func runDownload(args []string) error {
+	flags := flag.NewFlagSet("download", flag.ContinueOnError)
+	var (
+		urls      = flags.String("urls", "", "Comma-separated list of RSS feed URLs or local files")
+		cfgPath   = flags.String("config", "", "JSON file with per-feed settings such as scraped HTML sources; its feeds are added to -urls")
+		dir       = flags.String("dir", ".", "Directory to download enclosures into")
+		layout    = flags.String("layout", defaultLayout, "Go template for the path of each file below -dir; fields: Feed, Title, Date, Year, Month, Day, Season, Episode, Name, Ext")
+		types     = flags.String("types", defaultTypes, "Comma-separated MIME types to download, with type/* wildcards (empty downloads all)")
+		minSize   = flags.String("min-size", "", "Skip enclosures smaller than this, e.g. 500K")
+		maxSize   = flags.String("max-size", "", "Skip enclosures larger than this, e.g. 2G")
+		jobs      = flags.Int("jobs", 2, "Maximum number of downloads running at once")
+		statePath = flags.String("state", "", "File recording downloaded enclosures and their checksums (default .rssreader-downloads.json in -dir)")
+		timeout   = flags.Duration("timeout", 30*time.Second, "Timeout for fetching feeds")
+		blockPriv = flags.Bool("block-private", false, "Refuse to fetch feeds and enclosures from private, loopback, link-local and other non-public addresses, and to read local feed files")
+		allowPriv = flags.String("allow-private", "", "Comma-separated CIDR ranges or IPs that -block-private still allows")
+	)
+	logging := addLogFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	logOpts, err := logging.apply()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*cfgPath)
+	if err != nil {
+		return err
+	}
+	urlList := cfg.urls(splitList(*urls))
+	if len(urlList) == 0 {
+		return errors.New("-urls or -config flag is required")
+	}
+	tmpl, err := template.New("layout").Option("missingkey=error").Parse(*layout)
+	if err != nil {
+		return fmt.Errorf("parsing -layout: %w", err)
+	}
+	minBytes, err := parseSize(*minSize)
+	if err != nil {
+		return fmt.Errorf("-min-size: %w", err)
+	}
+	maxBytes, err := parseSize(*maxSize)
+	if err != nil {
+		return fmt.Errorf("-max-size: %w", err)
+	}
+	if *jobs < 1 {
+		return errors.New("-jobs must be at least 1")
+	}
+	if *statePath == "" {
+		*statePath = filepath.Join(*dir, ".rssreader-downloads.json")
+	}
+	state, err := loadDownloadState(*statePath)
+	if err != nil {
+		return err
+	}
+
+	client, err := newHTTPClient(*blockPriv, *allowPriv)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	parseCtx, cancel := context.WithTimeout(ctx, *timeout)
+	opts := append(logOpts, rssreader.WithHTTPClient(client))
+	if *blockPriv {
+		opts = append(opts, rssreader.WithoutLocalFiles())
+	}
+	items, err := cfg.parseFunc(opts...)(parseCtx, urlList)
+	cancel()
+	if err != nil {
+		// Download what the other feeds offer
+		log.Printf("Error parsing RSS feeds: %v", err)
+	}
+
+	d := &downloader{
+		client:  client,
+		dir:     *dir,
+		layout:  tmpl,
+		types:   splitList(*types),
+		minSize: minBytes,
+		maxSize: maxBytes,
+		jobs:    *jobs,
+		state:   state,
+		out:     os.Stdout,
+	}
+	return d.run(ctx, items)
+}
+
+// downloader fetches the enclosures of items that are not recorded in its
+// state yet.
+type downloader struct {
+	client  *http.Client
+	dir     string
+	layout  *template.Template
+	types   []string
+	minSize int64 // 0 for no limit
+	maxSize int64 // 0 for no limit
+	jobs    int
+	state   *downloadState
+	out     io.Writer // receives the path of every downloaded file
+}
+
+// downloadJob is a single enclosure to fetch.
+type downloadJob struct {
+	enclosure rssreader.Enclosure
+	path      string // relative to the download directory
+}
+
+// layoutData holds the fields available to the -layout template. All
+// strings are safe to use as a single path segment.
+type layoutData struct {
+	Feed    string
+	Title   string
+	Date    string // 2006-01-02, empty if the item has no date
+	Year    string
+	Month   string
+	Day     string
+	Season  int
+	Episode int
+	Name    string // file name of the enclosure URL without extension
+	Ext     string // extension including the dot, from the URL or the MIME type
+}
+
+// run downloads the enclosures of items, at most d.jobs at once. Failed
+// downloads are reported together after the others finished.
+func (d *downloader) run(ctx context.Context, items []rssreader.RssItem) error {
+	jobs, err := d.plan(items)
+	if err != nil {
+		return err
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		errs   []error
+		tokens = make(chan struct{}, d.jobs)
+	)
+	for _, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case tokens <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-tokens }()
+
+			err := d.download(ctx, job)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("downloading %s: %w", rssreader.RedactURL(job.enclosure.URL), err))
+				return
+			}
+			_, _ = fmt.Fprintln(d.out, filepath.Join(d.dir, job.path))
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// plan selects the enclosures to download and assigns each a unique path.
+func (d *downloader) plan(items []rssreader.RssItem) ([]downloadJob, error) {
+	var jobs []downloadJob
+	planned := map[string]bool{}
+	queued := map[string]bool{}
+	for _, item := range items {
+		for _, enclosure := range item.Enclosures {
+			if queued[enclosure.URL] || d.state.has(enclosure.URL) || !d.wanted(enclosure) {
+				continue
+			}
+			rel, err := d.filePath(item, enclosure)
+			if err != nil {
+				return nil, err
+			}
+			rel = d.uniquePath(rel, planned)
+			planned[rel] = true
+			queued[enclosure.URL] = true
+			jobs = append(jobs, downloadJob{enclosure: enclosure, path: rel})
+		}
+	}
+	return jobs, nil
+}
+
+// wanted applies the type and declared size filters to an enclosure.
+func (d *downloader) wanted(enclosure rssreader.Enclosure) bool {
+	if enclosure.Length > 0 && !d.sizeAllowed(enclosure.Length) {
+		return false
+	}
+	if len(d.types) == 0 {
+		return true
+	}
+	mediaType := enclosureType(enclosure)
+	for _, pattern := range d.types {
+		pattern = strings.ToLower(pattern)
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if mediaType == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *downloader) sizeAllowed(size int64) bool {
+	return (d.minSize == 0 || size >= d.minSize) && (d.maxSize == 0 || size <= d.maxSize)
+}
+
+// enclosureType returns the media type of an enclosure without parameters,
+// guessing it from the URL when the feed does not declare one.
+func enclosureType(enclosure rssreader.Enclosure) string {
+	mediaType := enclosure.Type
+	if mediaType == "" {
+		mediaType = mime.TypeByExtension(urlExt(enclosure.URL))
+	}
+	if parsed, _, err := mime.ParseMediaType(mediaType); err == nil {
+		return parsed
+	}
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
+
+// filePath renders the layout for an enclosure and checks that the result
+// stays inside the download directory.
+func (d *downloader) filePath(item rssreader.RssItem, enclosure rssreader.Enclosure) (string, error) {
+	name := strings.TrimSuffix(path.Base(urlPath(enclosure.URL)), urlExt(enclosure.URL))
+	data := layoutData{
+		Feed:  safeName(item.Source),
+		Title: safeName(item.Title),
+		Name:  safeName(name),
+		Ext:   enclosureExt(enclosure),
+	}
+	if data.Feed == "" {
+		data.Feed = safeName(item.RssURL)
+	}
+	if !item.PublishDate.IsZero() {
+		date := item.PublishDate.UTC()
+		data.Date = date.Format("2006-01-02")
+		data.Year = date.Format("2006")
+		data.Month = date.Format("01")
+		data.Day = date.Format("02")
+	}
+	if item.ITunes != nil {
+		data.Season = item.ITunes.Season
+		data.Episode = item.ITunes.Episode
+	}
+
+	var b strings.Builder
+	if err := d.layout.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("rendering -layout: %w", err)
+	}
+	rel := filepath.Clean(filepath.FromSlash(strings.TrimSpace(b.String())))
+	if !filepath.IsLocal(rel) || strings.HasSuffix(rel, partSuffix) {
+		return "", fmt.Errorf("-layout produced invalid path %q", b.String())
+	}
+	return rel, nil
+}
+
+// uniquePath appends a counter to rel when another planned download or an
+// unrelated existing file already uses it. Partial downloads are not
+// conflicts, they are resumed.
+func (d *downloader) uniquePath(rel string, planned map[string]bool) string {
+	ext := filepath.Ext(rel)
+	base := strings.TrimSuffix(rel, ext)
+	candidate := rel
+	for n := 2; ; n++ {
+		_, err := os.Stat(filepath.Join(d.dir, candidate))
+		if !planned[candidate] && errors.Is(err, fs.ErrNotExist) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s (%d)%s", base, n, ext)
+	}
+}
+
+// download fetches one enclosure into its .part file, resuming a previous
+// attempt with a Range request, and moves it into place when complete.
+func (d *downloader) download(ctx context.Context, job downloadJob) error {
+	target := filepath.Join(d.dir, job.path)
+	part := target + partSuffix
+	if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+		return err
+	}
+
+	var offset int64
+	if info, err := os.Stat(part); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, job.enclosure.URL, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	flags := os.O_WRONLY | os.O_CREATE
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0 && rangeStart(resp.Header.Get("Content-Range")) == offset:
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0 && rangeTotal(resp.Header.Get("Content-Range")) == offset:
+		// The previous attempt already received everything
+		return d.finish(job, part, target)
+	case resp.StatusCode == http.StatusOK:
+		// The server ignored the range, start over
+		offset = 0
+		flags |= os.O_TRUNC
+	default:
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	if resp.ContentLength >= 0 {
+		if total := offset + resp.ContentLength; !d.sizeAllowed(total) {
+			return fmt.Errorf("size %d bytes is outside -min-size/-max-size", total)
+		}
+	}
+
+	f, err := os.OpenFile(part, flags, 0o600) // #nosec G304 -- the path is confined to the download directory
+	if err != nil {
+		return err
+	}
+	body := io.Reader(resp.Body)
+	if d.maxSize > 0 {
+		// Servers that don't announce the size may still send too much
+		body = io.LimitReader(resp.Body, d.maxSize-offset+1)
+	}
+	written, copyErr := io.Copy(f, body)
+	if err := f.Close(); copyErr == nil {
+		copyErr = err
+	}
+	if copyErr != nil {
+		// Keep the partial file so the next run can resume it
+		return copyErr
+	}
+	if d.maxSize > 0 && offset+written > d.maxSize {
+		_ = os.Remove(part)
+		return fmt.Errorf("larger than -max-size of %d bytes", d.maxSize)
+	}
+	return d.finish(job, part, target)
+}
+
+// finish checksums a complete .part file, moves it to its target and
+// records it in the state.
+func (d *downloader) finish(job downloadJob, part, target string) error {
+	f, err := os.Open(part) // #nosec G304 -- the path is confined to the download directory
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	_ = f.Close()
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(part, target); err != nil {
+		return err
+	}
+
+	return d.state.record(job.enclosure.URL, downloadRecord{
+		Path:       filepath.ToSlash(job.path),
+		Size:       size,
+		SHA256:     hex.EncodeToString(hash.Sum(nil)),
+		Downloaded: time.Now().UTC(),
+	})
+}
+
+// rangeStart returns the first byte position of a "bytes first-last/total"
+// Content-Range header, or -1 if it cannot be parsed.
+func rangeStart(header string) int64 {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return -1
+	}
+	first, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(first), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// rangeTotal returns the complete length of a "bytes */total" Content-Range
+// header, or -1 if it is unknown.
+func rangeTotal(header string) int64 {
+	_, total, ok := strings.Cut(header, "/")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(total), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// urlPath returns the unescaped path of rawURL.
+func urlPath(rawURL string) string {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Path
+}
+
+// urlExt returns the lowercased extension of the URL path if it looks like
+// a real file extension.
+func urlExt(rawURL string) string {
+	ext := strings.ToLower(path.Ext(urlPath(rawURL)))
+	if len(ext) < 2 || len(ext) > 6 {
+		return ""
+	}
+	for _, r := range ext[1:] {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return ""
+		}
+	}
+	return ext
+}
+
+// enclosureExt picks the file extension for an enclosure, preferring the
+// one in its URL over the declared MIME type.
+func enclosureExt(enclosure rssreader.Enclosure) string {
+	if ext := urlExt(enclosure.URL); ext != "" {
+		return ext
+	}
+	// mime lists extensions alphabetically, so prefer the common ones
+	switch enclosureType(enclosure) {
+	case "audio/mpeg":
+		return ".mp3"
+	case "video/mp4":
+		return ".mp4"
+	}
+	if exts, err := mime.ExtensionsByType(enclosureType(enclosure)); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// safeName turns feed data into a single path segment that is valid on
+// common file systems.
+func safeName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return ' '
+		case strings.ContainsRune(`/\:*?"<>|`, r), unicode.IsControl(r):
+			return '_'
+		default:
+			return r
+		}
+	}, s)
+	s = strings.Trim(strings.Join(strings.Fields(s), " "), " .")
+	if len(s) > maxNameLength {
+		s = s[:maxNameLength]
+		for !utf8.ValidString(s) {
+			s = s[:len(s)-1]
+		}
+		s = strings.TrimRight(s, " .")
+	}
+	return s
+}
+
+// parseSize parses a byte size with an optional K, M or G suffix (powers of
+// 1024). An empty string means no limit.
+func parseSize(size string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	if s == "" {
+		return 0, nil
+	}
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	multiplier := int64(1)
+	if n := len(s); n > 0 {
+		switch s[n-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		}
+		if multiplier > 1 {
+			s = s[:n-1]
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	return n * multiplier, nil
+}
+
+// downloadState records the enclosures downloaded so far, keyed by URL.
+type downloadState struct {
+	path string
+	mu   sync.Mutex
+
+	Files map[string]downloadRecord
+}
+
+// downloadRecord describes a completed download.
+type downloadRecord struct {
+	Path       string // relative to the download directory
+	Size       int64
+	SHA256     string
+	Downloaded time.Time
+}
+
+// loadDownloadState reads the state file at path. A missing file yields an
+// empty state.
+func loadDownloadState(path string) (*downloadState, error) {
+	state := &downloadState{path: path, Files: map[string]downloadRecord{}}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- the state path is supplied by the user on purpose
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading download state: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("decoding download state %s: %w", path, err)
+	}
+	if state.Files == nil {
+		state.Files = map[string]downloadRecord{}
+	}
+	return state, nil
+}
+
+func (s *downloadState) has(url string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.Files[url]
+	return ok
+}
+
+// record adds a completed download and saves the state right away, so an
+// interrupted run keeps what it finished.
+func (s *downloadState) record(url string, record downloadRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Files[url] = record
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding download state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o750); err != nil {
+		return fmt.Errorf("writing download state: %w", err)
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("writing download state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+)
+
+// fileServer serves fixed files with Range support and records the requests.
+type fileServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	ranges   []string // Range header of every request
+	inFlight int
+	peak     int
+	delay    time.Duration
+}
+
+func newFileServer(t *testing.T, files map[string]string) *fileServer {
+	fs := &fileServer{}
+	fs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fs.mu.Lock()
+		fs.ranges = append(fs.ranges, r.Header.Get("Range"))
+		fs.inFlight++
+		fs.peak = max(fs.peak, fs.inFlight)
+		fs.mu.Unlock()
+		defer func() {
+			fs.mu.Lock()
+			fs.inFlight--
+			fs.mu.Unlock()
+		}()
+
+		time.Sleep(fs.delay)
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, r.URL.Path, time.Time{}, strings.NewReader(content))
+	}))
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+func (fs *fileServer) requests() []string {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return append([]string(nil), fs.ranges...)
+}
+
+func newTestDownloader(t *testing.T, dir string) (*downloader, *bytes.Buffer) {
+	t.Helper()
+	state, err := loadDownloadState(filepath.Join(dir, ".rssreader-downloads.json"))
+	if err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+	out := &bytes.Buffer{}
+	return &downloader{
+		client: http.DefaultClient,
+		dir:    dir,
+		layout: template.Must(template.New("layout").Parse(defaultLayout)),
+		types:  splitList(defaultTypes),
+		jobs:   2,
+		state:  state,
+		out:    out,
+	}, out
+}
+
+func podcastItem(title string, day int, enclosures ...rssreader.Enclosure) rssreader.RssItem {
+	return rssreader.RssItem{
+		Title:       title,
+		Source:      "Pod/Cast",
+		RssURL:      "http://feeds.test/pod",
+		PublishDate: time.Date(2024, 1, day, 10, 0, 0, 0, time.UTC),
+		Enclosures:  enclosures,
+	}
+}
+
+func TestDownloader_LayoutChecksumAndSkip(t *testing.T) {
+	content := "ID3 episode one audio"
+	server := newFileServer(t, map[string]string{"/media/ep1.mp3": content})
+	dir := t.TempDir()
+	items := []rssreader.RssItem{
+		podcastItem("Ep: 1", 2, rssreader.Enclosure{URL: server.URL + "/media/ep1.mp3", Type: "audio/mpeg"}),
+	}
+
+	d, out := newTestDownloader(t, dir)
+	if err := d.run(context.Background(), items); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	want := filepath.Join(dir, "Pod_Cast", "2024-01-02 Ep_ 1.mp3")
+	data, err := os.ReadFile(want)
+	if err != nil {
+		t.Fatalf("Expected file at %s: %v", want, err)
+	}
+	if string(data) != content {
+		t.Errorf("Expected %q, got %q", content, data)
+	}
+	if strings.TrimSpace(out.String()) != want {
+		t.Errorf("Expected the path to be printed, got %q", out.String())
+	}
+
+	sum := sha256.Sum256([]byte(content))
+	record := d.state.Files[server.URL+"/media/ep1.mp3"]
+	if record.SHA256 != hex.EncodeToString(sum[:]) || record.Size != int64(len(content)) || record.Path != "Pod_Cast/2024-01-02 Ep_ 1.mp3" {
+		t.Errorf("Unexpected state record: %+v", record)
+	}
+
+	// A new run with the saved state skips the enclosure
+	d, _ = newTestDownloader(t, dir)
+	if err := d.run(context.Background(), items); err != nil {
+		t.Fatalf("Second run failed: %v", err)
+	}
+	if requests := server.requests(); len(requests) != 1 {
+		t.Errorf("Expected the second run to skip the download, got %d requests", len(requests))
+	}
+}
+
+func TestDownloader_ResumesPartialDownload(t *testing.T) {
+	content := "0123456789abcdefghij"
+	server := newFileServer(t, map[string]string{"/ep.mp3": content})
+	dir := t.TempDir()
+	target := filepath.Join(dir, "Pod_Cast", "2024-01-03 Resumed.mp3")
+	if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target+partSuffix, []byte(content[:8]), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	d, _ := newTestDownloader(t, dir)
+	items := []rssreader.RssItem{podcastItem("Resumed", 3, rssreader.Enclosure{URL: server.URL + "/ep.mp3"})}
+	if err := d.run(context.Background(), items); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	if requests := server.requests(); len(requests) != 1 || requests[0] != "bytes=8-" {
+		t.Errorf("Expected a single request for the missing range, got %q", requests)
+	}
+	data, err := os.ReadFile(target)
+	if err != nil || string(data) != content {
+		t.Errorf("Expected the complete file, got %q (%v)", data, err)
+	}
+	if _, err := os.Stat(target + partSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected the partial file to be gone, got %v", err)
+	}
+	sum := sha256.Sum256([]byte(content))
+	if record := d.state.Files[server.URL+"/ep.mp3"]; record.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("Expected the checksum of the whole file, got %+v", record)
+	}
+}
+
+func TestDownloader_Filters(t *testing.T) {
+	server := newFileServer(t, map[string]string{
+		"/notes.pdf":  "%PDF notes",
+		"/cover.jpg":  "JPEG",
+		"/big.mp3":    strings.Repeat("x", 100),
+		"/undeclared": strings.Repeat("y", 100),
+	})
+	dir := t.TempDir()
+	items := []rssreader.RssItem{
+		podcastItem("Notes", 4, rssreader.Enclosure{URL: server.URL + "/notes.pdf"}),
+		podcastItem("Cover", 5, rssreader.Enclosure{URL: server.URL + "/cover.jpg", Type: "image/jpeg"}),
+		podcastItem("Big", 6, rssreader.Enclosure{URL: server.URL + "/big.mp3", Type: "audio/mpeg", Length: 100}),
+		podcastItem("Undeclared", 7, rssreader.Enclosure{URL: server.URL + "/undeclared", Type: "audio/mpeg"}),
+	}
+
+	d, _ := newTestDownloader(t, dir)
+	d.maxSize = 50
+	err := d.run(context.Background(), items)
+	if err == nil || !strings.Contains(err.Error(), "/undeclared") {
+		t.Errorf("Expected an error for the oversized undeclared download, got %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "Pod_Cast", "2024-01-04 Notes.pdf")); err != nil {
+		t.Errorf("Expected the PDF to be downloaded: %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "Pod_Cast"))
+	if len(entries) != 1 {
+		t.Errorf("Expected only the PDF, got %v", entries)
+	}
+	if len(server.requests()) != 2 {
+		t.Errorf("Expected no requests for filtered enclosures, got %d requests", len(server.requests()))
+	}
+}
+
+func TestDownloader_LimitsConcurrency(t *testing.T) {
+	files := map[string]string{}
+	var items []rssreader.RssItem
+	server := newFileServer(t, files)
+	server.delay = 20 * time.Millisecond
+	for i := 1; i <= 6; i++ {
+		name := "/ep" + string(rune('0'+i)) + ".mp3"
+		files[name] = "audio"
+		items = append(items, podcastItem("Episode", i, rssreader.Enclosure{URL: server.URL + name, Type: "audio/mpeg"}))
+	}
+
+	d, _ := newTestDownloader(t, t.TempDir())
+	if err := d.run(context.Background(), items); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if len(d.state.Files) != 6 {
+		t.Errorf("Expected 6 downloads, got %d", len(d.state.Files))
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.peak > d.jobs {
+		t.Errorf("Expected at most %d concurrent downloads, got %d", d.jobs, server.peak)
+	}
+}
+
+func TestDownloader_CollidingPaths(t *testing.T) {
+	server := newFileServer(t, map[string]string{"/a/part.mp3": "a", "/b/part.mp3": "b"})
+	dir := t.TempDir()
+	items := []rssreader.RssItem{podcastItem("Two parts", 8,
+		rssreader.Enclosure{URL: server.URL + "/a/part.mp3"},
+		rssreader.Enclosure{URL: server.URL + "/b/part.mp3"},
+	)}
+
+	d, _ := newTestDownloader(t, dir)
+	if err := d.run(context.Background(), items); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	for _, name := range []string{"2024-01-08 Two parts.mp3", "2024-01-08 Two parts (2).mp3"} {
+		if _, err := os.Stat(filepath.Join(dir, "Pod_Cast", name)); err != nil {
+			t.Errorf("Expected %s: %v", name, err)
+		}
+	}
+}
+
+func TestDownloader_LayoutMustStayInsideDir(t *testing.T) {
+	d, _ := newTestDownloader(t, t.TempDir())
+	d.layout = template.Must(template.New("layout").Parse("../{{.Title}}{{.Ext}}"))
+	items := []rssreader.RssItem{podcastItem("Escape", 9, rssreader.Enclosure{URL: "http://media.test/x.mp3"})}
+	if err := d.run(context.Background(), items); err == nil {
+		t.Error("Expected an error for a layout outside the download directory")
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{"": 0, "1024": 1024, "500K": 500 << 10, "2g": 2 << 30, "10MB": 10 << 20, "1MiB": 1 << 20}
+	for input, want := range tests {
+		got, err := parseSize(input)
+		if err != nil || got != want {
+			t.Errorf("parseSize(%q): expected %d, got %d (%v)", input, want, got, err)
+		}
+	}
+	for _, input := range []string{"big", "-1", "1T"} {
+		if _, err := parseSize(input); err == nil {
+			t.Errorf("parseSize(%q): expected an error", input)
+		}
+	}
+}
+
+func TestSafeName(t *testing.T) {
+	tests := map[string]string{
+		"Ep: 1/2":                "Ep_ 1_2",
+		"  ..hidden  ":           "hidden",
+		"tab\tand\nlines":        "tab and lines",
+		strings.Repeat("ä", 100): strings.Repeat("ä", 75),
+	}
+	for input, want := range tests {
+		if got := safeName(input); got != want {
+			t.Errorf("safeName(%q): expected %q, got %q", input, want, got)
+		}
+	}
+}
+
+func TestRunDownload_BlockPrivate(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/episode.mp3" {
+			_, _ = w.Write([]byte("audio"))
+			return
+		}
+		_, _ = w.Write([]byte(`<rss version="2.0"><channel><title>Cast</title><item><title>One</title>` +
+			`<enclosure url="` + server.URL + `/episode.mp3" type="audio/mpeg" length="5"/></item></channel></rss>`))
+	}))
+	defer server.Close()
+
+	countFiles := func(dir string) int {
+		n := 0
+		_ = filepath.WalkDir(dir, func(path string, d os.DirEntry, _ error) error {
+			if !d.IsDir() && strings.HasSuffix(path, ".mp3") {
+				n++
+			}
+			return nil
+		})
+		return n
+	}
+
+	blocked := t.TempDir()
+	if err := runDownload([]string{"-urls", server.URL + "/feed", "-dir", blocked, "-block-private"}); err != nil {
+		t.Fatalf("Expected the blocked feed to be logged, got: %v", err)
+	}
+	if n := countFiles(blocked); n != 0 {
+		t.Errorf("Expected nothing downloaded from a loopback server, got %d files", n)
+	}
+
+	allowed := t.TempDir()
+	if err := runDownload([]string{"-urls", server.URL + "/feed", "-dir", allowed, "-block-private", "-allow-private", "127.0.0.1"}); err != nil {
+		t.Fatalf("Expected the download to succeed, got: %v", err)
+	}
+	if n := countFiles(allowed); n != 1 {
+		t.Errorf("Expected the allowlisted enclosure to be downloaded, got %d files", n)
+	}
+}
@@ -0,0 +1,713 @@
+package main
+
+import (
+	"cmp"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+)
+
+// Stream and tag IDs of the Google Reader API.
+const (
+	greaderReadingList = "user/-/state/com.google/reading-list"
+	greaderRead        = "user/-/state/com.google/read"
+	greaderStarred     = "user/-/state/com.google/starred"
+	greaderKeptUnread  = "user/-/state/com.google/kept-unread"
+	greaderFeedPrefix  = "feed/"
+	greaderItemPrefix  = "tag:google.com,2005:reader/item/"
+)
+
+const (
+	greaderDefaultCount = 20
+	greaderMaxContents  = 1000
+	greaderMaxIDs       = 10000
+)
+
+// greaderAPI implements the subset of the Google Reader API used by mobile
+// clients such as Reeder, NetNewsWire and FeedMe on top of the server's items.
+type greaderAPI struct {
+	srv      *server
+	user     string
+	password string
+	state    *readState
+}
+
+func newGReaderAPI(srv *server, user, password string, state *readState) *greaderAPI {
+	return &greaderAPI{srv: srv, user: user, password: password, state: state}
+}
+
+// register adds the API routes to mux.
+func (g *greaderAPI) register(mux *http.ServeMux) {
+	mux.HandleFunc("/accounts/ClientLogin", g.handleLogin)
+	mux.HandleFunc("GET /reader/api/0/token", g.authorized(g.handleToken))
+	mux.HandleFunc("GET /reader/api/0/user-info", g.authorized(g.handleUserInfo))
+	mux.HandleFunc("GET /reader/api/0/subscription/list", g.authorized(g.handleSubscriptions))
+	mux.HandleFunc("GET /reader/api/0/tag/list", g.authorized(g.handleTags))
+	mux.HandleFunc("GET /reader/api/0/unread-count", g.authorized(g.handleUnreadCount))
+	mux.HandleFunc("GET /reader/api/0/stream/contents/{stream...}", g.authorized(g.handleStreamContents))
+	mux.HandleFunc("GET /reader/api/0/stream/items/ids", g.authorized(g.handleStreamIDs))
+	mux.HandleFunc("/reader/api/0/stream/items/contents", g.authorized(g.handleItemContents))
+	mux.HandleFunc("POST /reader/api/0/edit-tag", g.authorized(g.checkToken(g.handleEditTag)))
+	mux.HandleFunc("POST /reader/api/0/mark-all-as-read", g.authorized(g.checkToken(g.handleMarkAllAsRead)))
+}
+
+// sign derives a token for purpose from the configured credentials, so tokens
+// stay valid across restarts without storing sessions.
+func (g *greaderAPI) sign(purpose string) string {
+	mac := hmac.New(sha256.New, []byte(g.password))
+	mac.Write([]byte(purpose + "\n" + g.user))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (g *greaderAPI) authToken() string { return g.user + "/" + g.sign("auth") }
+
+func (g *greaderAPI) editToken() string { return g.sign("edit") }
+
+func (g *greaderAPI) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Error=BadRequest", http.StatusBadRequest)
+		return
+	}
+	user := r.Form.Get("Email")
+	password := r.Form.Get("Passwd")
+	if !secureEqual(user, g.user) || !secureEqual(password, g.password) {
+		http.Error(w, "Error=BadAuthentication", http.StatusUnauthorized)
+		return
+	}
+	token := g.authToken()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = fmt.Fprintf(w, "SID=%s\nLSID=null\nAuth=%s\n", token, token)
+}
+
+// authorized rejects requests without a valid "GoogleLogin auth=" header.
+func (g *greaderAPI) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "GoogleLogin auth=")
+		if !ok || !secureEqual(token, g.authToken()) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// checkToken rejects edits without the token handed out by /token.
+func (g *greaderAPI) checkToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !secureEqual(r.Form.Get("T"), g.editToken()) {
+			w.Header().Set("X-Reader-Google-Bad-Token", "true")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (g *greaderAPI) handleToken(w http.ResponseWriter, _ *http.Request) {
+	writeText(w, g.editToken())
+}
+
+func (g *greaderAPI) handleUserInfo(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"userId":        "1",
+		"userName":      g.user,
+		"userProfileId": "1",
+		"userEmail":     g.user,
+	})
+}
+
+type greaderSubscription struct {
+	ID         string   `json:"id"`
+	Title      string   `json:"title"`
+	Categories []string `json:"categories"`
+	URL        string   `json:"url"`
+	HTMLURL    string   `json:"htmlUrl"`
+	IconURL    string   `json:"iconUrl"`
+}
+
+func (g *greaderAPI) handleSubscriptions(w http.ResponseWriter, _ *http.Request) {
+	subs := []greaderSubscription{}
+	for _, feed := range g.srv.feeds() {
+		title := feed.Title
+		if title == "" {
+			title = feed.URL
+		}
+		subs = append(subs, greaderSubscription{
+			ID:         greaderFeedPrefix + feed.URL,
+			Title:      title,
+			Categories: []string{},
+			URL:        feed.URL,
+			HTMLURL:    feed.Link,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"subscriptions": subs})
+}
+
+func (g *greaderAPI) handleTags(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tags": []map[string]string{{"id": greaderStarred}},
+	})
+}
+
+type greaderUnreadCount struct {
+	ID                      string `json:"id"`
+	Count                   int    `json:"count"`
+	NewestItemTimestampUsec string `json:"newestItemTimestampUsec"`
+}
+
+func (g *greaderAPI) handleUnreadCount(w http.ResponseWriter, _ *http.Request) {
+	items := g.srv.snapshot()
+	total := greaderUnreadCount{ID: greaderReadingList}
+	counts := map[string]*greaderUnreadCount{}
+	order := []string{}
+	for _, item := range items {
+		if g.state.isRead(greaderItemID(item)) {
+			continue
+		}
+		id := greaderFeedPrefix + item.RssURL
+		count, ok := counts[id]
+		if !ok {
+			count = &greaderUnreadCount{ID: id}
+			counts[id] = count
+			order = append(order, id)
+		}
+		// Items are sorted oldest first, so the last one seen is the newest
+		usec := strconv.FormatInt(greaderTimestamp(item).UnixMicro(), 10)
+		count.Count++
+		count.NewestItemTimestampUsec = usec
+		total.Count++
+		total.NewestItemTimestampUsec = usec
+	}
+
+	result := []greaderUnreadCount{}
+	for _, id := range order {
+		result = append(result, *counts[id])
+	}
+	if total.Count > 0 {
+		result = append(result, total)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"max": greaderMaxContents, "unreadcounts": result})
+}
+
+// greaderQuery holds the stream parameters shared by stream/contents and
+// stream/items/ids.
+type greaderQuery struct {
+	stream       string
+	include      []string
+	exclude      []string
+	newer, older time.Time
+	oldestFirst  bool
+	count        int
+	continuation *rssreader.RssItem
+}
+
+// parseGReaderQuery reads the stream parameters from r, capping the count at maxCount.
+func parseGReaderQuery(r *http.Request, stream string, maxCount int) (greaderQuery, error) {
+	q := r.URL.Query()
+	query := greaderQuery{
+		stream:      normalizeGReaderTag(stream),
+		oldestFirst: q.Get("r") == "o",
+		count:       greaderDefaultCount,
+	}
+	for _, tag := range q["it"] {
+		query.include = append(query.include, normalizeGReaderTag(tag))
+	}
+	for _, tag := range q["xt"] {
+		query.exclude = append(query.exclude, normalizeGReaderTag(tag))
+	}
+	if v := q.Get("n"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return query, fmt.Errorf("invalid n %q", v)
+		}
+		query.count = min(n, maxCount)
+	}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"ot", &query.newer}, {"nt", &query.older}} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		secs, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return query, fmt.Errorf("invalid %s %q: expected Unix seconds", p.name, v)
+		}
+		*p.dst = time.Unix(secs, 0)
+	}
+	if v := q.Get("c"); v != "" {
+		item, err := decodeCursor(v)
+		if err != nil {
+			return query, err
+		}
+		query.continuation = &item
+	}
+	return query, nil
+}
+
+// find returns the items of the requested stream page and the continuation
+// for the next page, if any.
+func (g *greaderAPI) find(query greaderQuery) ([]rssreader.RssItem, string) {
+	items := g.srv.snapshot()
+	if !query.oldestFirst {
+		slices.Reverse(items)
+	}
+
+	page := []rssreader.RssItem{}
+	for _, item := range items {
+		if query.continuation != nil {
+			c := compareItems(item, *query.continuation)
+			if (query.oldestFirst && c <= 0) || (!query.oldestFirst && c >= 0) {
+				continue
+			}
+		}
+		id := greaderItemID(item)
+		if !g.inStream(item, id, query.stream) {
+			continue
+		}
+		if slices.ContainsFunc(query.include, func(tag string) bool { return !g.inStream(item, id, tag) }) {
+			continue
+		}
+		if slices.ContainsFunc(query.exclude, func(tag string) bool { return g.inStream(item, id, tag) }) {
+			continue
+		}
+		ts := greaderTimestamp(item)
+		if !query.newer.IsZero() && ts.Before(query.newer) {
+			continue
+		}
+		if !query.older.IsZero() && !ts.Before(query.older) {
+			continue
+		}
+		if len(page) == query.count {
+			return page, encodeCursor(page[len(page)-1])
+		}
+		page = append(page, item)
+	}
+	return page, ""
+}
+
+// inStream reports whether item belongs to the stream or tag with the given ID.
+func (g *greaderAPI) inStream(item rssreader.RssItem, id uint64, stream string) bool {
+	switch stream {
+	case greaderReadingList:
+		return true
+	case greaderRead:
+		return g.state.isRead(id)
+	case greaderStarred:
+		return g.state.isStarred(id)
+	case greaderKeptUnread:
+		return !g.state.isRead(id)
+	}
+	if url, ok := strings.CutPrefix(stream, greaderFeedPrefix); ok {
+		return item.RssURL == url
+	}
+	return false
+}
+
+// greaderStream is the response body of stream/contents and stream/items/contents.
+type greaderStream struct {
+	ID           string        `json:"id"`
+	Updated      int64         `json:"updated"`
+	Items        []greaderItem `json:"items"`
+	Continuation string        `json:"continuation,omitempty"`
+}
+
+type greaderItem struct {
+	ID            string         `json:"id"`
+	CrawlTimeMsec string         `json:"crawlTimeMsec"`
+	TimestampUsec string         `json:"timestampUsec"`
+	Published     int64          `json:"published"`
+	Updated       int64          `json:"updated"`
+	Title         string         `json:"title"`
+	Canonical     []greaderLink  `json:"canonical"`
+	Alternate     []greaderLink  `json:"alternate"`
+	Summary       greaderContent `json:"summary"`
+	Author        string         `json:"author"`
+	Categories    []string       `json:"categories"`
+	Origin        greaderOrigin  `json:"origin"`
+}
+
+type greaderLink struct {
+	Href string `json:"href"`
+	Type string `json:"type,omitempty"`
+}
+
+type greaderContent struct {
+	Direction string `json:"direction"`
+	Content   string `json:"content"`
+}
+
+type greaderOrigin struct {
+	StreamID string `json:"streamId"`
+	Title    string `json:"title"`
+	HTMLURL  string `json:"htmlUrl"`
+}
+
+// toGReaderItem converts item to the API representation with its current flags.
+func (g *greaderAPI) toGReaderItem(item rssreader.RssItem) greaderItem {
+	id := greaderItemID(item)
+	ts := greaderTimestamp(item)
+	categories := []string{greaderReadingList}
+	if g.state.isRead(id) {
+		categories = append(categories, greaderRead)
+	}
+	if g.state.isStarred(id) {
+		categories = append(categories, greaderStarred)
+	}
+	links := []greaderLink{}
+	if item.Link != "" {
+		links = append(links, greaderLink{Href: item.Link, Type: "text/html"})
+	}
+	return greaderItem{
+		ID:            fmt.Sprintf("%s%016x", greaderItemPrefix, id),
+		CrawlTimeMsec: strconv.FormatInt(ts.UnixMilli(), 10),
+		TimestampUsec: strconv.FormatInt(ts.UnixMicro(), 10),
+		Published:     ts.Unix(),
+		Updated:       ts.Unix(),
+		Title:         item.Title,
+		Canonical:     links,
+		Alternate:     links,
+		Summary:       greaderContent{Direction: "ltr", Content: cmp.Or(item.FullContent, item.Content, item.Description)},
+		Categories:    categories,
+		Origin: greaderOrigin{
+			StreamID: greaderFeedPrefix + item.RssURL,
+			Title:    item.Source,
+			HTMLURL:  item.SourceURL,
+		},
+	}
+}
+
+func (g *greaderAPI) writeStream(w http.ResponseWriter, id string, items []rssreader.RssItem, continuation string) {
+	stream := greaderStream{
+		ID:           id,
+		Updated:      time.Now().Unix(),
+		Items:        []greaderItem{},
+		Continuation: continuation,
+	}
+	for _, item := range items {
+		stream.Items = append(stream.Items, g.toGReaderItem(item))
+	}
+	writeJSON(w, http.StatusOK, stream)
+}
+
+func (g *greaderAPI) handleStreamContents(w http.ResponseWriter, r *http.Request) {
+	stream := r.PathValue("stream")
+	if stream == "" {
+		stream = greaderReadingList
+	}
+	query, err := parseGReaderQuery(r, stream, greaderMaxContents)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	items, continuation := g.find(query)
+	g.writeStream(w, stream, items, continuation)
+}
+
+type greaderItemRef struct {
+	ID              string   `json:"id"`
+	DirectStreamIDs []string `json:"directStreamIds"`
+	TimestampUsec   string   `json:"timestampUsec"`
+}
+
+func (g *greaderAPI) handleStreamIDs(w http.ResponseWriter, r *http.Request) {
+	stream := r.URL.Query().Get("s")
+	if stream == "" {
+		stream = greaderReadingList
+	}
+	query, err := parseGReaderQuery(r, stream, greaderMaxIDs)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	items, continuation := g.find(query)
+
+	refs := []greaderItemRef{}
+	for _, item := range items {
+		refs = append(refs, greaderItemRef{
+			ID:              strconv.FormatInt(int64(greaderItemID(item)), 10), // #nosec G115 -- short IDs are the signed form by definition
+			DirectStreamIDs: []string{},
+			TimestampUsec:   strconv.FormatInt(greaderTimestamp(item).UnixMicro(), 10),
+		})
+	}
+	body := map[string]any{"itemRefs": refs}
+	if continuation != "" {
+		body["continuation"] = continuation
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+func (g *greaderAPI) handleItemContents(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	ids, err := parseGReaderItemIDs(r.Form["i"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	byID := map[uint64]rssreader.RssItem{}
+	for _, item := range g.srv.snapshot() {
+		byID[greaderItemID(item)] = item
+	}
+	items := []rssreader.RssItem{}
+	for _, id := range ids {
+		if item, ok := byID[id]; ok {
+			items = append(items, item)
+		}
+	}
+	g.writeStream(w, greaderReadingList, items, "")
+}
+
+func (g *greaderAPI) handleEditTag(w http.ResponseWriter, r *http.Request) {
+	ids, err := parseGReaderItemIDs(r.Form["i"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	now := time.Now()
+	for _, tag := range r.Form["a"] {
+		g.state.apply(ids, normalizeGReaderTag(tag), true, now)
+	}
+	for _, tag := range r.Form["r"] {
+		g.state.apply(ids, normalizeGReaderTag(tag), false, now)
+	}
+	g.saveState(w, now)
+}
+
+func (g *greaderAPI) handleMarkAllAsRead(w http.ResponseWriter, r *http.Request) {
+	stream := normalizeGReaderTag(r.Form.Get("s"))
+	if stream == "" {
+		stream = greaderReadingList
+	}
+	var before time.Time
+	if v := r.Form.Get("ts"); v != "" {
+		usec, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid ts %q: expected Unix microseconds", v))
+			return
+		}
+		before = time.UnixMicro(usec)
+	}
+
+	now := time.Now()
+	var ids []uint64
+	for _, item := range g.srv.snapshot() {
+		id := greaderItemID(item)
+		if !g.inStream(item, id, stream) {
+			continue
+		}
+		if !before.IsZero() && greaderTimestamp(item).After(before) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	g.state.apply(ids, greaderRead, true, now)
+	g.saveState(w, now)
+}
+
+// saveState persists the flags and acknowledges the edit.
+func (g *greaderAPI) saveState(w http.ResponseWriter, now time.Time) {
+	present := map[uint64]bool{}
+	for _, item := range g.srv.snapshot() {
+		present[greaderItemID(item)] = true
+	}
+	if err := g.state.save(now, present); err != nil {
+		log.Printf("Error saving reader state: %v", err)
+		http.Error(w, "Error saving state", http.StatusInternalServerError)
+		return
+	}
+	writeText(w, "OK")
+}
+
+// greaderItemID derives a stable 64-bit item ID from the same identity the
+// -state file uses.
+func greaderItemID(item rssreader.RssItem) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(itemKey(item)))
+	return h.Sum64()
+}
+
+// parseGReaderItemIDs accepts item IDs in the long "tag:google.com,..." form
+// (hexadecimal) and the short decimal form.
+func parseGReaderItemIDs(values []string) ([]uint64, error) {
+	ids := make([]uint64, 0, len(values))
+	for _, v := range values {
+		if hexID, ok := strings.CutPrefix(v, greaderItemPrefix); ok {
+			id, err := strconv.ParseUint(hexID, 16, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid item id %q", v)
+			}
+			ids = append(ids, id)
+			continue
+		}
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid item id %q", v)
+		}
+		ids = append(ids, uint64(id)) // #nosec G115 -- short IDs are the signed form of the 64-bit ID
+	}
+	return ids, nil
+}
+
+// normalizeGReaderTag replaces the user ID in "user/<id>/..." tags with "-".
+func normalizeGReaderTag(tag string) string {
+	rest, ok := strings.CutPrefix(tag, "user/")
+	if !ok {
+		return tag
+	}
+	if _, after, found := strings.Cut(rest, "/"); found {
+		return "user/-/" + after
+	}
+	return tag
+}
+
+// greaderTimestamp is the time an item is sorted and filtered by. Items
+// without a publish date count as published at the Unix epoch.
+func greaderTimestamp(item rssreader.RssItem) time.Time {
+	if item.PublishDate.IsZero() {
+		return time.Unix(0, 0)
+	}
+	return item.PublishDate
+}
+
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func writeText(w http.ResponseWriter, text string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = fmt.Fprint(w, text)
+}
+
+// readState persists the read and starred flags of the Google Reader API.
+type readState struct {
+	path string // empty for a state that only lives in memory
+
+	mu sync.Mutex
+	// Read and Starred map hexadecimal item IDs to when the flag was set.
+	Read    map[string]time.Time
+	Starred map[string]time.Time
+}
+
+// loadReadState reads the state file at path. A missing file or an empty
+// path yields an empty state.
+func loadReadState(path string) (*readState, error) {
+	state := &readState{path: path, Read: map[string]time.Time{}, Starred: map[string]time.Time{}}
+	if path == "" {
+		return state, nil
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- the state path is supplied by the user on purpose
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading reader state: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("decoding reader state %s: %w", path, err)
+	}
+	if state.Read == nil {
+		state.Read = map[string]time.Time{}
+	}
+	if state.Starred == nil {
+		state.Starred = map[string]time.Time{}
+	}
+	return state, nil
+}
+
+func stateKey(id uint64) string { return fmt.Sprintf("%016x", id) }
+
+func (s *readState) isRead(id uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.Read[stateKey(id)]
+	return ok
+}
+
+func (s *readState) isStarred(id uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.Starred[stateKey(id)]
+	return ok
+}
+
+// apply sets or clears tag on the items. Unsupported tags such as labels are ignored.
+func (s *readState) apply(ids []uint64, tag string, set bool, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var flags map[string]time.Time
+	switch tag {
+	case greaderRead:
+		flags = s.Read
+	case greaderKeptUnread:
+		// Keeping an item unread is the same as clearing its read flag
+		flags, set = s.Read, !set
+	case greaderStarred:
+		flags = s.Starred
+	default:
+		return
+	}
+	for _, id := range ids {
+		if set {
+			flags[stateKey(id)] = now
+		} else {
+			delete(flags, stateKey(id))
+		}
+	}
+}
+
+// save drops read flags of items that left their feeds more than
+// stateRetention ago and writes the state file. Starred items are kept.
+func (s *readState) save(now time.Time, present map[uint64]bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, marked := range s.Read {
+		id, err := strconv.ParseUint(key, 16, 64)
+		if err == nil && present[id] {
+			continue
+		}
+		if now.Sub(marked) > stateRetention {
+			delete(s.Read, key)
+		}
+	}
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding reader state: %w", err)
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("writing reader state: %w", err)
+	}
+	return nil
+}
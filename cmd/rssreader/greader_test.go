@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// greaderClient talks to a test server with the Google Reader API enabled.
+type greaderClient struct {
+	t     *testing.T
+	base  string
+	auth  string
+	token string
+}
+
+func newGReaderTestServer(t *testing.T, statePath string) *httptest.Server {
+	t.Helper()
+	state, err := loadReadState(statePath)
+	if err != nil {
+		t.Fatalf("Failed to load reader state: %v", err)
+	}
+	srv := newServer([]string{"http://feeds.test/go", "http://feeds.test/rust"}, stubParse(testItems()), 5*time.Second)
+	srv.greader = newGReaderAPI(srv, "alice", "s3cret", state)
+	if err := srv.refresh(context.Background()); err != nil {
+		t.Fatalf("Initial refresh failed: %v", err)
+	}
+	ts := httptest.NewServer(srv.handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func loginGReader(t *testing.T, base string) *greaderClient {
+	t.Helper()
+	resp, err := http.PostForm(base+"/accounts/ClientLogin", url.Values{"Email": {"alice"}, "Passwd": {"s3cret"}})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected login to succeed, got %d: %s", resp.StatusCode, body)
+	}
+
+	c := &greaderClient{t: t, base: base}
+	for _, line := range strings.Split(string(body), "\n") {
+		if v, ok := strings.CutPrefix(line, "Auth="); ok {
+			c.auth = v
+		}
+	}
+	if c.auth == "" {
+		t.Fatalf("Expected Auth token in login response, got %q", body)
+	}
+	c.token = string(c.do(http.MethodGet, "/reader/api/0/token", nil, http.StatusOK))
+	return c
+}
+
+func (c *greaderClient) do(method, path string, form url.Values, wantStatus int) []byte {
+	c.t.Helper()
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequest(method, c.base+path, body)
+	if err != nil {
+		c.t.Fatalf("Failed to build request: %v", err)
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if c.auth != "" {
+		req.Header.Set("Authorization", "GoogleLogin auth="+c.auth)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != wantStatus {
+		c.t.Fatalf("%s %s: expected status %d, got %d: %s", method, path, wantStatus, resp.StatusCode, data)
+	}
+	return data
+}
+
+func (c *greaderClient) getJSON(path string, v any) {
+	c.t.Helper()
+	if err := json.Unmarshal(c.do(http.MethodGet, path, nil, http.StatusOK), v); err != nil {
+		c.t.Fatalf("Failed to decode %s: %v", path, err)
+	}
+}
+
+func (c *greaderClient) stream(path string) greaderStream {
+	c.t.Helper()
+	var stream greaderStream
+	c.getJSON(path, &stream)
+	return stream
+}
+
+func streamTitles(stream greaderStream) []string {
+	titles := []string{}
+	for _, item := range stream.Items {
+		titles = append(titles, item.Title)
+	}
+	return titles
+}
+
+func TestGReader_Login(t *testing.T) {
+	ts := newGReaderTestServer(t, "")
+
+	resp, err := http.PostForm(ts.URL+"/accounts/ClientLogin", url.Values{"Email": {"alice"}, "Passwd": {"wrong"}})
+	if err != nil {
+		t.Fatalf("Login request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a wrong password, got %d", resp.StatusCode)
+	}
+
+	anonymous := &greaderClient{t: t, base: ts.URL}
+	anonymous.do(http.MethodGet, "/reader/api/0/subscription/list", nil, http.StatusUnauthorized)
+
+	forged := &greaderClient{t: t, base: ts.URL, auth: "alice/deadbeef"}
+	forged.do(http.MethodGet, "/reader/api/0/subscription/list", nil, http.StatusUnauthorized)
+
+	c := loginGReader(t, ts.URL)
+	var info map[string]string
+	c.getJSON("/reader/api/0/user-info", &info)
+	if info["userName"] != "alice" {
+		t.Errorf("Expected user alice, got %v", info)
+	}
+}
+
+func TestGReader_SubscriptionList(t *testing.T) {
+	c := loginGReader(t, newGReaderTestServer(t, "").URL)
+
+	var body struct {
+		Subscriptions []greaderSubscription `json:"subscriptions"`
+	}
+	c.getJSON("/reader/api/0/subscription/list?output=json", &body)
+	if len(body.Subscriptions) != 2 {
+		t.Fatalf("Expected 2 subscriptions, got %d", len(body.Subscriptions))
+	}
+	go1 := body.Subscriptions[0]
+	if go1.ID != "feed/http://feeds.test/go" || go1.Title != "Go Blog" || go1.URL != "http://feeds.test/go" {
+		t.Errorf("Unexpected subscription: %+v", go1)
+	}
+}
+
+func TestGReader_StreamContents(t *testing.T) {
+	c := loginGReader(t, newGReaderTestServer(t, "").URL)
+
+	stream := c.stream("/reader/api/0/stream/contents/user/-/state/com.google/reading-list")
+	want := []string{"Rust 1.85 released", "Go 1.24 released", "Go 1.23 released"}
+	if got := streamTitles(stream); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected newest first %v, got %v", want, got)
+	}
+	item := stream.Items[0]
+	if !strings.HasPrefix(item.ID, greaderItemPrefix) || item.Origin.StreamID != "feed/http://feeds.test/rust" {
+		t.Errorf("Unexpected item: %+v", item)
+	}
+	if item.Alternate[0].Href != "http://rust.test/1.85" || item.Summary.Content != "The 2024 edition is stable" {
+		t.Errorf("Unexpected item link or summary: %+v", item)
+	}
+	if item.Published != time.Date(2025, 2, 20, 12, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("Unexpected published timestamp %d", item.Published)
+	}
+
+	// Feed streams are addressed with an escaped feed URL
+	feed := url.PathEscape("feed/http://feeds.test/go")
+	stream = c.stream("/reader/api/0/stream/contents/" + feed + "?r=o")
+	want = []string{"Go 1.23 released", "Go 1.24 released"}
+	if got := streamTitles(stream); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected oldest first %v, got %v", want, got)
+	}
+}
+
+func TestGReader_Continuation(t *testing.T) {
+	c := loginGReader(t, newGReaderTestServer(t, "").URL)
+
+	var got []string
+	path := "/reader/api/0/stream/contents/user/-/state/com.google/reading-list?n=2"
+	for range 3 {
+		stream := c.stream(path)
+		got = append(got, streamTitles(stream)...)
+		if stream.Continuation == "" {
+			break
+		}
+		path = "/reader/api/0/stream/contents/user/-/state/com.google/reading-list?n=2&c=" + url.QueryEscape(stream.Continuation)
+	}
+	want := []string{"Rust 1.85 released", "Go 1.24 released", "Go 1.23 released"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected %v across pages, got %v", want, got)
+	}
+}
+
+func TestGReader_MarkReadAndStarred(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "reader.json")
+	c := loginGReader(t, newGReaderTestServer(t, statePath).URL)
+
+	var ids struct {
+		ItemRefs []greaderItemRef `json:"itemRefs"`
+	}
+	c.getJSON("/reader/api/0/stream/items/ids?s=user/-/state/com.google/reading-list&n=1000", &ids)
+	if len(ids.ItemRefs) != 3 {
+		t.Fatalf("Expected 3 item refs, got %d", len(ids.ItemRefs))
+	}
+	newest := ids.ItemRefs[0].ID
+
+	c.do(http.MethodPost, "/reader/api/0/edit-tag", url.Values{
+		"i": {newest},
+		"a": {"user/-/state/com.google/read", "user/-/state/com.google/starred"},
+	}, http.StatusUnauthorized)
+
+	c.do(http.MethodPost, "/reader/api/0/edit-tag", url.Values{
+		"T": {c.token},
+		"i": {newest},
+		"a": {"user/1/state/com.google/read", "user/-/state/com.google/starred"},
+	}, http.StatusOK)
+
+	unread := c.stream("/reader/api/0/stream/contents/user/-/state/com.google/reading-list?xt=user/-/state/com.google/read")
+	if got := streamTitles(unread); len(got) != 2 || got[0] != "Go 1.24 released" {
+		t.Errorf("Expected the read item to be excluded, got %v", got)
+	}
+	starred := c.stream("/reader/api/0/stream/contents/user/-/state/com.google/starred")
+	if got := streamTitles(starred); len(got) != 1 || got[0] != "Rust 1.85 released" {
+		t.Errorf("Expected the starred item, got %v", got)
+	}
+
+	var counts struct {
+		UnreadCounts []greaderUnreadCount `json:"unreadcounts"`
+	}
+	c.getJSON("/reader/api/0/unread-count?output=json", &counts)
+	total := counts.UnreadCounts[len(counts.UnreadCounts)-1]
+	if total.ID != greaderReadingList || total.Count != 2 {
+		t.Errorf("Expected 2 unread items in total, got %+v", total)
+	}
+
+	// Flags survive a restart through the state file
+	c = loginGReader(t, newGReaderTestServer(t, statePath).URL)
+	form := url.Values{"i": {greaderItemPrefix + fmt.Sprintf("%016x", mustParseShortID(t, newest))}}
+	contents := greaderStream{}
+	if err := json.Unmarshal(c.do(http.MethodPost, "/reader/api/0/stream/items/contents", form, http.StatusOK), &contents); err != nil {
+		t.Fatalf("Failed to decode item contents: %v", err)
+	}
+	if len(contents.Items) != 1 {
+		t.Fatalf("Expected the requested item, got %d items", len(contents.Items))
+	}
+	categories := strings.Join(contents.Items[0].Categories, " ")
+	if !strings.Contains(categories, greaderRead) || !strings.Contains(categories, greaderStarred) {
+		t.Errorf("Expected read and starred flags after restart, got %v", categories)
+	}
+}
+
+func TestGReader_MarkAllAsRead(t *testing.T) {
+	c := loginGReader(t, newGReaderTestServer(t, "").URL)
+
+	// Only items published up to ts are marked
+	ts := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC).UnixMicro()
+	c.do(http.MethodPost, "/reader/api/0/mark-all-as-read", url.Values{
+		"T":  {c.token},
+		"s":  {"feed/http://feeds.test/go"},
+		"ts": {fmt.Sprint(ts)},
+	}, http.StatusOK)
+
+	unread := c.stream("/reader/api/0/stream/contents/user/-/state/com.google/reading-list?xt=user/-/state/com.google/read")
+	if got := streamTitles(unread); len(got) != 1 || got[0] != "Rust 1.85 released" {
+		t.Errorf("Expected only the Rust item to stay unread, got %v", got)
+	}
+
+	c.do(http.MethodPost, "/reader/api/0/edit-tag", url.Values{
+		"T": {c.token},
+		"i": {greaderItemPrefix + fmt.Sprintf("%016x", greaderItemID(testItems()[0]))},
+		"r": {"user/-/state/com.google/read"},
+	}, http.StatusOK)
+	unread = c.stream("/reader/api/0/stream/contents/user/-/state/com.google/reading-list?xt=user/-/state/com.google/read")
+	if got := streamTitles(unread); len(got) != 2 {
+		t.Errorf("Expected the item to be unread again, got %v", got)
+	}
+}
+
+func TestGReader_DisabledByDefault(t *testing.T) {
+	ts := newTestServer(t, stubParse(testItems()))
+	resp, err := http.PostForm(ts.URL+"/accounts/ClientLogin", url.Values{"Email": {"alice"}, "Passwd": {"s3cret"}})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 without -greader-user, got %d", resp.StatusCode)
+	}
+}
+
+func TestParseGReaderItemIDs(t *testing.T) {
+	ids, err := parseGReaderItemIDs([]string{"tag:google.com,2005:reader/item/ffffffffffffffff", "-1", "42"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if ids[0] != ids[1] || ids[2] != 42 {
+		t.Errorf("Expected long and short forms to agree, got %v", ids)
+	}
+	if _, err := parseGReaderItemIDs([]string{"nope"}); err == nil {
+		t.Error("Expected error for an invalid id, got nil")
+	}
+}
+
+func mustParseShortID(t *testing.T, id string) uint64 {
+	t.Helper()
+	ids, err := parseGReaderItemIDs([]string{id})
+	if err != nil {
+		t.Fatalf("Invalid short id %q: %v", id, err)
+	}
+	return ids[0]
+}
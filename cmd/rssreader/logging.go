@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+)
+
+// logFlags are the flags controlling the structured log of feed fetches.
+type logFlags struct {
+	level  *string
+	format *string
+}
+
+func addLogFlags(flags *flag.FlagSet) logFlags {
+	return logFlags{
+		level:  flags.String("log-level", "", "Log every feed fetch at this level and above: debug, info, warn, error (default no fetch log)"),
+		format: flags.String("log-format", "text", "Format of the log: text or json"),
+	}
+}
+
+// apply returns the Parse options logging feed fetches to the redacting
+// standard error, if -log-level is set. With -log-level or -log-format=json,
+// the messages of the command go through the same slog handler, so that
+// every line has the same format.
+func (f logFlags) apply() ([]rssreader.Option, error) {
+	var newHandler func(opts *slog.HandlerOptions) slog.Handler
+	switch *f.format {
+	case "text":
+		newHandler = func(opts *slog.HandlerOptions) slog.Handler { return slog.NewTextHandler(logRedactor, opts) }
+	case "json":
+		newHandler = func(opts *slog.HandlerOptions) slog.Handler { return slog.NewJSONHandler(logRedactor, opts) }
+	default:
+		return nil, fmt.Errorf("invalid -log-format %q: use text or json", *f.format)
+	}
+	var level slog.Level
+	if *f.level != "" {
+		if err := level.UnmarshalText([]byte(*f.level)); err != nil {
+			return nil, fmt.Errorf("invalid -log-level %q: use debug, info, warn or error", *f.level)
+		}
+	} else if *f.format == "text" {
+		return nil, nil
+	}
+
+	// The messages of the command are shown whatever the level of the fetch
+	// log, as they are without the flags
+	log.SetFlags(0)
+	log.SetOutput(logWriter{slog.New(newHandler(&slog.HandlerOptions{Level: slog.LevelDebug}))})
+
+	if *f.level == "" {
+		return nil, nil
+	}
+	logger := slog.New(newHandler(&slog.HandlerOptions{Level: level}))
+	return []rssreader.Option{rssreader.WithLogger(logger)}, nil
+}
+
+// logWriter turns the lines of the standard logger into slog records, at
+// error level for "Error: " lines, warn level for "Warning: " lines and info
+// level otherwise.
+type logWriter struct {
+	logger *slog.Logger
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSuffix(string(p), "\n")
+	level := slog.LevelInfo
+	if rest, ok := strings.CutPrefix(msg, "Error: "); ok {
+		level, msg = slog.LevelError, rest
+	} else if rest, ok := strings.CutPrefix(msg, "Warning: "); ok {
+		level, msg = slog.LevelWarn, rest
+	}
+	w.logger.Log(context.Background(), level, msg)
+	return len(p), nil
+}
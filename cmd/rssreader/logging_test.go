@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"log"
+	"log/slog"
+	"testing"
+)
+
+func TestLogFlags(t *testing.T) {
+	tests := []struct {
+		args    []string
+		options int
+		wantErr bool
+	}{
+		{nil, 0, false},
+		{[]string{"-log-level=debug"}, 1, false},
+		{[]string{"-log-level=WARN", "-log-format=json"}, 1, false},
+		{[]string{"-log-format=json"}, 0, false},
+		{[]string{"-log-level=verbose"}, 0, true},
+		{[]string{"-log-level=info", "-log-format=xml"}, 0, true},
+	}
+	output, logFlags := log.Writer(), log.Flags()
+	t.Cleanup(func() {
+		log.SetOutput(output)
+		log.SetFlags(logFlags)
+	})
+
+	for _, tt := range tests {
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		logging := addLogFlags(flags)
+		if err := flags.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		opts, err := logging.apply()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%v: expected error %v, got %v", tt.args, tt.wantErr, err)
+		}
+		if len(opts) != tt.options {
+			t.Errorf("%v: expected %d options, got %d", tt.args, tt.options, len(opts))
+		}
+	}
+}
+
+func TestLogWriter(t *testing.T) {
+	var out bytes.Buffer
+	logger := log.New(logWriter{slog.New(slog.NewJSONHandler(&out, nil))}, "", 0)
+	logger.Printf("Error: parsing %s failed", "feed")
+	logger.Print("Warning: repaired feed")
+	logger.Print("Serving 2 feeds on :8080")
+
+	var records []map[string]any
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var record map[string]any
+		if err := dec.Decode(&record); err != nil {
+			t.Fatalf("Expected JSON records, got %v", err)
+		}
+		records = append(records, record)
+	}
+	want := [][2]string{{"ERROR", "parsing feed failed"}, {"WARN", "repaired feed"}, {"INFO", "Serving 2 feeds on :8080"}}
+	if len(records) != len(want) {
+		t.Fatalf("Expected %d records, got %v", len(want), records)
+	}
+	for i, w := range want {
+		if records[i]["level"] != w[0] || records[i]["msg"] != w[1] {
+			t.Errorf("Expected %s %q, got %v", w[0], w[1], records[i])
+		}
+	}
+}
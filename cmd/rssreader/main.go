@@ -3,25 +3,84 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/http"
+	"net/netip"
 	"os"
+	"os/signal"
+	"slices"
+	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	rssreader "github.com/RssReaderProject/RssReader"
+	"github.com/RssReaderProject/RssReader/metrics"
+	"github.com/RssReaderProject/RssReader/notify"
 )
 
 func main() {
+	log.SetOutput(logRedactor)
+
+	// Dispatch subcommands before parsing the default flag set
+	if len(os.Args) > 1 && (os.Args[1] == "serve" || os.Args[1] == "download") {
+		run := runServe
+		if os.Args[1] == "download" {
+			run = runDownload
+		}
+		if err := run(os.Args[2:]); err != nil {
+			log.Printf("Error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Define command line flags
 	var (
-		urls    = flag.String("urls", "", "Comma-separated list of RSS feed URLs")
-		format  = flag.String("format", "json", "Output format: json, text")
-		timeout = flag.Duration("timeout", 30*time.Second, "Timeout for fetching feeds")
-		help    = flag.Bool("help", false, "Show help message")
+		urls      = flag.String("urls", "", "Comma-separated list of RSS feed URLs, local files (starting with /, ./ or ../), or - for stdin")
+		cfgPath   = flag.String("config", "", "JSON file with per-feed settings such as scraped HTML sources; its feeds are added to -urls")
+		format    = flag.String("format", "json", "Output format: json, text, csv, ndjson, markdown, html, rss, atom, jsonfeed")
+		fields    = flag.String("fields", "", "Comma-separated item fields for csv, ndjson and markdown formats (e.g. Title,Link,PublishDate)")
+		timeout   = flag.Duration("timeout", 30*time.Second, "Timeout for fetching feeds")
+		feedTitle = flag.String("feed-title", "RSS Reader", "Title of the merged feed for html, rss, atom and jsonfeed formats")
+		feedLink  = flag.String("feed-link", "", "Website URL of the merged feed for rss, atom and jsonfeed formats")
+		feedURL   = flag.String("feed-url", "", "URL the merged feed is published at for rss, atom and jsonfeed formats")
+		htmlDir   = flag.String("html-dir", "", "Write the html format as a static site into this directory instead of stdout")
+		pageSize  = flag.Int("page-size", 50, "Items per page for the html format when writing to -html-dir")
+		tmplPath  = flag.String("template", "", "Render items through a Go template file instead of -format (.html/.htm files use html/template)")
+		statePath = flag.String("state", "", "Only output and notify about items not seen in previous runs, remembering seen items in this file")
+		smtpAddr  = flag.String("smtp-addr", "", "SMTP server (host:port) for mailing a digest of the items")
+		smtpUser  = flag.String("smtp-user", "", "SMTP username; the password is read from $RSSREADER_SMTP_PASSWORD")
+		mailFrom  = flag.String("mail-from", "", "Sender address of the digest email")
+		mailTo    = flag.String("mail-to", "", "Comma-separated recipients of the digest email (requires -state or -watch)")
+		mailSubj  = flag.String("mail-subject", "", "Subject of the digest email (default \"RSS digest: N new items\")")
+		webhooks  = flag.String("webhook", "", "Comma-separated webhook URLs to POST new items to (requires -state or -watch); the signing secret is read from $RSSREADER_WEBHOOK_SECRET")
+		preset    = flag.String("webhook-preset", "json", "Webhook payload: json, slack, discord, mattermost")
+		batchSize = flag.Int("webhook-batch", 0, "Maximum items per webhook request (0 sends all at once)")
+		execCmd   = flag.String("exec", "", "Run this command for every new item (requires -state or -watch); {} is replaced by the item link, fields are set as RSS_* environment variables and the item is written as JSON to stdin")
+		execJobs  = flag.Int("exec-jobs", 1, "Maximum number of -exec commands running at once")
+		execLimit = flag.Duration("exec-timeout", 10*time.Second, "Timeout for each -exec command; all notifications together are limited by -timeout")
+		watch     = flag.Duration("watch", 0, "Poll the feeds at this interval and only output and notify about new items")
+		websubAt  = flag.String("websub-addr", "", "In watch mode, listen on this address for WebSub pushes from feeds that advertise a hub")
+		websubURL = flag.String("websub-callback", "", "Public URL hubs reach the -websub-addr listener at")
+		metricsAt = flag.String("metrics-addr", "", "In watch mode, serve Prometheus metrics of the feed fetches at /metrics on this address")
+		fullText  = flag.String("fulltext", "", "Extract the full article from the item links of these comma-separated feed URLs, or all for every feed")
+		ftCache   = flag.String("fulltext-cache", "", "Directory to cache extracted articles in (default in memory)")
+		blockPriv = flag.Bool("block-private", false, "Refuse to fetch from private, loopback, link-local and other non-public addresses, and to read local feed files")
+		allowPriv = flag.String("allow-private", "", "Comma-separated CIDR ranges or IPs that -block-private still allows")
+		lenient   = flag.Bool("lenient", false, "Repair malformed XML feeds and keep the items that parse, logging a warning, instead of failing")
+		help      = flag.Bool("help", false, "Show help message")
 	)
+	redact := addRedactFlags(flag.CommandLine)
+	logging := addLogFlags(flag.CommandLine)
 
 	flag.Parse()
 
@@ -31,49 +90,246 @@ func main() {
 		fmt.Println()
 		fmt.Println("Usage:")
 		fmt.Println("  go run cmd/rssreader/main.go -urls=\"https://example.com/feed.xml,https://another.com/rss\"")
+		fmt.Println("  go run cmd/rssreader/main.go serve -urls=\"https://example.com/feed.xml\" -addr=:8080")
+		fmt.Println("  go run cmd/rssreader/main.go download -urls=\"https://example.com/podcast.xml\" -dir=podcasts")
 		fmt.Println()
 		fmt.Println("Flags:")
 		flag.PrintDefaults()
 		return
 	}
 
+	// Set up logging first, so that every message has the requested format
+	logOpts, err := logging.apply()
+	if err != nil {
+		log.Printf("Error: %v", err)
+		os.Exit(1)
+	}
+
 	// Check if URLs are provided
-	if *urls == "" {
-		log.Print("Error: -urls flag is required. Use -help for usage information.")
+	if *urls == "" && *cfgPath == "" {
+		log.Print("Error: -urls or -config flag is required. Use -help for usage information.")
 		os.Exit(1)
 	}
 
-	// Parse URLs from comma-separated string
-	urlList := []string{}
-	if *urls != "" {
-		// Simple comma splitting - in a real app you might want more sophisticated parsing
-		for _, url := range strings.Split(*urls, ",") {
-			url = strings.TrimSpace(url)
-			if url != "" {
-				urlList = append(urlList, url)
-			}
+	cfg, err := loadConfig(*cfgPath)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		os.Exit(1)
+	}
+
+	client, err := newHTTPClient(*blockPriv, *allowPriv)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		os.Exit(1)
+	}
+
+	// Validate the field selection before fetching anything
+	if _, err := parseFields(*fields, nil); err != nil {
+		log.Printf("Error: %v", err)
+		os.Exit(1)
+	}
+
+	// Load the output template up front so syntax errors fail fast
+	var tmpl executor
+	if *tmplPath != "" {
+		tmpl, err = loadTemplate(*tmplPath)
+		if err != nil {
+			log.Printf("Error: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	// Load the seen-item state and configure notifiers before fetching anything
+	var state *seenState
+	if *statePath != "" {
+		state, err = loadState(*statePath)
+		if err != nil {
+			log.Printf("Error: %v", err)
+			os.Exit(1)
 		}
 	}
 
+	// Notifiers only send the new items, which needs the seen items of an
+	// earlier run
+	remembers := *statePath != "" || *watch > 0
+
+	var notifiers []notify.Notifier
+	if *mailTo != "" {
+		if !remembers {
+			log.Print("Error: -mail-to requires -state or -watch")
+			os.Exit(1)
+		}
+		if *smtpAddr == "" || *mailFrom == "" {
+			log.Print("Error: -mail-to requires -smtp-addr and -mail-from")
+			os.Exit(1)
+		}
+		notifiers = append(notifiers, &notify.SMTP{
+			Addr:     *smtpAddr,
+			Username: *smtpUser,
+			Password: os.Getenv("RSSREADER_SMTP_PASSWORD"),
+			From:     *mailFrom,
+			To:       splitList(*mailTo),
+			Subject:  *mailSubj,
+		})
+	}
+
+	if *webhooks != "" {
+		if !remembers {
+			log.Print("Error: -webhook requires -state or -watch")
+			os.Exit(1)
+		}
+		if err := notify.ValidatePreset(*preset); err != nil {
+			log.Printf("Error: %v", err)
+			os.Exit(1)
+		}
+		for _, hookURL := range splitList(*webhooks) {
+			notifiers = append(notifiers, &notify.Webhook{
+				URL:       hookURL,
+				Preset:    *preset,
+				Secret:    os.Getenv("RSSREADER_WEBHOOK_SECRET"),
+				BatchSize: *batchSize,
+			})
+		}
+	}
+
+	if *execCmd != "" {
+		if !remembers {
+			log.Print("Error: -exec requires -state or -watch")
+			os.Exit(1)
+		}
+		command, err := splitCommand(*execCmd)
+		if err != nil {
+			log.Printf("Error: invalid -exec command: %v", err)
+			os.Exit(1)
+		}
+		notifiers = append(notifiers, &notify.Exec{
+			Command:     command,
+			Concurrency: *execJobs,
+			Timeout:     *execLimit,
+			Stdout:      os.Stdout,
+		})
+	}
+
+	if *websubAt != "" && (*watch <= 0 || *websubURL == "") {
+		log.Print("Error: -websub-addr requires -watch and -websub-callback")
+		os.Exit(1)
+	}
+
+	if *metricsAt != "" && *watch <= 0 {
+		log.Print("Error: -metrics-addr requires -watch")
+		os.Exit(1)
+	}
+
+	// Parse URLs from comma-separated string
+	urlList := cfg.urls(splitList(*urls))
 	if len(urlList) == 0 {
 		log.Print("Error: No valid URLs provided")
 		os.Exit(1)
 	}
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	opts := append(redact.apply(), rssreader.WithHTTPClient(client))
+	opts = append(opts, logOpts...)
+	if *blockPriv {
+		opts = append(opts, rssreader.WithoutLocalFiles())
+	}
+	if *lenient {
+		opts = append(opts, rssreader.WithLenientParsing(logRepairs))
+	}
+	fetchMetrics := metrics.New()
+	if *metricsAt != "" {
+		opts = append(opts, rssreader.WithFetchObserver(fetchMetrics))
+	}
+	parse := cfg.parseFunc(opts...)
+	if slices.Contains(urlList, "-") {
+		if *watch > 0 {
+			log.Print("Error: reading a feed from stdin (-) cannot be combined with -watch")
+			os.Exit(1)
+		}
+		parse = withStdin(parse, os.Stdin, append(cfg.options(), opts...)...)
+	}
+	var fullTextFetcher *rssreader.FullTextFetcher
+	if *fullText != "" {
+		fullTextFetcher = newFullTextFetcher(*fullText, *ftCache, client)
+		parse = withFullText(parse, fullTextFetcher)
+	}
+
+	r := &runner{
+		urls:      urlList,
+		timeout:   *timeout,
+		parse:     parse,
+		fullText:  fullTextFetcher,
+		state:     state,
+		notifiers: notifiers,
+		output: func(items []rssreader.RssItem) {
+			outputItems(outputConfig{
+				format:    *format,
+				fields:    *fields,
+				tmpl:      tmpl,
+				htmlDir:   *htmlDir,
+				pageSize:  *pageSize,
+				feedTitle: *feedTitle,
+				feedLink:  *feedLink,
+				feedURL:   *feedURL,
+			}, items)
+		},
+	}
+
+	// Keep polling until interrupted in watch mode
+	if *watch > 0 {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if *websubAt != "" {
+			if err := startWebSub(ctx, r, *websubAt, *websubURL, client, append(cfg.options(), opts...)); err != nil {
+				log.Printf("Error: %v", err)
+				os.Exit(1)
+			}
+		}
+		if *metricsAt != "" {
+			if err := startMetrics(ctx, *metricsAt, fetchMetrics); err != nil {
+				log.Printf("Error: %v", err)
+				os.Exit(1)
+			}
+		}
+		r.watch(ctx, *watch)
+		return
+	}
 
 	// Parse RSS feeds
-	items, err := rssreader.Parse(ctx, urlList)
+	items, err := r.fetch(context.Background())
 	if err != nil {
 		log.Printf("Error parsing RSS feeds: %v", err)
 		os.Exit(1)
 	}
 
-	defer cancel()
+	if err := r.process(context.Background(), items, time.Now()); err != nil {
+		log.Printf("Error processing items: %v", err)
+		os.Exit(1)
+	}
+}
 
-	// Output results based on format
-	switch *format {
+// outputConfig collects the flags that control how items are written.
+type outputConfig struct {
+	format    string
+	fields    string
+	tmpl      executor
+	htmlDir   string
+	pageSize  int
+	feedTitle string
+	feedLink  string
+	feedURL   string
+}
+
+// outputItems writes items to stdout through the template if one is set,
+// otherwise in the configured format.
+func outputItems(cfg outputConfig, items []rssreader.RssItem) {
+	if cfg.tmpl != nil {
+		if err := outputTemplate(os.Stdout, cfg.tmpl, items); err != nil {
+			log.Printf("Error outputting template: %v", err)
+		}
+		return
+	}
+
+	switch cfg.format {
 	case "json":
 		err := outputJSON(items)
 		if err != nil {
@@ -81,9 +337,240 @@ func main() {
 		}
 	case "text":
 		outputText(items)
+	case "csv", "ndjson", "markdown":
+		if err := outputFields(cfg.format, cfg.fields, items); err != nil {
+			log.Printf("Error outputting %s: %v", cfg.format, err)
+		}
+	case "html":
+		opts := digestOptions{
+			Title:     cfg.feedTitle,
+			Location:  time.Local,
+			Generated: time.Now(),
+		}
+		if err := outputDigest(cfg.htmlDir, cfg.pageSize, opts, items); err != nil {
+			log.Printf("Error outputting html: %v", err)
+		}
+	case "rss", "atom", "jsonfeed":
+		info := rssreader.FeedInfo{
+			Title:       cfg.feedTitle,
+			Link:        cfg.feedLink,
+			FeedURL:     cfg.feedURL,
+			Description: "Aggregated by RSS Reader",
+		}
+		if err := outputFeed(cfg.format, info, items); err != nil {
+			log.Printf("Error outputting %s: %v", cfg.format, err)
+		}
 	default:
-		log.Printf("Unknown format: %s. Supported formats: json, text", *format)
+		log.Printf("Unknown format: %s. Supported formats: json, text, csv, ndjson, markdown, html, rss, atom, jsonfeed", cfg.format)
+	}
+}
+
+// startWebSub serves the WebSub callback on addr and routes pushed items
+// through r. Pushes from https hubs are signed with $RSSREADER_WEBSUB_SECRET,
+// or a random secret if it is unset. Hubs are contacted with client, and
+// pushed content is parsed with opts like the polled feeds.
+func startWebSub(ctx context.Context, r *runner, addr, callbackURL string, client *http.Client, opts []rssreader.Option) error {
+	secret := os.Getenv("RSSREADER_WEBSUB_SECRET")
+	if secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		secret = hex.EncodeToString(b)
+	}
+	r.websub = &rssreader.Subscriber{
+		CallbackURL: callbackURL,
+		Client:      client,
+		Secret:      secret,
+		Options:     opts,
+		OnItems: func(_ string, items []rssreader.RssItem) {
+			r.push(ctx, items)
+		},
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("websub listener: %w", err)
 	}
+	httpServer := &http.Server{Handler: r.websub, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Error serving WebSub callback: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		_ = httpServer.Close()
+	}()
+	return nil
+}
+
+// startMetrics serves m at /metrics on addr until ctx is done.
+func startMetrics(ctx context.Context, addr string, m *metrics.Metrics) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("metrics listener: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m.Handler())
+	httpServer := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Error serving metrics: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		_ = httpServer.Close()
+	}()
+	return nil
+}
+
+// withStdin returns a parse function that reads the feed listed as "-" from
+// stdin with opts and all other feeds with parse. Items are merged in publish
+// date order.
+func withStdin(parse parseFunc, stdin io.Reader, opts ...rssreader.Option) parseFunc {
+	return func(ctx context.Context, urls []string) ([]rssreader.RssItem, error) {
+		var rest []string
+		for _, url := range urls {
+			if url != "-" {
+				rest = append(rest, url)
+			}
+		}
+
+		var items []rssreader.RssItem
+		var err error
+		if len(rest) > 0 {
+			items, err = parse(ctx, rest)
+		}
+
+		stdinItems, stdinErr := rssreader.ParseReader(ctx, stdin, "stdin", opts...)
+		if stdinErr != nil {
+			stdinErr = fmt.Errorf("failed to parse feed from stdin: %w", stdinErr)
+		}
+		items = append(items, stdinItems...)
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].PublishDate.Before(items[j].PublishDate)
+		})
+		return items, errors.Join(err, stdinErr)
+	}
+}
+
+// newHTTPClient returns the client feeds and articles are fetched with. With
+// blockPrivate, it refuses non-public addresses except those in the
+// comma-separated allow list of CIDR ranges and IPs.
+func newHTTPClient(blockPrivate bool, allow string) (*http.Client, error) {
+	if !blockPrivate {
+		if allow != "" {
+			return nil, errors.New("-allow-private requires -block-private")
+		}
+		return http.DefaultClient, nil
+	}
+	guard := &rssreader.AddressGuard{}
+	for _, entry := range splitList(allow) {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(entry)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid -allow-private range %q", entry)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		guard.Allow = append(guard.Allow, prefix)
+	}
+	return guard.Client(), nil
+}
+
+// logRepairs logs the repairs lenient parsing made to a malformed feed.
+func logRepairs(feedURL string, warnings []string) {
+	log.Printf("Warning: repaired feed %s: %s", feedURL, strings.Join(warnings, "; "))
+}
+
+// newFullTextFetcher configures full text extraction for the feeds in list,
+// or for every feed if list is "all".
+func newFullTextFetcher(list, cacheDir string, client *http.Client) *rssreader.FullTextFetcher {
+	fetcher := &rssreader.FullTextFetcher{Cache: &rssreader.MemoryCache{}, Client: client}
+	if list != "all" {
+		fetcher.Feeds = splitList(list)
+	}
+	if cacheDir != "" {
+		fetcher.Cache = rssreader.DirCache(cacheDir)
+	}
+	return fetcher
+}
+
+// withFullText wraps parse to add the full article text to the items.
+// Pages that cannot be extracted are logged and leave the item as it is.
+func withFullText(parse parseFunc, fetcher *rssreader.FullTextFetcher) parseFunc {
+	return func(ctx context.Context, urls []string) ([]rssreader.RssItem, error) {
+		items, err := parse(ctx, urls)
+		if ftErr := fetcher.Fetch(ctx, items); ftErr != nil {
+			log.Printf("Error fetching full text: %v", ftErr)
+		}
+		return items, err
+	}
+}
+
+// splitList splits a comma-separated list such as feed URLs, dropping empty entries.
+func splitList(s string) []string {
+	list := []string{}
+	// Simple comma splitting - in a real app you might want more sophisticated parsing
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// splitCommand splits a command line into arguments. Whitespace separates
+// arguments unless it is quoted with single or double quotes, and a backslash
+// escapes the next character outside single quotes.
+func splitCommand(s string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if escaped || quote != 0 {
+		return nil, fmt.Errorf("unterminated quote or escape in %q", s)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	if len(args) == 0 {
+		return nil, errors.New("empty command")
+	}
+	return args, nil
 }
 
 func outputJSON(items []rssreader.RssItem) error {
@@ -95,6 +582,52 @@ func outputJSON(items []rssreader.RssItem) error {
 	return nil
 }
 
+// outputFields writes items in one of the formats that support -fields.
+func outputFields(format, fieldList string, items []rssreader.RssItem) error {
+	switch format {
+	case "csv":
+		fields, err := parseFields(fieldList, defaultFields)
+		if err != nil {
+			return err
+		}
+		return outputCSV(os.Stdout, items, fields)
+	case "ndjson":
+		fields, err := parseFields(fieldList, nil)
+		if err != nil {
+			return err
+		}
+		return outputNDJSON(os.Stdout, items, fields)
+	default:
+		fields, err := parseFields(fieldList, defaultMarkdownFields)
+		if err != nil {
+			return err
+		}
+		return outputMarkdown(os.Stdout, items, fields)
+	}
+}
+
+// outputDigest writes the HTML digest as a single page to stdout, or as a
+// paginated static site when dir is set.
+func outputDigest(dir string, pageSize int, opts digestOptions, items []rssreader.RssItem) error {
+	if dir == "" {
+		return writeDigest(os.Stdout, buildDigestPages(items, opts)[0])
+	}
+	opts.PageSize = pageSize
+	return writeDigestSite(dir, items, opts)
+}
+
+// outputFeed re-publishes the merged items as an RSS, Atom or JSON Feed document.
+func outputFeed(format string, info rssreader.FeedInfo, items []rssreader.RssItem) error {
+	switch format {
+	case "rss":
+		return rssreader.WriteRSS(os.Stdout, info, items)
+	case "atom":
+		return rssreader.WriteAtom(os.Stdout, info, items)
+	default:
+		return rssreader.WriteJSONFeed(os.Stdout, info, items)
+	}
+}
+
 func outputText(items []rssreader.RssItem) {
 	for i, item := range items {
 		fmt.Printf("=== Item %d ===\n", i+1)
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
+	"net/http"
 	"os"
+	"slices"
+	"strings"
 	"testing"
+
+	rssreader "github.com/RssReaderProject/RssReader"
 )
 
 func TestMainCompiles(t *testing.T) {
@@ -25,3 +31,124 @@ func TestHelpFlag(t *testing.T) {
 }
 
 // TODO(jannis-seemann): Mock Parse and test that this CLI tool works as expected.
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"open {}", []string{"open", "{}"}},
+		{`notify-send  "New item" '$RSS_TITLE'`, []string{"notify-send", "New item", "$RSS_TITLE"}},
+		{`sh -c "echo \"$RSS_TITLE\" >> log"`, []string{"sh", "-c", `echo "$RSS_TITLE" >> log`}},
+		{`say ''`, []string{"say", ""}},
+		{`a\ b c`, []string{"a b", "c"}},
+	}
+	for _, tt := range tests {
+		got, err := splitCommand(tt.input)
+		if err != nil {
+			t.Errorf("splitCommand(%q) returned error: %v", tt.input, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("splitCommand(%q): expected %q, got %q", tt.input, tt.want, got)
+		}
+	}
+
+	for _, input := range []string{"", "   ", `echo "unterminated`, `echo \`} {
+		if _, err := splitCommand(input); err == nil {
+			t.Errorf("Expected error for %q, got nil", input)
+		}
+	}
+}
+
+func TestWithStdin(t *testing.T) {
+	stdin := strings.NewReader(`<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Piped Feed</title>
+    <item>
+      <title>Piped item</title>
+      <link>http://piped.test/1</link>
+      <pubDate>Mon, 01 Jan 2024 12:00:00 GMT</pubDate>
+    </item>
+  </channel>
+</rss>`)
+
+	var gotURLs []string
+	parse := func(_ context.Context, urls []string) ([]rssreader.RssItem, error) {
+		gotURLs = urls
+		return testItems(), nil
+	}
+
+	items, err := withStdin(parse, stdin)(context.Background(), []string{"http://feeds.test/go", "-"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !slices.Equal(gotURLs, []string{"http://feeds.test/go"}) {
+		t.Errorf("Expected only network feeds to be passed on, got %v", gotURLs)
+	}
+	if len(items) != 4 {
+		t.Fatalf("Expected 4 items, got %d", len(items))
+	}
+	if items[0].Title != "Piped item" || items[0].RssURL != "stdin" {
+		t.Errorf("Expected the stdin item first by date, got %+v", items[0])
+	}
+}
+
+func TestWithStdin_AppliesOptions(t *testing.T) {
+	// A stray "<" only parses leniently
+	stdin := strings.NewReader(`<rss version="2.0"><channel><title>Piped</title><item><title>a < b</title></item></channel></rss>`)
+	var repaired []string
+	opts := []rssreader.Option{rssreader.WithLenientParsing(func(feedURL string, _ []string) {
+		repaired = append(repaired, feedURL)
+	})}
+
+	items, err := withStdin(stubParse(nil), stdin, opts...)(context.Background(), []string{"-"})
+	if err != nil {
+		t.Fatalf("Expected the lenient option to repair stdin, got: %v", err)
+	}
+	if len(items) != 1 || !slices.Equal(repaired, []string{"stdin"}) {
+		t.Errorf("Expected 1 repaired item from stdin, got %+v and repairs %v", items, repaired)
+	}
+}
+
+func TestNewFullTextFetcher(t *testing.T) {
+	if fetcher := newFullTextFetcher("all", "", http.DefaultClient); fetcher.Feeds != nil {
+		t.Errorf("Expected all feeds to be selected, got %v", fetcher.Feeds)
+	}
+	fetcher := newFullTextFetcher("http://a.test/feed, http://b.test/feed", t.TempDir(), http.DefaultClient)
+	if !slices.Equal(fetcher.Feeds, []string{"http://a.test/feed", "http://b.test/feed"}) {
+		t.Errorf("Unexpected feeds: %v", fetcher.Feeds)
+	}
+	if _, ok := fetcher.Cache.(rssreader.DirCache); !ok {
+		t.Errorf("Expected a directory cache, got %T", fetcher.Cache)
+	}
+}
+
+func TestWithFullText_KeepsItemsWhenExtractionFails(t *testing.T) {
+	items := []rssreader.RssItem{{Title: "Unreachable", Link: "http://127.0.0.1:1/article", RssURL: "http://a.test/feed"}}
+	parse := withFullText(stubParse(items), newFullTextFetcher("all", "", http.DefaultClient))
+
+	got, err := parse(context.Background(), []string{"http://a.test/feed"})
+	if err != nil {
+		t.Fatalf("Expected extraction errors not to fail parsing, got: %v", err)
+	}
+	if len(got) != 1 || got[0].FullContent != "" {
+		t.Errorf("Expected the item without full text, got %+v", got)
+	}
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	if client, err := newHTTPClient(false, ""); err != nil || client != http.DefaultClient {
+		t.Errorf("Expected the default client, got %v, %v", client, err)
+	}
+	if _, err := newHTTPClient(false, "10.0.0.0/8"); err == nil {
+		t.Error("Expected -allow-private without -block-private to fail")
+	}
+	if _, err := newHTTPClient(true, "10.0.0.0/8, 192.168.1.5"); err != nil {
+		t.Errorf("Expected ranges and single IPs to be accepted, got: %v", err)
+	}
+	if _, err := newHTTPClient(true, "intranet"); err == nil {
+		t.Error("Expected an invalid range to fail")
+	}
+}
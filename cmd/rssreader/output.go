@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+)
+
+// defaultFields are shown by the csv format when -fields is not set.
+var defaultFields = []string{"Title", "Source", "SourceURL", "Link", "PublishDate", "Description", "RssURL"}
+
+// itemFields lists the RssItem fields selectable via -fields.
+var itemFields = append(slices.Clone(defaultFields), "Content", "FullContent", "Updated")
+
+// defaultMarkdownFields are shown by the markdown format when -fields is not set.
+var defaultMarkdownFields = []string{"Title", "Link", "PublishDate"}
+
+// parseFields resolves a comma-separated field list against itemFields,
+// ignoring case. An empty list selects the given defaults.
+func parseFields(s string, defaults []string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return defaults, nil
+	}
+
+	var fields []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		canonical := ""
+		for _, field := range itemFields {
+			if strings.EqualFold(field, name) {
+				canonical = field
+				break
+			}
+		}
+		if canonical == "" {
+			return nil, fmt.Errorf("unknown field %q. Supported fields: %s", name, strings.Join(itemFields, ", "))
+		}
+		fields = append(fields, canonical)
+	}
+	if len(fields) == 0 {
+		return defaults, nil
+	}
+	return fields, nil
+}
+
+// fieldValue returns the value of the named field, keeping the dates as time.Time.
+func fieldValue(item rssreader.RssItem, field string) any {
+	switch field {
+	case "Title":
+		return item.Title
+	case "Source":
+		return item.Source
+	case "SourceURL":
+		return item.SourceURL
+	case "Link":
+		return item.Link
+	case "PublishDate":
+		return item.PublishDate
+	case "Description":
+		return item.Description
+	case "RssURL":
+		return item.RssURL
+	case "Content":
+		return item.Content
+	case "FullContent":
+		return item.FullContent
+	case "Updated":
+		return item.Updated
+	default:
+		return nil
+	}
+}
+
+// fieldString formats a field value for the plain text based formats.
+func fieldString(item rssreader.RssItem, field string) string {
+	switch v := fieldValue(item, field).(type) {
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	case string:
+		return v
+	default:
+		return ""
+	}
+}
+
+// outputCSV writes a header row followed by one row per item.
+func outputCSV(w io.Writer, items []rssreader.RssItem, fields []string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(fields); err != nil {
+		return fmt.Errorf("encoding error: %w", err)
+	}
+
+	row := make([]string, len(fields))
+	for _, item := range items {
+		for i, field := range fields {
+			row[i] = fieldString(item, field)
+		}
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("encoding error: %w", err)
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("encoding error: %w", err)
+	}
+	return nil
+}
+
+// outputNDJSON writes one compact JSON object per line. Without a field
+// selection each object has the same shape as the json format's items.
+func outputNDJSON(w io.Writer, items []rssreader.RssItem, fields []string) error {
+	encoder := json.NewEncoder(w)
+	for _, item := range items {
+		var v any = item
+		if fields != nil {
+			obj := make(map[string]any, len(fields))
+			for _, field := range fields {
+				obj[field] = fieldValue(item, field)
+			}
+			v = obj
+		}
+		if err := encoder.Encode(v); err != nil {
+			return fmt.Errorf("encoding error: %w", err)
+		}
+	}
+	return nil
+}
+
+// outputMarkdown writes one section per source, in order of first
+// appearance, with a bullet per item linking to the article.
+func outputMarkdown(w io.Writer, items []rssreader.RssItem, fields []string) error {
+	showTitle, showLink, showDescription := false, false, false
+	var extra []string
+	for _, field := range fields {
+		switch field {
+		case "Title":
+			showTitle = true
+		case "Link":
+			showLink = true
+		case "Description":
+			showDescription = true
+		default:
+			extra = append(extra, field)
+		}
+	}
+
+	var b strings.Builder
+	for i, g := range rssreader.GroupBySource(items) {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		title := g.Source
+		if title == "" {
+			title = g.RssURL
+		}
+		if g.SourceURL != "" {
+			fmt.Fprintf(&b, "## [%s](%s)\n\n", markdownEscape(title), markdownURL(g.SourceURL))
+		} else {
+			fmt.Fprintf(&b, "## %s\n\n", markdownEscape(title))
+		}
+
+		for _, item := range g.Items {
+			var parts []string
+			label := ""
+			if showTitle {
+				label = markdownEscape(item.Title)
+			}
+			switch {
+			case showLink && item.Link != "" && label != "":
+				parts = append(parts, fmt.Sprintf("[%s](%s)", label, markdownURL(item.Link)))
+			case showLink && item.Link != "":
+				parts = append(parts, fmt.Sprintf("<%s>", markdownURL(item.Link)))
+			case label != "":
+				parts = append(parts, label)
+			}
+			for _, field := range extra {
+				if v := fieldString(item, field); v != "" {
+					parts = append(parts, markdownEscape(v))
+				}
+			}
+			fmt.Fprintf(&b, "- %s\n", strings.Join(parts, " — "))
+
+			if showDescription && item.Description != "" {
+				for _, line := range strings.Split(strings.TrimSpace(item.Description), "\n") {
+					fmt.Fprintf(&b, "  %s\n", markdownEscape(strings.TrimSpace(line)))
+				}
+			}
+		}
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("encoding error: %w", err)
+	}
+	return nil
+}
+
+// markdownEscaper escapes characters that would otherwise be interpreted as
+// Markdown or inline HTML.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`,
+)
+
+func markdownEscape(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// markdownURL percent-encodes characters that would terminate a Markdown link target.
+func markdownURL(s string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E").Replace(s)
+}
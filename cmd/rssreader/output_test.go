@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+)
+
+var update = flag.Bool("update", false, "Update golden files in testdata")
+
+// outputTestItems returns items whose fields exercise escaping in every format.
+func outputTestItems() []rssreader.RssItem {
+	return []rssreader.RssItem{
+		{
+			Title:       "Hello, \"World\"",
+			Source:      "Example Blog",
+			SourceURL:   "http://example.com/feed.xml",
+			Link:        "http://example.com/hello",
+			PublishDate: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+			Description: "First line\nsecond line with a, comma",
+			RssURL:      "http://example.com/feed.xml",
+		},
+		{
+			Title:       "Markdown *special* [chars]",
+			Source:      "Another Blog",
+			SourceURL:   "http://another.com/rss",
+			Link:        "http://another.com/posts/(1)",
+			PublishDate: time.Date(2006, 1, 3, 9, 0, 0, 0, time.UTC),
+			Description: "Uses <b>HTML</b> & friends",
+			RssURL:      "http://another.com/rss",
+		},
+		{
+			Title:       "Undated follow-up",
+			Source:      "Example Blog",
+			SourceURL:   "http://example.com/feed.xml",
+			Link:        "http://example.com/follow-up",
+			Description: "",
+			RssURL:      "http://example.com/feed.xml",
+		},
+	}
+}
+
+// assertGolden compares got with testdata/name, rewriting it when -update is set.
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o600); err != nil {
+			t.Fatalf("Failed to update golden file: %v", err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Output does not match %s\n--- got ---\n%s\n--- want ---\n%s", path, got, want)
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	fields, err := parseFields(" title, LINK ,publishdate", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	want := []string{"Title", "Link", "PublishDate"}
+	if len(fields) != len(want) {
+		t.Fatalf("Expected fields %v, got %v", want, fields)
+	}
+	for i := range want {
+		if fields[i] != want[i] {
+			t.Errorf("Expected field %q at %d, got %q", want[i], i, fields[i])
+		}
+	}
+
+	if fields, _ := parseFields("", defaultFields); len(fields) != len(defaultFields) {
+		t.Errorf("Expected defaults for empty field list, got %v", fields)
+	}
+
+	if _, err := parseFields("Title,Author", nil); err == nil {
+		t.Error("Expected error for unknown field, got nil")
+	}
+}
+
+func TestOutputCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := outputCSV(&buf, outputTestItems(), defaultFields); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	assertGolden(t, "items.csv.golden", buf.Bytes())
+}
+
+func TestOutputCSV_SelectedFields(t *testing.T) {
+	var buf bytes.Buffer
+	if err := outputCSV(&buf, outputTestItems(), []string{"PublishDate", "Title"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	assertGolden(t, "items_fields.csv.golden", buf.Bytes())
+}
+
+func TestOutputNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := outputNDJSON(&buf, outputTestItems(), nil); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	assertGolden(t, "items.ndjson.golden", buf.Bytes())
+}
+
+func TestOutputNDJSON_SelectedFields(t *testing.T) {
+	var buf bytes.Buffer
+	if err := outputNDJSON(&buf, outputTestItems(), []string{"Title", "PublishDate"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	assertGolden(t, "items_fields.ndjson.golden", buf.Bytes())
+}
+
+func TestOutputMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := outputMarkdown(&buf, outputTestItems(), defaultMarkdownFields); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	assertGolden(t, "items.md.golden", buf.Bytes())
+}
+
+func TestOutputMarkdown_SelectedFields(t *testing.T) {
+	var buf bytes.Buffer
+	if err := outputMarkdown(&buf, outputTestItems(), []string{"Title", "Source", "Description"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	assertGolden(t, "items_fields.md.golden", buf.Bytes())
+}
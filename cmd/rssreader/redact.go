@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"regexp"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+)
+
+// logRedactor is the output of the standard logger, so that no URL the
+// command logs reveals credentials.
+var logRedactor = &redactingWriter{w: os.Stderr}
+
+// urlPattern matches URLs in log lines, including those quoted by net/http.
+var urlPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]+`)
+
+// redactingWriter writes its input to w with every URL passed through
+// rssreader.RedactURL.
+type redactingWriter struct {
+	w      io.Writer
+	params []string
+}
+
+func (r *redactingWriter) Write(p []byte) (int, error) {
+	text := urlPattern.ReplaceAllStringFunc(string(p), func(url string) string {
+		return rssreader.RedactURL(url, r.params...)
+	})
+	if _, err := io.WriteString(r.w, text); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// redactFlags are the flags controlling how credentials in feed URLs are
+// redacted.
+type redactFlags struct {
+	urls   *bool
+	params *string
+}
+
+func addRedactFlags(flags *flag.FlagSet) redactFlags {
+	return redactFlags{
+		urls:   flags.Bool("redact-urls", false, "Redact credentials in feed URLs from the RssURL and SourceURL of output items"),
+		params: flags.String("sensitive-params", "", "Comma-separated query parameters redacted from URLs in addition to token, key, password and the other defaults"),
+	}
+}
+
+// apply makes the logger redact the configured parameters and returns the
+// matching Parse options.
+func (f redactFlags) apply() []rssreader.Option {
+	params := splitList(*f.params)
+	logRedactor.params = params
+	opts := []rssreader.Option{rssreader.WithSensitiveParams(params...)}
+	if *f.urls {
+		opts = append(opts, rssreader.WithRedactedURLs())
+	}
+	return opts
+}
+
+// redactor returns the function that redacts feed URLs for display, or
+// nil if they are shown as they are.
+func (f redactFlags) redactor() func(string) string {
+	if !*f.urls {
+		return nil
+	}
+	params := splitList(*f.params)
+	return func(url string) string {
+		return rssreader.RedactURL(url, params...)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+)
+
+func TestRedactingWriter(t *testing.T) {
+	var out strings.Builder
+	logger := log.New(&redactingWriter{w: &out, params: []string{"feed_key"}}, "", 0)
+	logger.Printf(`Error parsing RSS feeds: failed to parse feed https://alice:pw@feeds.test/a?token=t0ken: Get "https://feeds.test/b?feed_key=k3y&page=2": EOF`)
+
+	got := out.String()
+	for _, secret := range []string{"alice", "pw@", "t0ken", "k3y"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("Expected %q to be redacted, got %s", secret, got)
+		}
+	}
+	if !strings.Contains(got, `"https://feeds.test/b?feed_key=REDACTED&page=2"`) {
+		t.Errorf("Expected the quoted URL to stay intact apart from the secret, got %s", got)
+	}
+}
+
+func TestRedactFlags(t *testing.T) {
+	defer func() { logRedactor.params = nil }()
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	redact := addRedactFlags(flags)
+	if err := flags.Parse([]string{"-redact-urls", "-sensitive-params=feed_key"}); err != nil {
+		t.Fatal(err)
+	}
+	if opts := redact.apply(); len(opts) != 2 {
+		t.Errorf("Expected sensitive params and redacted URL options, got %d", len(opts))
+	}
+	if len(logRedactor.params) != 1 || logRedactor.params[0] != "feed_key" {
+		t.Errorf("Expected the logger to redact feed_key, got %v", logRedactor.params)
+	}
+
+	// /feeds lists the URLs like the redacted items refer to them
+	srv := newServer([]string{"http://feeds.test/go?feed_key=k3y"}, stubParse([]rssreader.RssItem{
+		{Title: "Go 1.24 released", Source: "Go Blog", RssURL: "http://feeds.test/go?feed_key=REDACTED"},
+	}), 5*time.Second)
+	srv.redact = redact.redactor()
+	if err := srv.refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	feeds := srv.feeds()
+	if feeds[0].URL != "http://feeds.test/go?feed_key=REDACTED" || feeds[0].ItemCount != 1 {
+		t.Errorf("Expected the redacted feed with its item, got %+v", feeds[0])
+	}
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+	"github.com/RssReaderProject/RssReader/metrics"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// parseFunc matches the signature of rssreader.Parse so tests can swap it out.
+type parseFunc func(ctx context.Context, urls []string) ([]rssreader.RssItem, error)
+
+// server exposes the aggregated items of a fixed set of feeds as a JSON API.
+type server struct {
+	urls    []string
+	parse   parseFunc
+	timeout time.Duration
+
+	// ctx is the lifetime of the server. Refreshes run on it rather than on
+	// the context of the request that triggered them, so a client giving up
+	// does not fail the refresh.
+	ctx context.Context
+
+	// refreshMu serializes refreshes so concurrent triggers don't pile up fetches.
+	refreshMu sync.Mutex
+
+	mu        sync.RWMutex
+	items     []rssreader.RssItem
+	refreshed time.Time
+
+	greader *greaderAPI // nil unless the Google Reader API is enabled
+
+	// metrics of the feed fetches, served at /metrics unless nil
+	metrics *metrics.Metrics
+
+	// redact hides credentials in the feed URLs listed by /feeds, matching
+	// the redacted RssURL of the items. Nil shows them as they are.
+	redact func(string) string
+}
+
+// feedInfo summarizes a single configured feed for the /feeds endpoint.
+type feedInfo struct {
+	URL           string
+	Title         string
+	Link          string
+	ItemCount     int
+	LastPublished time.Time
+}
+
+// itemsPage is the response body of the /items endpoint.
+type itemsPage struct {
+	Items      []rssreader.RssItem
+	NextCursor string `json:",omitempty"`
+}
+
+// refreshResult is the response body of the /refresh endpoint.
+type refreshResult struct {
+	ItemCount int
+	Refreshed time.Time
+	Error     string `json:",omitempty"`
+}
+
+// errorBody is the response body for any failed request.
+type errorBody struct {
+	Error string
+}
+
+func newServer(urls []string, parse parseFunc, timeout time.Duration) *server {
+	return &server{
+		urls:    urls,
+		parse:   parse,
+		timeout: timeout,
+		ctx:     context.Background(),
+	}
+}
+
+// runServe implements the "serve" subcommand.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	var (
+		urls     = fs.String("urls", "", "Comma-separated list of RSS feed URLs")
+		cfgPath  = fs.String("config", "", "JSON file with per-feed settings such as scraped HTML sources; its feeds are added to -urls")
+		addr     = fs.String("addr", ":8080", "Address to listen on")
+		timeout  = fs.Duration("timeout", 30*time.Second, "Timeout for fetching feeds")
+		interval = fs.Duration("refresh", 15*time.Minute, "Interval between automatic refreshes (0 disables)")
+		gUser    = fs.String("greader-user", "", "Enable the Google Reader API for this user; the password is read from $RSSREADER_GREADER_PASSWORD")
+		gState   = fs.String("greader-state", "", "File to persist read and starred flags of the Google Reader API in")
+		fullText = fs.String("fulltext", "", "Extract the full article from the item links of these comma-separated feed URLs, or all for every feed")
+		ftCache  = fs.String("fulltext-cache", "", "Directory to cache extracted articles in (default in memory)")
+		blockIPs = fs.Bool("block-private", false, "Refuse to fetch from private, loopback, link-local and other non-public addresses, and to read local feed files")
+		allowIPs = fs.String("allow-private", "", "Comma-separated CIDR ranges or IPs that -block-private still allows")
+		prom     = fs.Bool("metrics", false, "Serve Prometheus metrics of the feed fetches at /metrics")
+		lenient  = fs.Bool("lenient", false, "Repair malformed XML feeds and keep the items that parse, logging a warning, instead of failing")
+	)
+	redact := addRedactFlags(fs)
+	logging := addLogFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	logOpts, err := logging.apply()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*cfgPath)
+	if err != nil {
+		return err
+	}
+	urlList := cfg.urls(splitList(*urls))
+	if len(urlList) == 0 {
+		return errors.New("-urls or -config flag is required")
+	}
+
+	client, err := newHTTPClient(*blockIPs, *allowIPs)
+	if err != nil {
+		return err
+	}
+	opts := append(redact.apply(), rssreader.WithHTTPClient(client))
+	opts = append(opts, logOpts...)
+	if *blockIPs {
+		opts = append(opts, rssreader.WithoutLocalFiles())
+	}
+	if *lenient {
+		opts = append(opts, rssreader.WithLenientParsing(logRepairs))
+	}
+	var fetchMetrics *metrics.Metrics
+	if *prom {
+		fetchMetrics = metrics.New()
+		opts = append(opts, rssreader.WithFetchObserver(fetchMetrics))
+	}
+	parse := cfg.parseFunc(opts...)
+	if *fullText != "" {
+		parse = withFullText(parse, newFullTextFetcher(*fullText, *ftCache, client))
+	}
+	srv := newServer(urlList, parse, *timeout)
+	srv.redact = redact.redactor()
+	srv.metrics = fetchMetrics
+	if *gUser != "" {
+		password := os.Getenv("RSSREADER_GREADER_PASSWORD")
+		if password == "" {
+			return errors.New("-greader-user requires $RSSREADER_GREADER_PASSWORD")
+		}
+		state, err := loadReadState(*gState)
+		if err != nil {
+			return err
+		}
+		srv.greader = newGReaderAPI(srv, *gUser, password, state)
+	}
+
+	if err := srv.refresh(srv.ctx); err != nil {
+		log.Printf("Error parsing RSS feeds: %v", err)
+	}
+	if *interval > 0 {
+		go srv.refreshEvery(srv.ctx, *interval)
+	}
+
+	log.Printf("Serving %d feeds on %s", len(urlList), *addr)
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           srv.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return httpServer.ListenAndServe()
+}
+
+// handler returns the HTTP routes of the API.
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /feeds", s.handleFeeds)
+	mux.HandleFunc("GET /items", s.handleItems)
+	mux.HandleFunc("POST /refresh", s.handleRefresh)
+	if s.greader != nil {
+		s.greader.register(mux)
+	}
+	if s.metrics != nil {
+		mux.Handle("GET /metrics", s.metrics.Handler())
+	}
+	return mux
+}
+
+// refresh fetches all feeds and replaces the cached items. If some feeds
+// fail, the items of the feeds that parsed successfully replace their cached
+// ones, while the feeds without any fetched items keep their cached items.
+func (s *server) refresh(ctx context.Context) error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	items, err := s.parse(ctx, s.urls)
+	if err != nil {
+		items = append(slices.Clone(items), s.missingFeedItems(items)...)
+	}
+
+	// Parse leaves items unsorted when some feeds fail, so always impose
+	// a total order that cursors can rely on.
+	sorted := make([]rssreader.RssItem, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareItems(sorted[i], sorted[j]) < 0
+	})
+
+	s.mu.Lock()
+	s.items = sorted
+	s.refreshed = time.Now()
+	s.mu.Unlock()
+
+	return err
+}
+
+// missingFeedItems returns the cached items of the feeds that have no items
+// in fetched, which are the feeds that failed to refresh.
+func (s *server) missingFeedItems(fetched []rssreader.RssItem) []rssreader.RssItem {
+	refreshed := make(map[string]bool)
+	for _, item := range fetched {
+		refreshed[item.RssURL] = true
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var kept []rssreader.RssItem
+	for _, item := range s.items {
+		if !refreshed[item.RssURL] {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// refreshEvery refreshes the feeds periodically until ctx is done.
+func (s *server) refreshEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.refresh(ctx); err != nil {
+				log.Printf("Error parsing RSS feeds: %v", err)
+			}
+		}
+	}
+}
+
+func (s *server) handleFeeds(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.feeds())
+}
+
+// feeds summarizes every configured feed from the cached items.
+func (s *server) feeds() []feedInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	byURL := make(map[string]*feedInfo, len(s.urls))
+	feeds := make([]feedInfo, len(s.urls))
+	for i, url := range s.urls {
+		if s.redact != nil {
+			url = s.redact(url)
+		}
+		feeds[i].URL = url
+		byURL[url] = &feeds[i]
+	}
+	for _, item := range s.items {
+		info, ok := byURL[item.RssURL]
+		if !ok {
+			continue
+		}
+		info.Title = item.Source
+		info.Link = item.SourceURL
+		info.ItemCount++
+		if item.PublishDate.After(info.LastPublished) {
+			info.LastPublished = item.PublishDate
+		}
+	}
+	return feeds
+}
+
+// snapshot returns the cached items in compareItems order.
+func (s *server) snapshot() []rssreader.RssItem {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.items)
+}
+
+func (s *server) handleItems(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit := defaultPageSize
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", v))
+			return
+		}
+		limit = min(n, maxPageSize)
+	}
+
+	var since, until time.Time
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &since}, {"until", &until}} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid %s %q: expected RFC 3339 timestamp", p.name, v))
+			return
+		}
+		*p.dst = t
+	}
+
+	var after *rssreader.RssItem
+	if v := q.Get("cursor"); v != "" {
+		item, err := decodeCursor(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		after = &item
+	}
+
+	feeds := q["feed"]
+	query := strings.ToLower(q.Get("q"))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	page := itemsPage{Items: []rssreader.RssItem{}}
+	for _, item := range s.items {
+		if after != nil && compareItems(item, *after) <= 0 {
+			continue
+		}
+		if len(feeds) > 0 && !slices.Contains(feeds, item.RssURL) {
+			continue
+		}
+		if !since.IsZero() && item.PublishDate.Before(since) {
+			continue
+		}
+		if !until.IsZero() && !item.PublishDate.Before(until) {
+			continue
+		}
+		if query != "" &&
+			!strings.Contains(strings.ToLower(item.Title), query) &&
+			!strings.Contains(strings.ToLower(item.Description), query) {
+			continue
+		}
+		if len(page.Items) == limit {
+			page.NextCursor = encodeCursor(page.Items[len(page.Items)-1])
+			break
+		}
+		page.Items = append(page.Items, item)
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *server) handleRefresh(w http.ResponseWriter, _ *http.Request) {
+	err := s.refresh(s.ctx)
+
+	s.mu.RLock()
+	result := refreshResult{
+		ItemCount: len(s.items),
+		Refreshed: s.refreshed,
+	}
+	s.mu.RUnlock()
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// compareItems orders items by publish date, then feed, link and title so
+// that every item has a stable position for cursor pagination.
+func compareItems(a, b rssreader.RssItem) int {
+	if c := a.PublishDate.Compare(b.PublishDate); c != 0 {
+		return c
+	}
+	if c := strings.Compare(a.RssURL, b.RssURL); c != 0 {
+		return c
+	}
+	if c := strings.Compare(a.Link, b.Link); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Title, b.Title)
+}
+
+// encodeCursor returns an opaque cursor pointing just after item.
+func encodeCursor(item rssreader.RssItem) string {
+	raw := strings.Join([]string{
+		item.PublishDate.Format(time.RFC3339Nano),
+		item.RssURL,
+		item.Link,
+		item.Title,
+	}, "\n")
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor reverses encodeCursor, returning only the fields used by compareItems.
+func decodeCursor(cursor string) (rssreader.RssItem, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return rssreader.RssItem{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+	parts := strings.SplitN(string(raw), "\n", 4)
+	if len(parts) != 4 {
+		return rssreader.RssItem{}, errors.New("invalid cursor: malformed payload")
+	}
+	date, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return rssreader.RssItem{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return rssreader.RssItem{
+		PublishDate: date,
+		RssURL:      parts[1],
+		Link:        parts[2],
+		Title:       parts[3],
+	}, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		log.Printf("Error outputting JSON: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorBody{Error: err.Error()})
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+	"github.com/RssReaderProject/RssReader/metrics"
+)
+
+// testItems returns a small fixture spread over two feeds.
+func testItems() []rssreader.RssItem {
+	return []rssreader.RssItem{
+		{
+			Title:       "Go 1.24 released",
+			Source:      "Go Blog",
+			SourceURL:   "http://feeds.test/go",
+			Link:        "http://go.test/1.24",
+			PublishDate: time.Date(2025, 2, 11, 12, 0, 0, 0, time.UTC),
+			Description: "Generic type aliases and more",
+			RssURL:      "http://feeds.test/go",
+		},
+		{
+			Title:       "Rust 1.85 released",
+			Source:      "Rust Blog",
+			SourceURL:   "http://feeds.test/rust",
+			Link:        "http://rust.test/1.85",
+			PublishDate: time.Date(2025, 2, 20, 12, 0, 0, 0, time.UTC),
+			Description: "The 2024 edition is stable",
+			RssURL:      "http://feeds.test/rust",
+		},
+		{
+			Title:       "Go 1.23 released",
+			Source:      "Go Blog",
+			SourceURL:   "http://feeds.test/go",
+			Link:        "http://go.test/1.23",
+			PublishDate: time.Date(2024, 8, 13, 12, 0, 0, 0, time.UTC),
+			Description: "Range over func",
+			RssURL:      "http://feeds.test/go",
+		},
+	}
+}
+
+// newTestServer starts an API server backed by a stub parse function.
+func newTestServer(t *testing.T, parse parseFunc) *httptest.Server {
+	t.Helper()
+	srv := newServer([]string{"http://feeds.test/go", "http://feeds.test/rust", "http://feeds.test/empty"}, parse, 5*time.Second)
+	if err := srv.refresh(context.Background()); err != nil {
+		t.Fatalf("Initial refresh failed: %v", err)
+	}
+	ts := httptest.NewServer(srv.handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func stubParse(items []rssreader.RssItem) parseFunc {
+	return func(_ context.Context, _ []string) ([]rssreader.RssItem, error) {
+		return items, nil
+	}
+}
+
+func getJSON(t *testing.T, rawURL string, wantStatus int, v any) {
+	t.Helper()
+	resp, err := http.Get(rawURL)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", rawURL, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("GET %s: expected status %d, got %d", rawURL, wantStatus, resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %q", ct)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+}
+
+func titles(items []rssreader.RssItem) []string {
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i] = item.Title
+	}
+	return out
+}
+
+func TestServe_Feeds(t *testing.T) {
+	ts := newTestServer(t, stubParse(testItems()))
+
+	var feeds []feedInfo
+	getJSON(t, ts.URL+"/feeds", http.StatusOK, &feeds)
+
+	if len(feeds) != 3 {
+		t.Fatalf("Expected 3 feeds, got %d", len(feeds))
+	}
+	if feeds[0].Title != "Go Blog" || feeds[0].ItemCount != 2 {
+		t.Errorf("Unexpected first feed: %+v", feeds[0])
+	}
+	if !feeds[0].LastPublished.Equal(time.Date(2025, 2, 11, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected LastPublished of newest Go item, got %v", feeds[0].LastPublished)
+	}
+	if feeds[2].URL != "http://feeds.test/empty" || feeds[2].ItemCount != 0 {
+		t.Errorf("Expected empty feed to be listed without items, got %+v", feeds[2])
+	}
+}
+
+func TestServe_ItemsSortedAndShapedLikeCLI(t *testing.T) {
+	ts := newTestServer(t, stubParse(testItems()))
+
+	resp, err := http.Get(ts.URL + "/items")
+	if err != nil {
+		t.Fatalf("GET /items failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// Decode generically to check the field names match the CLI's JSON output
+	var raw struct {
+		Items []map[string]any
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(raw.Items) != 3 {
+		t.Fatalf("Expected 3 items, got %d", len(raw.Items))
+	}
+	for _, field := range []string{"Title", "Source", "SourceURL", "Link", "PublishDate", "Description", "RssURL"} {
+		if _, ok := raw.Items[0][field]; !ok {
+			t.Errorf("Expected item field %q in response", field)
+		}
+	}
+
+	var page itemsPage
+	getJSON(t, ts.URL+"/items", http.StatusOK, &page)
+	want := []string{"Go 1.23 released", "Go 1.24 released", "Rust 1.85 released"}
+	if got := titles(page.Items); !slices.Equal(got, want) {
+		t.Errorf("Expected items %v, got %v", want, got)
+	}
+	if page.NextCursor != "" {
+		t.Errorf("Expected no next cursor on a complete page, got %q", page.NextCursor)
+	}
+}
+
+func TestServe_ItemsFilters(t *testing.T) {
+	ts := newTestServer(t, stubParse(testItems()))
+
+	tests := []struct {
+		name  string
+		query url.Values
+		want  []string
+	}{
+		{
+			name:  "by feed",
+			query: url.Values{"feed": {"http://feeds.test/rust"}},
+			want:  []string{"Rust 1.85 released"},
+		},
+		{
+			name:  "by multiple feeds",
+			query: url.Values{"feed": {"http://feeds.test/rust", "http://feeds.test/go"}},
+			want:  []string{"Go 1.23 released", "Go 1.24 released", "Rust 1.85 released"},
+		},
+		{
+			name:  "since",
+			query: url.Values{"since": {"2025-01-01T00:00:00Z"}},
+			want:  []string{"Go 1.24 released", "Rust 1.85 released"},
+		},
+		{
+			name:  "until is exclusive",
+			query: url.Values{"until": {"2025-02-20T12:00:00Z"}},
+			want:  []string{"Go 1.23 released", "Go 1.24 released"},
+		},
+		{
+			name:  "query matches title case-insensitively",
+			query: url.Values{"q": {"RUST"}},
+			want:  []string{"Rust 1.85 released"},
+		},
+		{
+			name:  "query matches description",
+			query: url.Values{"q": {"range over"}},
+			want:  []string{"Go 1.23 released"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var page itemsPage
+			getJSON(t, ts.URL+"/items?"+tt.query.Encode(), http.StatusOK, &page)
+			if got := titles(page.Items); !slices.Equal(got, tt.want) {
+				t.Errorf("Expected items %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestServe_ItemsPagination(t *testing.T) {
+	ts := newTestServer(t, stubParse(testItems()))
+
+	var got []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("Pagination did not terminate")
+		}
+		query := url.Values{"limit": {"2"}}
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		var page itemsPage
+		getJSON(t, ts.URL+"/items?"+query.Encode(), http.StatusOK, &page)
+		got = append(got, titles(page.Items)...)
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	want := []string{"Go 1.23 released", "Go 1.24 released", "Rust 1.85 released"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected paginated items %v, got %v", want, got)
+	}
+}
+
+func TestServe_ItemsBadRequest(t *testing.T) {
+	ts := newTestServer(t, stubParse(testItems()))
+
+	for _, query := range []string{"limit=0", "limit=abc", "since=yesterday", "cursor=!!!"} {
+		t.Run(query, func(t *testing.T) {
+			var body errorBody
+			getJSON(t, ts.URL+"/items?"+query, http.StatusBadRequest, &body)
+			if body.Error == "" {
+				t.Error("Expected an error message")
+			}
+		})
+	}
+}
+
+func TestServe_Refresh(t *testing.T) {
+	var calls atomic.Int32
+	parse := func(_ context.Context, _ []string) ([]rssreader.RssItem, error) {
+		if calls.Add(1) == 1 {
+			return testItems()[:1], nil
+		}
+		return testItems(), errors.New("encountered 1 errors")
+	}
+	ts := newTestServer(t, parse)
+
+	resp, err := http.Post(ts.URL+"/refresh", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST /refresh failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var result refreshResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected parse to be called twice, got %d", calls.Load())
+	}
+	if result.ItemCount != 3 {
+		t.Errorf("Expected 3 items after refresh, got %d", result.ItemCount)
+	}
+	if result.Error == "" {
+		t.Error("Expected refresh error to be reported")
+	}
+
+	var page itemsPage
+	getJSON(t, ts.URL+"/items", http.StatusOK, &page)
+	if len(page.Items) != 3 {
+		t.Errorf("Expected partial results to be served after refresh, got %d items", len(page.Items))
+	}
+}
+
+func TestServe_RefreshKeepsItemsOfFailedFeeds(t *testing.T) {
+	var calls atomic.Int32
+	parse := func(ctx context.Context, _ []string) ([]rssreader.RssItem, error) {
+		if calls.Add(1) == 1 {
+			return testItems(), nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// The Rust feed fails transiently
+		items := testItems()
+		return []rssreader.RssItem{items[0], items[2]}, errors.New("encountered 1 errors")
+	}
+	ts := newTestServer(t, parse)
+
+	// A client that gives up must not cancel the refresh
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, ts.URL+"/refresh", nil)
+	if resp, err := http.DefaultClient.Do(req); err == nil {
+		_ = resp.Body.Close()
+	}
+	resp, err := http.Post(ts.URL+"/refresh", "application/json", nil)
+	if err != nil {
+		t.Fatalf("POST /refresh failed: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	var page itemsPage
+	getJSON(t, ts.URL+"/items", http.StatusOK, &page)
+	if got := titles(page.Items); len(got) != 3 || !slices.Contains(got, "Rust 1.85 released") {
+		t.Errorf("Expected the Rust items to be kept, got %v", got)
+	}
+}
+
+func TestServe_MethodNotAllowed(t *testing.T) {
+	ts := newTestServer(t, stubParse(testItems()))
+
+	resp, err := http.Get(ts.URL + "/refresh")
+	if err != nil {
+		t.Fatalf("GET /refresh failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, resp.StatusCode)
+	}
+}
+
+func TestServe_Metrics(t *testing.T) {
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`<rss version="2.0"><channel><title>T</title><item><title>A</title></item></channel></rss>`))
+	}))
+	defer feed.Close()
+
+	m := metrics.New()
+	parse := func(ctx context.Context, urls []string) ([]rssreader.RssItem, error) {
+		return rssreader.Parse(ctx, urls, rssreader.WithFetchObserver(m))
+	}
+	srv := newServer([]string{feed.URL}, parse, 5*time.Second)
+	srv.metrics = m
+	if err := srv.refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+	want := `rssreader_fetches_total{feed="` + feed.URL + `",outcome="success"} 1`
+	if !strings.Contains(string(body), want) {
+		t.Errorf("Expected %q in the metrics, got:\n%s", want, body)
+	}
+
+	// Without metrics the endpoint doesn't exist
+	plain := newTestServer(t, stubParse(testItems()))
+	resp, err = http.Get(plain.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+)
+
+// stateRetention is how long an item is remembered after it was last seen in
+// a feed, which bounds the state file for feeds that drop old items.
+const stateRetention = 90 * 24 * time.Hour
+
+// seenState remembers which items were already reported, so repeated runs
+// only act on new items.
+type seenState struct {
+	path string // empty for a state that only lives in memory
+	// Seen maps item keys to the last time the item was present in a feed.
+	Seen map[string]time.Time
+}
+
+// loadState reads the state file at path. A missing file yields an empty state.
+func loadState(path string) (*seenState, error) {
+	state := &seenState{path: path, Seen: map[string]time.Time{}}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- the state path is supplied by the user on purpose
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading state: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("decoding state %s: %w", path, err)
+	}
+	if state.Seen == nil {
+		state.Seen = map[string]time.Time{}
+	}
+	return state, nil
+}
+
+// newMemoryState returns an empty state that is never written to disk.
+func newMemoryState() *seenState {
+	return &seenState{Seen: map[string]time.Time{}}
+}
+
+// itemKey identifies an item across runs. Links are the most stable identity
+// a feed offers; items without one fall back to their title and date.
+func itemKey(item rssreader.RssItem) string {
+	if item.Link != "" {
+		return item.RssURL + "\n" + item.Link
+	}
+	return item.RssURL + "\n" + item.Title + "\n" + item.PublishDate.UTC().Format(time.RFC3339)
+}
+
+// filterNew returns the items not seen before and marks all items as seen at now.
+func (s *seenState) filterNew(items []rssreader.RssItem, now time.Time) []rssreader.RssItem {
+	fresh := []rssreader.RssItem{}
+	for _, item := range items {
+		key := itemKey(item)
+		if _, ok := s.Seen[key]; !ok {
+			fresh = append(fresh, item)
+		}
+		s.Seen[key] = now
+	}
+	return fresh
+}
+
+// forget removes items from the state so they are reported again.
+func (s *seenState) forget(items []rssreader.RssItem) {
+	for _, item := range items {
+		delete(s.Seen, itemKey(item))
+	}
+}
+
+// save prunes expired entries and atomically rewrites the state file.
+func (s *seenState) save(now time.Time) error {
+	for key, seen := range s.Seen {
+		if now.Sub(seen) > stateRetention {
+			delete(s.Seen, key)
+		}
+	}
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding state: %w", err)
+	}
+
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("writing state: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic replaces the file at path with data, so readers never see
+// a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".rssreader-state-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
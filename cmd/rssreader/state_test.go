@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+)
+
+func TestState_FilterNewAcrossRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	state, err := loadState(path)
+	if err != nil {
+		t.Fatalf("Expected missing state file to be treated as empty, got: %v", err)
+	}
+	items := testItems()
+	if fresh := state.filterNew(items, now); len(fresh) != 3 {
+		t.Fatalf("Expected all 3 items to be new on first run, got %d", len(fresh))
+	}
+	if err := state.save(now); err != nil {
+		t.Fatalf("Failed to save state: %v", err)
+	}
+
+	state, err = loadState(path)
+	if err != nil {
+		t.Fatalf("Failed to reload state: %v", err)
+	}
+	added := rssreader.RssItem{Title: "Go 1.25 released", Link: "http://go.test/1.25", RssURL: "http://feeds.test/go"}
+	fresh := state.filterNew(append(items, added), now.Add(time.Hour))
+	if len(fresh) != 1 || fresh[0].Title != "Go 1.25 released" {
+		t.Errorf("Expected only the added item to be new, got %v", titles(fresh))
+	}
+}
+
+func TestState_ItemsWithoutLink(t *testing.T) {
+	state, err := loadState(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+	now := time.Now()
+
+	item := rssreader.RssItem{Title: "No link", RssURL: "http://feeds.test/go", PublishDate: now}
+	state.filterNew([]rssreader.RssItem{item}, now)
+
+	renamed := item
+	renamed.Title = "No link (updated)"
+	fresh := state.filterNew([]rssreader.RssItem{item, renamed}, now)
+	if len(fresh) != 1 || fresh[0].Title != "No link (updated)" {
+		t.Errorf("Expected title to identify items without link, got %v", titles(fresh))
+	}
+}
+
+func TestState_PrunesExpiredEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	state, _ := loadState(path)
+	state.filterNew(testItems()[:1], now.Add(-stateRetention-time.Hour))
+	state.filterNew(testItems()[1:], now)
+	if err := state.save(now); err != nil {
+		t.Fatalf("Failed to save state: %v", err)
+	}
+
+	state, err := loadState(path)
+	if err != nil {
+		t.Fatalf("Failed to reload state: %v", err)
+	}
+	if len(state.Seen) != 2 {
+		t.Errorf("Expected expired entry to be pruned, got %d entries", len(state.Seen))
+	}
+}
+
+func TestState_CorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+	if _, err := loadState(path); err == nil {
+		t.Error("Expected error for corrupt state file, got nil")
+	}
+}
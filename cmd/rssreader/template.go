@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+	"golang.org/x/net/html"
+)
+
+// templateData is the value passed as dot to user templates.
+type templateData struct {
+	Items     []rssreader.RssItem
+	Generated time.Time
+}
+
+// templateFuncs are the helpers available to user templates.
+var templateFuncs = map[string]any{
+	"formatDate":    formatDate,
+	"truncate":      truncate,
+	"stripHTML":     stripHTML,
+	"groupBySource": rssreader.GroupBySource,
+	"json":          jsonString,
+}
+
+// executor is the common subset of text/template and html/template.
+type executor interface {
+	Execute(w io.Writer, data any) error
+}
+
+// loadTemplate parses the template file, using html/template for files with
+// an .html or .htm extension so that item content is escaped contextually.
+func loadTemplate(path string) (executor, error) {
+	src, err := os.ReadFile(path) // #nosec G304 -- the template path is supplied by the user on purpose
+	if err != nil {
+		return nil, fmt.Errorf("reading template: %w", err)
+	}
+
+	name := filepath.Base(path)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		tmpl, err := htmltemplate.New(name).Funcs(templateFuncs).Parse(string(src))
+		if err != nil {
+			return nil, fmt.Errorf("parsing template: %w", err)
+		}
+		return tmpl, nil
+	default:
+		tmpl, err := texttemplate.New(name).Funcs(templateFuncs).Parse(string(src))
+		if err != nil {
+			return nil, fmt.Errorf("parsing template: %w", err)
+		}
+		return tmpl, nil
+	}
+}
+
+// outputTemplate renders items through a user-supplied template.
+func outputTemplate(w io.Writer, tmpl executor, items []rssreader.RssItem) error {
+	data := templateData{
+		Items:     items,
+		Generated: time.Now(),
+	}
+	if err := tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("executing template: %w", err)
+	}
+	return nil
+}
+
+// formatDate formats t with a Go reference-time layout or one of the names
+// rfc3339, rfc1123, date and datetime. Zero times render as an empty string.
+func formatDate(layout string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	switch strings.ToLower(layout) {
+	case "rfc3339":
+		layout = time.RFC3339
+	case "rfc1123":
+		layout = time.RFC1123
+	case "date":
+		layout = time.DateOnly
+	case "datetime":
+		layout = time.DateTime
+	}
+	return t.Format(layout)
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(n int, s string) string {
+	runes := []rune(s)
+	if n <= 0 || len(runes) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:n])) + "…"
+}
+
+// stripHTML returns the text content of an HTML fragment with entities
+// decoded, script and style contents removed and whitespace collapsed.
+func stripHTML(s string) string {
+	var b strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(s))
+	skip := 0
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return strings.Join(strings.Fields(b.String()), " ")
+		case html.StartTagToken:
+			name, _ := tokenizer.TagName()
+			if isRawTextElement(string(name)) {
+				skip++
+			}
+			b.WriteByte(' ')
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			if isRawTextElement(string(name)) && skip > 0 {
+				skip--
+			}
+			b.WriteByte(' ')
+		case html.SelfClosingTagToken:
+			b.WriteByte(' ')
+		case html.TextToken:
+			if skip == 0 {
+				b.Write(tokenizer.Text())
+			}
+		}
+	}
+}
+
+// isRawTextElement reports whether the element's content is never shown as text.
+func isRawTextElement(name string) bool {
+	return name == "script" || name == "style"
+}
+
+// jsonString encodes v as JSON, e.g. to embed item text in a webhook payload.
+func jsonString(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
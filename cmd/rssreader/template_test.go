@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+)
+
+func TestTemplate_Digest(t *testing.T) {
+	tmpl, err := loadTemplate(filepath.Join("testdata", "digest.tmpl"))
+	if err != nil {
+		t.Fatalf("Failed to load template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := outputTemplate(&buf, tmpl, outputTestItems()); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	assertGolden(t, "digest.tmpl.golden", buf.Bytes())
+}
+
+func TestTemplate_SlackMessageIsValidJSON(t *testing.T) {
+	tmpl, err := loadTemplate(filepath.Join("testdata", "slack.tmpl"))
+	if err != nil {
+		t.Fatalf("Failed to load template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := outputTemplate(&buf, tmpl, outputTestItems()); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+
+	var msg struct {
+		Text   string
+		Blocks []struct {
+			Text struct {
+				Text string
+			}
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &msg); err != nil {
+		t.Fatalf("Expected valid JSON, got %v:\n%s", err, buf.String())
+	}
+	if msg.Text != "3 new items" {
+		t.Errorf("Expected text '3 new items', got %q", msg.Text)
+	}
+	if len(msg.Blocks) != 3 || msg.Blocks[0].Text.Text != `<http://example.com/hello|Hello, "World">` {
+		t.Errorf("Unexpected blocks: %+v", msg.Blocks)
+	}
+}
+
+func TestTemplate_HTMLEscapesContent(t *testing.T) {
+	tmpl, err := loadTemplate(filepath.Join("testdata", "page.html"))
+	if err != nil {
+		t.Fatalf("Failed to load template: %v", err)
+	}
+
+	items := []rssreader.RssItem{{
+		Title:       "<script>alert(1)</script>",
+		Link:        "javascript:alert(1)",
+		PublishDate: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+	}}
+	var buf bytes.Buffer
+	if err := outputTemplate(&buf, tmpl, items); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "<script>") {
+		t.Errorf("Expected title to be escaped, got:\n%s", out)
+	}
+	if strings.Contains(out, `href="javascript:`) {
+		t.Errorf("Expected unsafe URL to be filtered, got:\n%s", out)
+	}
+	if !strings.Contains(out, "2006-01-02 15:04") {
+		t.Errorf("Expected formatted date, got:\n%s", out)
+	}
+}
+
+func TestTemplate_Errors(t *testing.T) {
+	if _, err := loadTemplate(filepath.Join("testdata", "missing.tmpl")); err == nil {
+		t.Error("Expected error for missing template file, got nil")
+	}
+
+	path := filepath.Join(t.TempDir(), "broken.tmpl")
+	if err := os.WriteFile(path, []byte("{{ range .Items }"), 0o600); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	if _, err := loadTemplate(path); err == nil {
+		t.Error("Expected error for malformed template, got nil")
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	date := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := map[string]string{
+		"rfc3339":  "2006-01-02T15:04:05Z",
+		"date":     "2006-01-02",
+		"datetime": "2006-01-02 15:04:05",
+		"Jan 2":    "Jan 2",
+	}
+	for layout, want := range tests {
+		if got := formatDate(layout, date); got != want {
+			t.Errorf("formatDate(%q): expected %q, got %q", layout, want, got)
+		}
+	}
+	if got := formatDate("date", time.Time{}); got != "" {
+		t.Errorf("Expected empty string for zero time, got %q", got)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate(5, "short"); got != "short" {
+		t.Errorf("Expected string within limit to be unchanged, got %q", got)
+	}
+	if got := truncate(4, "Grüße aus Köln"); got != "Grüß…" {
+		t.Errorf("Expected rune-aware truncation, got %q", got)
+	}
+}
+
+func TestStripHTML(t *testing.T) {
+	in := `<p>Hello&nbsp;<b>bold</b> &amp; <a href="#">link</a></p><script>var x = 1;</script><style>p{}</style><br/>done`
+	want := "Hello bold & link done"
+	if got := stripHTML(in); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
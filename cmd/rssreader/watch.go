@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+	"github.com/RssReaderProject/RssReader/notify"
+)
+
+// runner fetches the feeds and hands the resulting items to the output and
+// notifiers, filtering out items seen before when a state is configured.
+type runner struct {
+	urls      []string
+	timeout   time.Duration
+	parse     parseFunc
+	state     *seenState
+	notifiers []notify.Notifier
+	output    func(items []rssreader.RssItem)
+	watching  bool // suppresses output for cycles without new items
+
+	// websub receives pushed updates between polls in watch mode if set.
+	websub *rssreader.Subscriber
+	// fullText extracts the articles of pushed items, as parse does for
+	// polled ones, if set.
+	fullText *rssreader.FullTextFetcher
+
+	// mu serializes polls and pushes, which share the state.
+	mu sync.Mutex
+}
+
+// fetch parses all feeds within the configured timeout.
+func (r *runner) fetch(ctx context.Context) ([]rssreader.RssItem, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	return r.parse(ctx, r.urls)
+}
+
+// process outputs the new items and delivers them to every notifier. Items
+// are only remembered as seen once all notifications succeeded, so failed
+// deliveries are retried by the next run.
+func (r *runner) process(ctx context.Context, items []rssreader.RssItem, now time.Time) error {
+	if r.state != nil {
+		items = r.state.filterNew(items, now)
+	}
+
+	if len(items) > 0 || !r.watching {
+		r.output(items)
+	}
+
+	if failed, err := r.notify(ctx, items); err != nil {
+		if r.state != nil {
+			// Only the failed items need to be notified again
+			r.state.forget(failed)
+			if saveErr := r.state.save(now); saveErr != nil {
+				log.Printf("Error saving state: %v", saveErr)
+			}
+		}
+		return err
+	}
+
+	if r.state != nil {
+		return r.state.save(now)
+	}
+	return nil
+}
+
+// notify delivers items to every notifier and returns the items that at least
+// one of them failed to deliver. Notifiers returning a notify.PartialError
+// report which items failed; for other errors all items count as failed.
+func (r *runner) notify(ctx context.Context, items []rssreader.RssItem) ([]rssreader.RssItem, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var errs []error
+	var failed []rssreader.RssItem
+	for _, notifier := range r.notifiers {
+		err := notifier.Notify(ctx, items)
+		if err == nil {
+			continue
+		}
+		errs = append(errs, err)
+		var partial notify.PartialError
+		if errors.As(err, &partial) {
+			failed = append(failed, partial.FailedItems()...)
+		} else {
+			failed = items
+		}
+	}
+	return failed, errors.Join(errs...)
+}
+
+// watch fetches the feeds every interval until ctx is cancelled. Feeds that
+// fail are logged and retried on the next tick while items from the other
+// feeds are still processed. Without a state file, the items present at
+// startup are treated as already seen.
+func (r *runner) watch(ctx context.Context, interval time.Duration) {
+	r.watching = true
+	if r.state == nil {
+		r.state = newMemoryState()
+		items, err := r.fetch(ctx)
+		if err != nil {
+			log.Printf("Error parsing RSS feeds: %v", err)
+		}
+		r.state.filterNew(items, time.Now())
+	} else {
+		r.tick(ctx)
+	}
+	r.subscribe(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.tick(ctx)
+			r.renew(ctx, 2*interval)
+		}
+	}
+}
+
+// tick runs a single fetch and process cycle of watch mode.
+func (r *runner) tick(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	items, err := r.fetch(ctx)
+	if err != nil {
+		log.Printf("Error parsing RSS feeds: %v", err)
+	}
+	if err := r.process(ctx, items, time.Now()); err != nil {
+		log.Printf("Error processing items: %v", err)
+	}
+}
+
+// push processes items delivered by a WebSub hub between polls.
+func (r *runner) push(ctx context.Context, items []rssreader.RssItem) {
+	if r.fullText != nil {
+		if err := r.fullText.Fetch(ctx, items); err != nil {
+			log.Printf("Error fetching full text: %v", err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.process(ctx, items, time.Now()); err != nil {
+		log.Printf("Error processing pushed items: %v", err)
+	}
+}
+
+// subscribe asks the hub of every feed that advertises one to push updates.
+// Feeds without a hub are only polled.
+func (r *runner) subscribe(ctx context.Context) {
+	if r.websub == nil {
+		return
+	}
+	for _, url := range r.urls {
+		if err := r.websub.Subscribe(ctx, url); err != nil {
+			log.Printf("WebSub unavailable, polling only: %v", err)
+		}
+	}
+}
+
+// renew resubscribes before WebSub leases run out.
+func (r *runner) renew(ctx context.Context, within time.Duration) {
+	if r.websub == nil {
+		return
+	}
+	if err := r.websub.Renew(ctx, within); err != nil {
+		log.Printf("Error renewing WebSub subscriptions: %v", err)
+	}
+}
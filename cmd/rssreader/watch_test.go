@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+	"github.com/RssReaderProject/RssReader/notify"
+)
+
+// recordingNotifier records every batch it is asked to deliver.
+type recordingNotifier struct {
+	mu      sync.Mutex
+	batches [][]rssreader.RssItem
+	err     error
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, items []rssreader.RssItem) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.batches = append(n.batches, items)
+	return n.err
+}
+
+func (n *recordingNotifier) calls() [][]rssreader.RssItem {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([][]rssreader.RssItem(nil), n.batches...)
+}
+
+func newTestRunner(parse parseFunc, state *seenState, notifiers ...notify.Notifier) (*runner, *[][]rssreader.RssItem) {
+	var outputs [][]rssreader.RssItem
+	var mu sync.Mutex
+	return &runner{
+		urls:      []string{"http://feeds.test/go"},
+		timeout:   time.Second,
+		parse:     parse,
+		state:     state,
+		notifiers: notifiers,
+		output: func(items []rssreader.RssItem) {
+			mu.Lock()
+			defer mu.Unlock()
+			outputs = append(outputs, items)
+		},
+	}, &outputs
+}
+
+func TestRunner_NewOnlyAcrossRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	notifier := &recordingNotifier{}
+
+	for run, want := range []int{3, 0} {
+		state, err := loadState(path)
+		if err != nil {
+			t.Fatalf("Failed to load state: %v", err)
+		}
+		r, outputs := newTestRunner(stubParse(testItems()), state, notifier)
+
+		items, err := r.fetch(context.Background())
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		if err := r.process(context.Background(), items, time.Now()); err != nil {
+			t.Fatalf("Run %d failed: %v", run, err)
+		}
+		if got := len((*outputs)[0]); got != want {
+			t.Errorf("Run %d: expected %d new items in output, got %d", run, want, got)
+		}
+	}
+
+	calls := notifier.calls()
+	if len(calls) != 1 || len(calls[0]) != 3 {
+		t.Errorf("Expected a single notification with 3 items, got %d calls", len(calls))
+	}
+}
+
+func TestRunner_FailedNotificationIsRetried(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	state, _ := loadState(path)
+	notifier := &recordingNotifier{err: errors.New("receiver down")}
+	r, _ := newTestRunner(stubParse(testItems()), state, notifier)
+
+	if err := r.process(context.Background(), testItems(), time.Now()); err == nil {
+		t.Fatal("Expected notification error, got nil")
+	}
+
+	notifier.err = nil
+	if err := r.process(context.Background(), testItems(), time.Now()); err != nil {
+		t.Fatalf("Expected retry to succeed, got: %v", err)
+	}
+	calls := notifier.calls()
+	if len(calls) != 2 || len(calls[1]) != 3 {
+		t.Errorf("Expected items to be delivered again after failure, got %d calls", len(calls))
+	}
+}
+
+func TestRunner_WatchPrimesMemoryStateAndNotifiesNewItems(t *testing.T) {
+	var mu sync.Mutex
+	current := testItems()[:2]
+	parse := func(_ context.Context, _ []string) ([]rssreader.RssItem, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return current, nil
+	}
+	notifier := &recordingNotifier{}
+	r, _ := newTestRunner(parse, nil, notifier)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.watch(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	// Publish a new item after the watcher has primed itself
+	time.Sleep(30 * time.Millisecond)
+	mu.Lock()
+	current = testItems()
+	mu.Unlock()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(notifier.calls()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	calls := notifier.calls()
+	if len(calls) == 0 {
+		t.Fatal("Expected the new item to be notified")
+	}
+	if len(calls[0]) != 1 || calls[0][0].Title != "Go 1.23 released" {
+		t.Errorf("Expected only the new item, got %v", titles(calls[0]))
+	}
+	if len(calls) > 1 {
+		t.Errorf("Expected a single notification, got %d", len(calls))
+	}
+}
+
+func TestRunner_PartialExecFailureOnlyRetriesFailedItems(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	state, _ := loadState(path)
+	items := testItems()
+	notifier := &recordingNotifier{err: &notify.ExecError{
+		Total:    len(items),
+		Failures: []notify.ExecFailure{{Item: items[1], Err: errors.New("exit status 1")}},
+	}}
+	r, _ := newTestRunner(stubParse(items), state, notifier)
+
+	if err := r.process(context.Background(), items, time.Now()); err == nil {
+		t.Fatal("Expected exec error, got nil")
+	}
+
+	// A fresh run reads the saved state, so only the failed item is new
+	state, err := loadState(path)
+	if err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+	notifier.err = nil
+	r, _ = newTestRunner(stubParse(items), state, notifier)
+	if err := r.process(context.Background(), items, time.Now()); err != nil {
+		t.Fatalf("Expected retry to succeed, got: %v", err)
+	}
+	calls := notifier.calls()
+	if len(calls) != 2 || len(calls[1]) != 1 || calls[1][0].Title != items[1].Title {
+		t.Errorf("Expected only the failed item to be retried, got %v", titles(calls[len(calls)-1]))
+	}
+}
+
+func TestRunner_FailedNotifierDoesNotStopTheOthers(t *testing.T) {
+	items := testItems()
+	state := newMemoryState()
+	webhook := &recordingNotifier{err: &notify.WebhookError{Total: len(items), Failed: items[2:], Err: errors.New("400 Bad Request")}}
+	mail := &recordingNotifier{}
+	r, _ := newTestRunner(stubParse(items), state, webhook, mail)
+
+	if err := r.process(context.Background(), items, time.Now()); err == nil {
+		t.Fatal("Expected webhook error, got nil")
+	}
+	if len(mail.calls()) != 1 {
+		t.Errorf("Expected the second notifier to be called, got %d calls", len(mail.calls()))
+	}
+
+	// Only the undelivered items are new on the next run
+	if fresh := state.filterNew(items, time.Now()); len(fresh) != len(items)-2 {
+		t.Errorf("Expected %d items to be retried, got %v", len(items)-2, titles(fresh))
+	}
+}
+
+func TestRunner_PushedItemsShareStateWithPolling(t *testing.T) {
+	notifier := &recordingNotifier{}
+	r, _ := newTestRunner(stubParse(testItems()), newMemoryState(), notifier)
+	r.watching = true
+
+	r.push(context.Background(), testItems()[:1])
+	r.tick(context.Background())
+
+	calls := notifier.calls()
+	if len(calls) != 2 || len(calls[0]) != 1 || len(calls[1]) != 2 {
+		t.Errorf("Expected the pushed item not to be notified again by the poll, got %d calls", len(calls))
+	}
+}
+
+func TestRunner_PushedItemsGetFullText(t *testing.T) {
+	notifier := &recordingNotifier{}
+	r, _ := newTestRunner(stubParse(nil), newMemoryState(), notifier)
+	items := testItems()[:1]
+	cache := &rssreader.MemoryCache{}
+	_ = cache.Put(items[0].Link, "<p>The whole article</p>")
+	r.fullText = &rssreader.FullTextFetcher{Cache: cache}
+
+	r.push(context.Background(), items)
+
+	calls := notifier.calls()
+	if len(calls) != 1 || len(calls[0]) != 1 || calls[0][0].FullContent != "<p>The whole article</p>" {
+		t.Errorf("Expected the pushed item with its full text, got %v", calls)
+	}
+}
@@ -0,0 +1,234 @@
+package rssreader
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+// ITunes holds the iTunes podcast details of an item. Explicit and Image
+// fall back to the values of the podcast when the episode has none.
+type ITunes struct {
+	Duration    time.Duration `json:",omitempty"`
+	Episode     int           `json:",omitempty"`
+	Season      int           `json:",omitempty"`
+	EpisodeType string        `json:",omitempty"` // full, trailer or bonus
+	Explicit    bool          `json:",omitempty"`
+	Image       string        `json:",omitempty"`
+}
+
+// Media holds the Media RSS data of an item. Contents and thumbnails of
+// media:group elements are listed together with those of the item itself.
+type Media struct {
+	Title       string           `json:",omitempty"`
+	Description string           `json:",omitempty"`
+	Contents    []MediaContent   `json:",omitempty"`
+	Thumbnails  []MediaThumbnail `json:",omitempty"`
+}
+
+// MediaContent is a media:content element.
+type MediaContent struct {
+	URL       string
+	Type      string        `json:",omitempty"` // MIME type
+	Medium    string        `json:",omitempty"` // image, audio, video, document or executable
+	FileSize  int64         `json:",omitempty"`
+	Duration  time.Duration `json:",omitempty"`
+	Width     int           `json:",omitempty"`
+	Height    int           `json:",omitempty"`
+	IsDefault bool          `json:",omitempty"` // the default rendition of its group
+}
+
+// MediaThumbnail is a media:thumbnail element.
+type MediaThumbnail struct {
+	URL    string
+	Width  int `json:",omitempty"`
+	Height int `json:",omitempty"`
+}
+
+// YouTube holds the details of an entry in a YouTube channel or playlist
+// feed.
+type YouTube struct {
+	VideoID     string
+	ChannelID   string  `json:",omitempty"`
+	Views       int64   `json:",omitempty"`
+	Rating      float64 `json:",omitempty"` // average star rating
+	RatingCount int64   `json:",omitempty"`
+}
+
+// itunesExtension returns the iTunes details of item, or nil if it has none.
+func itunesExtension(feed *gofeed.Feed, item *gofeed.Item) *ITunes {
+	itunes := item.ITunesExt
+	// gofeed only fills ITunesExt for RSS, Atom podcasts keep it in Extensions
+	if itunes == nil && len(item.Extensions["itunes"]) > 0 {
+		itunes = ext.NewITunesItemExtension(item.Extensions["itunes"])
+	}
+	if itunes == nil {
+		return nil
+	}
+
+	result := &ITunes{
+		Duration:    parseITunesDuration(itunes.Duration),
+		Episode:     atoi(itunes.Episode),
+		Season:      atoi(itunes.Season),
+		EpisodeType: strings.ToLower(strings.TrimSpace(itunes.EpisodeType)),
+		Image:       strings.TrimSpace(itunes.Image),
+	}
+	explicit := itunes.Explicit
+	if feed.ITunesExt != nil {
+		if explicit == "" {
+			explicit = feed.ITunesExt.Explicit
+		}
+		if result.Image == "" {
+			result.Image = strings.TrimSpace(feed.ITunesExt.Image)
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(explicit)) {
+	case "yes", "true", "explicit":
+		result.Explicit = true
+	}
+	return result
+}
+
+// parseITunesDuration parses itunes:duration, which is either a number of
+// seconds or [[HH:]MM:]SS. Invalid durations are reported as 0.
+func parseITunesDuration(s string) time.Duration {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0
+	}
+	var total float64
+	for i, part := range parts {
+		// Only the seconds may have a fraction
+		if i < len(parts)-1 && strings.Contains(part, ".") {
+			return 0
+		}
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		total = total*60 + n
+	}
+	return time.Duration(total * float64(time.Second))
+}
+
+// mediaExtension returns the Media RSS data of an item, or nil if it has none.
+func mediaExtension(extensions ext.Extensions) *Media {
+	elements := extensions["media"]
+	if len(elements) == 0 {
+		return nil
+	}
+
+	media := &Media{}
+	media.collect(elements, false)
+	for _, group := range elements["group"] {
+		media.collect(group.Children, true)
+	}
+	if media.Title == "" && media.Description == "" && len(media.Contents) == 0 && len(media.Thumbnails) == 0 {
+		return nil
+	}
+	return media
+}
+
+// collect adds the media elements found among the children of an item,
+// group or content element. Titles and descriptions keep the first value.
+func (m *Media) collect(elements map[string][]ext.Extension, inGroup bool) {
+	if m.Title == "" {
+		m.Title = firstExtensionValue(elements["title"])
+	}
+	if m.Description == "" {
+		m.Description = firstExtensionValue(elements["description"])
+	}
+	for _, content := range elements["content"] {
+		if content.Attrs["url"] == "" {
+			continue
+		}
+		m.Contents = append(m.Contents, MediaContent{
+			URL:       content.Attrs["url"],
+			Type:      content.Attrs["type"],
+			Medium:    content.Attrs["medium"],
+			FileSize:  max(atoi64(content.Attrs["fileSize"]), 0),
+			Duration:  parseSeconds(content.Attrs["duration"]),
+			Width:     max(atoi(content.Attrs["width"]), 0),
+			Height:    max(atoi(content.Attrs["height"]), 0),
+			IsDefault: inGroup && content.Attrs["isDefault"] == "true",
+		})
+		m.collect(content.Children, false)
+	}
+	for _, thumbnail := range elements["thumbnail"] {
+		if thumbnail.Attrs["url"] == "" {
+			continue
+		}
+		m.Thumbnails = append(m.Thumbnails, MediaThumbnail{
+			URL:    thumbnail.Attrs["url"],
+			Width:  max(atoi(thumbnail.Attrs["width"]), 0),
+			Height: max(atoi(thumbnail.Attrs["height"]), 0),
+		})
+	}
+}
+
+// youTubeExtension returns the YouTube details of an entry, or nil if it has
+// none. The yt: prefix is not canonicalized by gofeed, so it has to be the
+// one YouTube uses.
+func youTubeExtension(extensions ext.Extensions) *YouTube {
+	videoID := firstExtensionValue(extensions["yt"]["videoId"])
+	if videoID == "" {
+		return nil
+	}
+
+	result := &YouTube{
+		VideoID:   videoID,
+		ChannelID: firstExtensionValue(extensions["yt"]["channelId"]),
+	}
+	for _, group := range extensions["media"]["group"] {
+		for _, community := range group.Children["community"] {
+			for _, rating := range community.Children["starRating"] {
+				result.Rating, _ = strconv.ParseFloat(rating.Attrs["average"], 64)
+				result.RatingCount = max(atoi64(rating.Attrs["count"]), 0)
+			}
+			for _, statistics := range community.Children["statistics"] {
+				result.Views = max(atoi64(statistics.Attrs["views"]), 0)
+			}
+		}
+	}
+	return result
+}
+
+func firstExtensionValue(elements []ext.Extension) string {
+	for _, element := range elements {
+		if value := strings.TrimSpace(element.Value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// parseSeconds parses a number of seconds such as media:content duration,
+// which may have a fraction. Invalid, negative and out of range values are
+// reported as 0.
+func parseSeconds(s string) time.Duration {
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || !(seconds >= 0) || seconds > math.MaxInt64/float64(time.Second) {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// atoi parses a decimal integer, returning 0 if s is not one.
+func atoi(s string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(s))
+	return n
+}
+
+// atoi64 parses a decimal integer, returning 0 if s is not one.
+func atoi64(s string) int64 {
+	n, _ := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	return n
+}
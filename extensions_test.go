@@ -0,0 +1,167 @@
+package rssreader
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPodcast_ITunes(t *testing.T) {
+	items := parseFixture(t, "podcast.xml")
+
+	full := items["Episode 2: Trailers"].ITunes
+	if full == nil {
+		t.Fatal("Expected iTunes details for episode 2")
+	}
+	want := ITunes{
+		Duration:    time.Hour + 2*time.Minute + 3*time.Second,
+		Episode:     2,
+		Season:      1,
+		EpisodeType: "full",
+		Explicit:    false,
+		Image:       "/episodes/2.jpg",
+	}
+	if *full != want {
+		t.Errorf("Expected %+v, got %+v", want, *full)
+	}
+
+	trailer := items["Episode 1: Hello"].ITunes
+	if trailer == nil {
+		t.Fatal("Expected iTunes details for episode 1")
+	}
+	if trailer.Duration != 754*time.Second || trailer.EpisodeType != "trailer" {
+		t.Errorf("Unexpected duration or episode type: %+v", *trailer)
+	}
+	if !trailer.Explicit || trailer.Image != "https://podcast.example.com/cover.jpg" {
+		t.Errorf("Expected explicit flag and image inherited from the podcast, got %+v", *trailer)
+	}
+}
+
+func TestPodcast_MediaGroup(t *testing.T) {
+	items := parseFixture(t, "podcast.xml")
+
+	media := items["Episode 2: Trailers"].Media
+	if media == nil {
+		t.Fatal("Expected Media RSS data for episode 2")
+	}
+	if media.Title != "Episode 2 video" {
+		t.Errorf("Expected the group title, got %q", media.Title)
+	}
+	wantContents := []MediaContent{
+		{
+			URL: "https://podcast.example.com/2-hd.mp4", Type: "video/mp4", Medium: "video",
+			FileSize: 104857600, Duration: 3723 * time.Second, Width: 1920, Height: 1080, IsDefault: true,
+		},
+		{URL: "https://podcast.example.com/2-sd.mp4", Type: "video/mp4", Medium: "video", Width: 640, Height: 360},
+	}
+	if !reflect.DeepEqual(media.Contents, wantContents) {
+		t.Errorf("Expected contents %+v, got %+v", wantContents, media.Contents)
+	}
+	wantThumbnails := []MediaThumbnail{{URL: "https://podcast.example.com/2-thumb.jpg", Width: 320, Height: 180}}
+	if !reflect.DeepEqual(media.Thumbnails, wantThumbnails) {
+		t.Errorf("Expected thumbnails %+v, got %+v", wantThumbnails, media.Thumbnails)
+	}
+}
+
+func TestPodcast_MediaContentChildren(t *testing.T) {
+	media := parseFixture(t, "podcast.xml")["Episode 1: Hello"].Media
+	if media == nil {
+		t.Fatal("Expected Media RSS data for episode 1")
+	}
+	if len(media.Contents) != 1 || media.Contents[0].Medium != "image" || media.Contents[0].IsDefault {
+		t.Errorf("Unexpected contents: %+v", media.Contents)
+	}
+	if media.Description != "Cover art of episode 1" {
+		t.Errorf("Expected the description nested in media:content, got %q", media.Description)
+	}
+	if len(media.Thumbnails) != 1 || media.Thumbnails[0].URL != "https://podcast.example.com/1-small.jpg" {
+		t.Errorf("Expected the thumbnail nested in media:content, got %+v", media.Thumbnails)
+	}
+}
+
+func TestPodcast_ResolvesRelativeImage(t *testing.T) {
+	data, err := os.ReadFile("./testdata/podcast.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := testServer(string(data), "application/rss+xml")
+	defer server.Close()
+
+	items, err := Parse(context.Background(), []string{server.URL + "/feed"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	for _, item := range items {
+		if item.Title == "Episode 2: Trailers" && item.ITunes.Image != server.URL+"/episodes/2.jpg" {
+			t.Errorf("Expected the episode image resolved against the feed URL, got %q", item.ITunes.Image)
+		}
+	}
+}
+
+func TestYouTube_ChannelFeed(t *testing.T) {
+	item := parseFixture(t, "youtube.xml")["Example Video"]
+
+	want := YouTube{VideoID: "dQw4w9WgXcQ", ChannelID: "UCexample", Views: 123456, Rating: 4.9, RatingCount: 1520}
+	if item.YouTube == nil || *item.YouTube != want {
+		t.Errorf("Expected %+v, got %+v", want, item.YouTube)
+	}
+	if item.Link != "https://www.youtube.com/watch?v=dQw4w9WgXcQ" {
+		t.Errorf("Expected the watch link, got %q", item.Link)
+	}
+	if item.Media == nil {
+		t.Fatal("Expected Media RSS data")
+	}
+	if item.Media.Description != "What this video is about." {
+		t.Errorf("Expected the video description, got %q", item.Media.Description)
+	}
+	if item.Description != "What this video is about." {
+		t.Errorf("Expected the media description as item description, got %q", item.Description)
+	}
+	if len(item.Media.Thumbnails) != 1 || item.Media.Thumbnails[0].Width != 480 {
+		t.Errorf("Expected the video thumbnail, got %+v", item.Media.Thumbnails)
+	}
+	if item.ITunes != nil {
+		t.Errorf("Expected no iTunes details, got %+v", item.ITunes)
+	}
+}
+
+func TestParseITunesDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":        0,
+		"754":     754 * time.Second,
+		"12:34":   12*time.Minute + 34*time.Second,
+		"1:02:03": time.Hour + 2*time.Minute + 3*time.Second,
+		"90.5":    90*time.Second + 500*time.Millisecond,
+		"1:2:3:4": 0,
+		"1.5:00":  0,
+		"-5":      0,
+		"an hour": 0,
+		" 01:00 ": time.Minute,
+	}
+	for input, want := range tests {
+		if got := parseITunesDuration(input); got != want {
+			t.Errorf("parseITunesDuration(%q): expected %v, got %v", input, want, got)
+		}
+	}
+}
+
+func TestParseSeconds(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":       0,
+		"3723":   3723 * time.Second,
+		"12.5":   12*time.Second + 500*time.Millisecond,
+		" 0.25 ": 250 * time.Millisecond,
+		"-5":     0,
+		"NaN":    0,
+		"Inf":    0,
+		"1e300":  0,
+		"long":   0,
+	}
+	for input, want := range tests {
+		if got := parseSeconds(input); got != want {
+			t.Errorf("parseSeconds(%q): expected %v, got %v", input, want, got)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package rssreader
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// fetchFeed requests the feed at url and records the requests it took in
+// fetch. Non-2xx responses are reported as gofeed.HTTPError, like gofeed's
+// own fetching.
+func fetchFeed(ctx context.Context, url string, o *options, fetch *feedFetch) (*http.Response, error) {
+	// Every request sent for the feed gets a connection, including the
+	// retries of a retrying client
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) { fetch.requests.Add(1) },
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", gofeed.NewParser().UserAgent)
+
+	client := o.client
+	if auth, ok := o.auth[url]; ok {
+		auth.apply(req)
+		client = auth.client(client)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	fetch.status, fetch.redirects = resp.StatusCode, redirects(resp)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		_ = resp.Body.Close()
+		return nil, gofeed.HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+		}
+	}
+	// Fail early when the server announces a body over the limit, which is
+	// otherwise enforced while reading
+	if maxSize := o.limits.MaxBodySize; maxSize > 0 && resp.ContentLength > maxSize {
+		_ = resp.Body.Close()
+		return nil, &FeedTooLargeError{Limit: "body size", Max: maxSize}
+	}
+	return resp, nil
+}
+
+// redirects returns the number of redirects followed to get resp.
+func redirects(resp *http.Response) int {
+	n := 0
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		n++
+	}
+	return n
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,435 @@
+package rssreader
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mmcdole/gofeed"
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// ErrNoArticle is returned by ExtractArticle when a page has no recognizable
+// article body.
+var ErrNoArticle = errors.New("no article found")
+
+const (
+	// maxArticleSize bounds the pages downloaded by FullTextFetcher.
+	maxArticleSize = 5 << 20
+	// minArticleLength is the text length below which an extraction is
+	// considered to have failed.
+	minArticleLength = 140
+	// defaultFullTextConcurrency is used when FullTextFetcher.Concurrency
+	// is not set.
+	defaultFullTextConcurrency = 4
+)
+
+// FullTextCache stores extracted articles by item link.
+type FullTextCache interface {
+	Get(link string) (content string, ok bool)
+	Put(link, content string) error
+}
+
+// FullTextFetcher downloads the pages items link to and stores the main
+// article body in RssItem.FullContent, for feeds that only provide a teaser.
+type FullTextFetcher struct {
+	// Feeds limits fetching to items of these feed URLs (RssItem.RssURL).
+	// Nil fetches full text for all items.
+	Feeds []string
+	// Cache is consulted before downloading a page. Nil disables caching.
+	Cache FullTextCache
+	// Client is used for downloads, http.DefaultClient if nil.
+	Client *http.Client
+	// Concurrency is the maximum number of pages downloaded at once, 4 if
+	// not set.
+	Concurrency int
+}
+
+// Fetch fills FullContent of the selected items. Items whose page cannot be
+// downloaded or has no recognizable article keep an empty FullContent; their
+// errors are returned together once all items were processed.
+func (f *FullTextFetcher) Fetch(ctx context.Context, items []RssItem) error {
+	concurrency := f.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultFullTextConcurrency
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		errs   []error
+		tokens = make(chan struct{}, concurrency)
+	)
+	for i := range items {
+		item := &items[i]
+		if item.Link == "" || item.FullContent != "" || (f.Feeds != nil && !slices.Contains(f.Feeds, item.RssURL)) {
+			continue
+		}
+		if f.Cache != nil {
+			if content, ok := f.Cache.Get(item.Link); ok {
+				item.FullContent = content
+				continue
+			}
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case tokens <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-tokens }()
+
+			content, err := f.fetch(ctx, item.Link)
+			if err == nil && f.Cache != nil {
+				err = f.Cache.Put(item.Link, content)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			item.FullContent = content
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to fetch full text of %s: %w", RedactURL(item.Link), redactError(err, nil)))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// fetch downloads a page and extracts its article.
+func (f *FullTextFetcher) fetch(ctx context.Context, link string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", gofeed.NewParser().UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", gofeed.HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return "", fmt.Errorf("unexpected content type %s", mediaType)
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, maxArticleSize), contentType)
+	if err != nil {
+		return "", err
+	}
+	// Relative URLs are resolved against the page after redirects
+	return ExtractArticle(body, resp.Request.URL.String())
+}
+
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|legends|menu|modal|nav|pager|pagination|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|tags|tool|widget|ad-break|advert`)
+	maybeCandidate     = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveClass      = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativeClass      = regexp.MustCompile(`(?i)hidden|banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+)
+
+// ExtractArticle returns the main article body of an HTML page as an HTML
+// fragment, using a readability-style scoring of the page's text blocks.
+// Relative URLs are resolved against pageURL. ErrNoArticle is returned when
+// no block holds enough text.
+func ExtractArticle(r io.Reader, pageURL string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return "", err
+	}
+
+	doc.Find("script, style, noscript, iframe, form, nav, aside, button, input, select, textarea, svg, object, embed, link, meta").Remove()
+	doc.Find("*").Each(func(_ int, s *goquery.Selection) {
+		if goquery.NodeName(s) == "body" || goquery.NodeName(s) == "article" || goquery.NodeName(s) == "main" {
+			return
+		}
+		match := s.AttrOr("class", "") + " " + s.AttrOr("id", "")
+		if unlikelyCandidates.MatchString(match) && !maybeCandidate.MatchString(match) {
+			s.Remove()
+		}
+	})
+
+	top, scores := topCandidate(doc)
+	if top == nil {
+		return "", ErrNoArticle
+	}
+	article := articleContent(top, scores)
+	if utf8.RuneCountInString(strings.TrimSpace(article.Text())) < minArticleLength {
+		return "", ErrNoArticle
+	}
+
+	cleanArticle(article)
+	content, err := article.Html()
+	if err != nil {
+		return "", err
+	}
+	if base, err := url.Parse(pageURL); err == nil && base.IsAbs() {
+		content = resolveHTML(base, content)
+	}
+	return strings.TrimSpace(content), nil
+}
+
+// topCandidate scores the ancestors of every paragraph by the text it holds
+// and returns the best scoring element together with all scores.
+func topCandidate(doc *goquery.Document) (*goquery.Selection, map[*nethtml.Node]float64) {
+	scores := map[*nethtml.Node]float64{}
+	var candidates []*goquery.Selection
+
+	addScore := func(s *goquery.Selection, score float64) {
+		if s.Length() == 0 || s.Nodes[0].Type != nethtml.ElementNode {
+			return
+		}
+		node := s.Nodes[0]
+		if _, ok := scores[node]; !ok {
+			scores[node] = initialScore(s)
+			candidates = append(candidates, s)
+		}
+		scores[node] += score
+	}
+
+	doc.Find("p, pre, td, blockquote").Each(func(_ int, s *goquery.Selection) {
+		text := strings.TrimSpace(s.Text())
+		length := utf8.RuneCountInString(text)
+		if length < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(length)/100, 3)
+		addScore(s.Parent(), score)
+		addScore(s.Parent().Parent(), score/2)
+	})
+
+	var best *goquery.Selection
+	for _, s := range candidates {
+		node := s.Nodes[0]
+		scores[node] *= 1 - linkDensity(s)
+		if best == nil || scores[node] > scores[best.Nodes[0]] {
+			best = s
+		}
+	}
+	return best, scores
+}
+
+// initialScore rates an element by its tag and class names.
+func initialScore(s *goquery.Selection) float64 {
+	score := classWeight(s)
+	switch goquery.NodeName(s) {
+	case "article":
+		score += 10
+	case "div":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+	return score
+}
+
+// classWeight rewards class and id names that suggest content and penalizes
+// those that suggest page furniture.
+func classWeight(s *goquery.Selection) float64 {
+	weight := 0.0
+	for _, name := range []string{s.AttrOr("class", ""), s.AttrOr("id", "")} {
+		if name == "" {
+			continue
+		}
+		if negativeClass.MatchString(name) {
+			weight -= 25
+		}
+		if positiveClass.MatchString(name) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity is the share of an element's text that is link text.
+func linkDensity(s *goquery.Selection) float64 {
+	length := utf8.RuneCountInString(s.Text())
+	if length == 0 {
+		return 0
+	}
+	linkLength := 0
+	s.Find("a").Each(func(_ int, a *goquery.Selection) {
+		linkLength += utf8.RuneCountInString(a.Text())
+	})
+	return float64(linkLength) / float64(length)
+}
+
+// articleContent returns a detached copy of the top candidate together with
+// siblings that look like part of the same article.
+func articleContent(top *goquery.Selection, scores map[*nethtml.Node]float64) *goquery.Selection {
+	article := goquery.NewDocumentFromNode(&nethtml.Node{Type: nethtml.ElementNode, Data: "div", DataAtom: atom.Div}).Selection
+
+	topScore := scores[top.Nodes[0]]
+	topClass := top.AttrOr("class", "")
+	threshold := math.Max(10, topScore*0.2)
+
+	siblings := top.Parent().Children()
+	if top.Parent().Length() == 0 {
+		siblings = top
+	}
+	siblings.Each(func(_ int, s *goquery.Selection) {
+		include := s.IsSelection(top)
+		if !include {
+			score := scores[s.Nodes[0]]
+			// Siblings sharing the class of the top candidate are likely more of the same
+			if topClass != "" && s.AttrOr("class", "") == topClass {
+				score += topScore * 0.2
+			}
+			include = score >= threshold
+		}
+		if !include && goquery.NodeName(s) == "p" {
+			text := strings.TrimSpace(s.Text())
+			length := utf8.RuneCountInString(text)
+			density := linkDensity(s)
+			include = (length > 80 && density < 0.25) || (length > 0 && density == 0 && strings.HasSuffix(text, "."))
+		}
+		if include {
+			article.AppendSelection(s.Clone())
+		}
+	})
+	return article
+}
+
+// keptAttributes are the attributes cleanArticle leaves in place.
+var keptAttributes = map[string]bool{"href": true, "src": true, "alt": true, "title": true, "colspan": true, "rowspan": true}
+
+// cleanArticle drops presentational attributes and blocks that are mostly
+// links, such as lists of related articles.
+func cleanArticle(article *goquery.Selection) {
+	article.Find("ul, ol, div, table").Each(func(_ int, s *goquery.Selection) {
+		if s.Find("img").Length() == 0 && linkDensity(s) > 0.5 {
+			s.Remove()
+		}
+	})
+	article.Find("*").Each(func(_ int, s *goquery.Selection) {
+		for _, node := range s.Nodes {
+			node.Attr = slices.DeleteFunc(node.Attr, func(attr nethtml.Attribute) bool {
+				return !keptAttributes[attr.Key]
+			})
+		}
+	})
+}
+
+// DefaultMemoryCacheEntries is the number of articles a MemoryCache keeps
+// when MaxEntries is zero.
+const DefaultMemoryCacheEntries = 1000
+
+// MemoryCache is a FullTextCache that keeps the most recently used articles
+// in memory, dropping the least recently used one when it is full. The zero
+// value is ready to use.
+type MemoryCache struct {
+	MaxEntries int // articles kept; DefaultMemoryCacheEntries if zero
+
+	mu      sync.Mutex
+	order   list.List // links, most recently used first
+	entries map[string]*list.Element
+}
+
+// memoryEntry is the value of the elements of MemoryCache.order.
+type memoryEntry struct {
+	link, content string
+}
+
+// Get returns the cached article of link and marks it as recently used.
+func (c *MemoryCache) Get(link string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[link]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*memoryEntry).content, true
+}
+
+// Put caches the article of link, dropping the least recently used article
+// if the cache is full.
+func (c *MemoryCache) Put(link, content string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[link]; ok {
+		elem.Value.(*memoryEntry).content = content
+		c.order.MoveToFront(elem)
+		return nil
+	}
+	if c.entries == nil {
+		c.entries = map[string]*list.Element{}
+	}
+	c.entries[link] = c.order.PushFront(&memoryEntry{link: link, content: content})
+
+	limit := c.MaxEntries
+	if limit <= 0 {
+		limit = DefaultMemoryCacheEntries
+	}
+	for c.order.Len() > limit {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryEntry).link)
+	}
+	return nil
+}
+
+// DirCache is a FullTextCache that stores every article as a file in a
+// directory, named after the SHA-256 hash of its link.
+type DirCache string
+
+func (c DirCache) path(link string) string {
+	sum := sha256.Sum256([]byte(link))
+	return filepath.Join(string(c), hex.EncodeToString(sum[:])+".html")
+}
+
+// Get reads the cached article of link from its file.
+func (c DirCache) Get(link string) (string, bool) {
+	data, err := os.ReadFile(c.path(link))
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// Put writes the article of link to its file, creating the directory if
+// needed.
+func (c DirCache) Put(link, content string) error {
+	if err := os.MkdirAll(string(c), 0o750); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path(link), []byte(content), 0o600)
+}
@@ -0,0 +1,143 @@
+package rssreader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestExtractArticle(t *testing.T) {
+	f, err := os.Open("./testdata/article.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	content, err := ExtractArticle(f, "https://news.example.com/science/gophers")
+	if err != nil {
+		t.Fatalf("Expected an article, got: %v", err)
+	}
+
+	for _, want := range []string{
+		"Gophers spend most of their lives underground",
+		"more than a ton of soil",
+		`<img src="https://news.example.com/images/gopher.jpg" alt="A gopher peeking out of its burrow"/>`,
+		`<a href="https://news.example.com/science/burrows">science of burrows</a>`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Expected article to contain %q, got:\n%s", want, content)
+		}
+	}
+	for _, unwanted := range []string{"Trending", "First comment", "Copyright", "trackPageView", "World", "class=", "style="} {
+		if strings.Contains(content, unwanted) {
+			t.Errorf("Expected article not to contain %q, got:\n%s", unwanted, content)
+		}
+	}
+}
+
+func TestExtractArticle_NoArticle(t *testing.T) {
+	page := `<html><body><nav><a href="/">Home</a></nav><p>Nothing to see here.</p></body></html>`
+	if _, err := ExtractArticle(strings.NewReader(page), "https://example.com/"); !errors.Is(err, ErrNoArticle) {
+		t.Errorf("Expected ErrNoArticle, got: %v", err)
+	}
+}
+
+// articleServer serves testdata/article.html at /article and counts requests.
+func articleServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	page, err := os.ReadFile("./testdata/article.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/article" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(page)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestFullTextFetcher_OptInFeedsAndCache(t *testing.T) {
+	server, requests := articleServer(t)
+	items := []RssItem{
+		{Title: "Teaser", Link: server.URL + "/article", RssURL: "http://feeds.test/teasers"},
+		{Title: "Complete", Link: server.URL + "/article", RssURL: "http://feeds.test/full"},
+	}
+
+	cache := DirCache(t.TempDir())
+	fetcher := &FullTextFetcher{Feeds: []string{"http://feeds.test/teasers"}, Cache: cache}
+	if err := fetcher.Fetch(context.Background(), items); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !strings.Contains(items[0].FullContent, "Gophers spend most of their lives underground") {
+		t.Errorf("Expected the article for the opted-in feed, got %q", items[0].FullContent)
+	}
+	if items[1].FullContent != "" {
+		t.Errorf("Expected no full text for other feeds, got %q", items[1].FullContent)
+	}
+	if cached, ok := cache.Get(server.URL + "/article"); !ok || cached != items[0].FullContent {
+		t.Errorf("Expected the article to be cached, got %q", cached)
+	}
+
+	// Fetching again is served from the cache
+	again := []RssItem{{Link: server.URL + "/article", RssURL: "http://feeds.test/teasers"}}
+	if err := fetcher.Fetch(context.Background(), again); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if again[0].FullContent != items[0].FullContent || requests.Load() != 1 {
+		t.Errorf("Expected a cached article without another request, got %d requests", requests.Load())
+	}
+}
+
+func TestFullTextFetcher_ReportsFailedPages(t *testing.T) {
+	server, _ := articleServer(t)
+	items := []RssItem{
+		{Title: "Gone", Link: server.URL + "/missing"},
+		{Title: "Fine", Link: server.URL + "/article"},
+	}
+
+	fetcher := &FullTextFetcher{Cache: &MemoryCache{}}
+	err := fetcher.Fetch(context.Background(), items)
+	if err == nil || !strings.Contains(err.Error(), "/missing") {
+		t.Errorf("Expected an error for the missing page, got: %v", err)
+	}
+	if items[0].FullContent != "" {
+		t.Errorf("Expected no full text for the missing page, got %q", items[0].FullContent)
+	}
+	if items[1].FullContent == "" {
+		t.Error("Expected the other page to be extracted")
+	}
+	if _, ok := fetcher.Cache.Get(server.URL + "/missing"); ok {
+		t.Error("Expected failures not to be cached")
+	}
+}
+
+func TestMemoryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := &MemoryCache{MaxEntries: 2}
+	_ = cache.Put("a", "A")
+	_ = cache.Put("b", "B")
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("Expected a to be cached")
+	}
+	_ = cache.Put("c", "C")
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("Expected the least recently used entry to be evicted")
+	}
+	for link, want := range map[string]string{"a": "A", "c": "C"} {
+		if got, ok := cache.Get(link); !ok || got != want {
+			t.Errorf("Expected %s to be cached as %q, got %q (%v)", link, want, got, ok)
+		}
+	}
+}
@@ -2,15 +2,17 @@ module github.com/RssReaderProject/RssReader
 
 go 1.24.4
 
-require github.com/mmcdole/gofeed v1.3.0
+require (
+	github.com/PuerkitoBio/goquery v1.10.3
+	github.com/andybalholm/cascadia v1.3.3
+	github.com/mmcdole/gofeed v1.3.0
+	golang.org/x/net v0.41.0
+	golang.org/x/text v0.26.0
+)
 
 require (
-	github.com/PuerkitoBio/goquery v1.10.3 // indirect
-	github.com/andybalholm/cascadia v1.3.3 // indirect
 	github.com/json-iterator/go v1.1.12 // indirect
 	github.com/mmcdole/goxpp v1.1.1 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
-	golang.org/x/net v0.41.0 // indirect
-	golang.org/x/text v0.26.0 // indirect
 )
@@ -0,0 +1,117 @@
+package rssreader
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddressGuard_Blocked(t *testing.T) {
+	guard := &AddressGuard{}
+	for _, addr := range []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.100.100.200",
+		"0.0.0.0", "255.255.255.255", "224.0.0.1", "::1", "::", "fe80::1", "fd00::1",
+		"::ffff:127.0.0.1", "::ffff:169.254.169.254", "64:ff9b::a9fe:a9fe",
+	} {
+		if !guard.Blocked(netip.MustParseAddr(addr)) {
+			t.Errorf("Expected %s to be blocked", addr)
+		}
+	}
+	for _, addr := range []string{"93.184.216.34", "8.8.8.8", "2606:4700:4700::1111", "64:ff9b::808:808"} {
+		if guard.Blocked(netip.MustParseAddr(addr)) {
+			t.Errorf("Expected %s not to be blocked", addr)
+		}
+	}
+}
+
+func TestAddressGuard_Allow(t *testing.T) {
+	guard := &AddressGuard{Allow: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}
+	if guard.Blocked(netip.MustParseAddr("10.20.30.40")) {
+		t.Error("Expected the allowed range not to be blocked")
+	}
+	if !guard.Blocked(netip.MustParseAddr("192.168.1.1")) {
+		t.Error("Expected other private ranges to stay blocked")
+	}
+}
+
+func TestAddressGuard_BlocksAfterResolution(t *testing.T) {
+	server := testServer(readTestdata(t, "rss2.xml"), "application/rss+xml")
+	defer server.Close()
+
+	// localhost is only known to be loopback once it is resolved
+	url := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	_, err := Parse(context.Background(), []string{url}, WithHTTPClient((&AddressGuard{}).Client()))
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("Expected ErrBlockedAddress, got: %v", err)
+	}
+}
+
+func TestAddressGuard_AllowsAllowlistedListener(t *testing.T) {
+	server := testServer(readTestdata(t, "rss2.xml"), "application/rss+xml")
+	defer server.Close()
+
+	guard := &AddressGuard{Allow: []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")}}
+	items, err := Parse(context.Background(), []string{server.URL}, WithHTTPClient(guard.Client()))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(items) == 0 {
+		t.Error("Expected items from the allowed listener")
+	}
+}
+
+func TestAddressGuard_BlocksRedirects(t *testing.T) {
+	// The internal listener is on 127.0.0.2, outside the allowlist
+	listener, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Skipf("Cannot listen on 127.0.0.2: %v", err)
+	}
+	internal := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		t.Error("Expected the internal listener not to be reached")
+	}))
+	_ = internal.Listener.Close()
+	internal.Listener = listener
+	internal.Start()
+	defer internal.Close()
+
+	public := httptest.NewServer(http.RedirectHandler(internal.URL+"/feed", http.StatusFound))
+	defer public.Close()
+
+	guard := &AddressGuard{Allow: []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")}}
+	_, err = Parse(context.Background(), []string{public.URL}, WithHTTPClient(guard.Client()))
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("Expected the redirect to be blocked, got: %v", err)
+	}
+}
+
+func readTestdata(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile("./testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestWithoutLocalFiles(t *testing.T) {
+	abs, err := filepath.Abs("./testdata/rss2.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, feedURL := range []string{"./testdata/rss2.xml", abs, "file://" + filepath.ToSlash(abs)} {
+		_, err := Parse(context.Background(), []string{feedURL}, WithoutLocalFiles())
+		if !errors.Is(err, ErrLocalFeed) {
+			t.Errorf("Expected ErrLocalFeed for %s, got: %v", feedURL, err)
+		}
+	}
+	if _, err := Parse(context.Background(), []string{"./testdata/rss2.xml"}); err != nil {
+		t.Errorf("Expected local files to be read by default, got: %v", err)
+	}
+}
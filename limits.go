@@ -0,0 +1,125 @@
+package rssreader
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Limits bounds how much of a feed is read and accepted, so a hostile or
+// broken feed cannot exhaust memory. A zero field means no limit.
+type Limits struct {
+	// MaxBodySize is the number of bytes read from a feed or scraped page.
+	MaxBodySize int64
+	// MaxItems is the number of items a single feed may contain.
+	MaxItems int
+	// MaxFieldLength is the number of bytes in an item's title, link,
+	// description or content.
+	MaxFieldLength int
+	// MaxDepth is how deeply the elements of an XML feed may be nested.
+	MaxDepth int
+}
+
+// DefaultLimits are the limits Parse and ParseReader enforce unless
+// WithLimits is given. They are far above what real feeds need.
+var DefaultLimits = Limits{
+	MaxBodySize:    20 << 20,
+	MaxItems:       10000,
+	MaxFieldLength: 1 << 20,
+	MaxDepth:       100,
+}
+
+// FeedTooLargeError is returned, wrapped, when a feed exceeds one of its
+// Limits.
+type FeedTooLargeError struct {
+	Limit string // "body size", "items", "field length" or "nesting depth"
+	Max   int64
+}
+
+// Error names the exceeded limit and its maximum.
+func (e *FeedTooLargeError) Error() string {
+	return fmt.Sprintf("feed too large: %s exceeds %d", e.Limit, e.Max)
+}
+
+// reader returns r limited to MaxBodySize.
+func (l Limits) reader(r io.Reader) io.Reader {
+	if l.MaxBodySize <= 0 {
+		return r
+	}
+	return &limitedReader{r: r, remaining: l.MaxBodySize, max: l.MaxBodySize}
+}
+
+// checkDepth scans an XML document for elements nested deeper than
+// MaxDepth, before it is handed to a parser that keeps a stack of them.
+// JSON documents are left to encoding/json, which has its own depth limit,
+// and malformed XML is left to the parser to report.
+func (l Limits) checkDepth(data []byte) error {
+	if l.MaxDepth <= 0 || bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return nil
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.CharsetReader = charset.NewReaderLabel
+	depth := 0
+	for {
+		token, err := decoder.RawToken()
+		if err != nil {
+			return nil
+		}
+		switch token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth > l.MaxDepth {
+				return &FeedTooLargeError{Limit: "nesting depth", Max: int64(l.MaxDepth)}
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// checkItems checks the number of items and the length of their fields.
+func (l Limits) checkItems(items []RssItem) error {
+	if l.MaxItems > 0 && len(items) > l.MaxItems {
+		return &FeedTooLargeError{Limit: "items", Max: int64(l.MaxItems)}
+	}
+	if l.MaxFieldLength <= 0 {
+		return nil
+	}
+	for _, item := range items {
+		for _, field := range []string{item.Title, item.Link, item.Description, item.Content} {
+			if len(field) > l.MaxFieldLength {
+				return &FeedTooLargeError{Limit: "field length", Max: int64(l.MaxFieldLength)}
+			}
+		}
+	}
+	return nil
+}
+
+// limitedReader fails with a FeedTooLargeError once more than max bytes
+// are read, rather than silently truncating like io.LimitReader.
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	max       int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, &FeedTooLargeError{Limit: "body size", Max: l.max}
+	}
+	// Read one byte past the limit to tell a body of exactly max bytes
+	// from a larger one
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n + int(l.remaining), &FeedTooLargeError{Limit: "body size", Max: l.max}
+	}
+	return n, err
+}
@@ -0,0 +1,141 @@
+package rssreader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	xunicode "golang.org/x/text/encoding/unicode"
+)
+
+// feedWithItems returns an RSS feed with n items whose descriptions are
+// description.
+func feedWithItems(n int, description string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0"?><rss version="2.0"><channel><title>Big</title>`)
+	for i := range n {
+		fmt.Fprintf(&b, "<item><title>Item %d</title><link>https://example.com/%d</link><description>%s</description></item>", i, i, description)
+	}
+	b.WriteString("</channel></rss>")
+	return b.String()
+}
+
+// tooLarge returns the FeedTooLargeError in err, failing the test if there is none.
+func tooLarge(t *testing.T, err error) *FeedTooLargeError {
+	t.Helper()
+	var tooLarge *FeedTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("Expected a FeedTooLargeError, got: %v", err)
+	}
+	return tooLarge
+}
+
+func TestLimits_BodySize(t *testing.T) {
+	feed := feedWithItems(3, "text")
+	limits := Limits{MaxBodySize: int64(len(feed))}
+
+	if _, err := ParseReader(context.Background(), strings.NewReader(feed), "big", WithLimits(limits)); err != nil {
+		t.Fatalf("Expected a body of exactly the limit to be accepted, got: %v", err)
+	}
+
+	limits.MaxBodySize--
+	_, err := ParseReader(context.Background(), strings.NewReader(feed), "big", WithLimits(limits))
+	if e := tooLarge(t, err); e.Limit != "body size" || e.Max != limits.MaxBodySize {
+		t.Errorf("Expected the body size limit, got %+v", e)
+	}
+}
+
+// endlessReader yields its pattern forever, like a server that never stops
+// sending.
+type endlessReader struct{ pattern string }
+
+func (r endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = r.pattern[i%len(r.pattern)]
+	}
+	return len(p), nil
+}
+
+func TestLimits_StopsReadingEndlessBody(t *testing.T) {
+	r := io.MultiReader(strings.NewReader(`<rss version="2.0"><channel>`), endlessReader{"<item><title>x</title></item>"})
+	_, err := ParseReader(context.Background(), r, "endless", WithLimits(Limits{MaxBodySize: 1 << 20}))
+	tooLarge(t, err)
+}
+
+func TestLimits_ContentLengthOverLimit(t *testing.T) {
+	server := testServer(feedWithItems(50, "text"), "application/rss+xml")
+	defer server.Close()
+
+	_, err := Parse(context.Background(), []string{server.URL}, WithLimits(Limits{MaxBodySize: 512}))
+	if e := tooLarge(t, err); e.Limit != "body size" {
+		t.Errorf("Expected the body size limit, got %+v", e)
+	}
+}
+
+func TestLimits_Items(t *testing.T) {
+	feed := feedWithItems(5, "text")
+
+	items, err := ParseReader(context.Background(), strings.NewReader(feed), "big", WithLimits(Limits{MaxItems: 5}))
+	if err != nil || len(items) != 5 {
+		t.Fatalf("Expected 5 items within the limit, got %d: %v", len(items), err)
+	}
+
+	_, err = ParseReader(context.Background(), strings.NewReader(feed), "big", WithLimits(Limits{MaxItems: 4}))
+	if e := tooLarge(t, err); e.Limit != "items" || e.Max != 4 {
+		t.Errorf("Expected the item limit, got %+v", e)
+	}
+}
+
+func TestLimits_FieldLength(t *testing.T) {
+	feed := feedWithItems(1, strings.Repeat("a", 2000))
+	_, err := ParseReader(context.Background(), strings.NewReader(feed), "big", WithLimits(Limits{MaxFieldLength: 1000}))
+	if e := tooLarge(t, err); e.Limit != "field length" {
+		t.Errorf("Expected the field length limit, got %+v", e)
+	}
+}
+
+func TestLimits_NestingDepth(t *testing.T) {
+	nested := strings.Repeat("<x>", 100000) + strings.Repeat("</x>", 100000)
+	feed := feedWithItems(1, "text")
+	feed = strings.Replace(feed, "<title>Big</title>", "<title>Big</title>"+nested, 1)
+
+	_, err := ParseReader(context.Background(), strings.NewReader(feed), "deep")
+	if e := tooLarge(t, err); e.Limit != "nesting depth" || e.Max != int64(DefaultLimits.MaxDepth) {
+		t.Errorf("Expected the default nesting depth limit, got %+v", e)
+	}
+}
+
+func TestLimits_NestingDepthUTF16(t *testing.T) {
+	nested := strings.Repeat("<x>", 500) + strings.Repeat("</x>", 500)
+	feed := strings.Replace(feedWithItems(1, "text"), "<title>Big</title>", "<title>Big</title>"+nested, 1)
+	encoded, err := xunicode.UTF16(xunicode.LittleEndian, xunicode.UseBOM).NewEncoder().String(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ParseReader(context.Background(), strings.NewReader(encoded), "deep")
+	if e := tooLarge(t, err); e.Limit != "nesting depth" {
+		t.Errorf("Expected the nesting depth limit for a UTF-16 feed, got %+v", e)
+	}
+}
+
+func TestLimits_EntityExpansion(t *testing.T) {
+	// A "billion laughs" document: expanding &lol9; would take gigabytes
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0"?><!DOCTYPE rss [<!ENTITY lol0 "lol">`)
+	for i := 1; i <= 9; i++ {
+		fmt.Fprintf(&b, `<!ENTITY lol%d "%s">`, i, strings.Repeat(fmt.Sprintf("&lol%d;", i-1), 10))
+	}
+	b.WriteString(`]><rss version="2.0"><channel><title>Laughs</title><item><title>&lol9;</title><link>https://example.com/</link></item></channel></rss>`)
+
+	items, err := ParseReader(context.Background(), strings.NewReader(b.String()), "laughs")
+	if err != nil {
+		t.Fatalf("Expected the feed to parse, got: %v", err)
+	}
+	if len(items) != 1 || len(items[0].Title) > 100 {
+		t.Errorf("Expected entities not to be expanded, got %+v", items)
+	}
+}
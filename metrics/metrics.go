@@ -0,0 +1,251 @@
+// Package metrics records feed fetches and exposes them in the Prometheus
+// text exposition format, without depending on a Prometheus client.
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+	"github.com/mmcdole/gofeed"
+)
+
+// ContentType is the media type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Fetch outcomes, the values of the outcome label of rssreader_fetches_total.
+const (
+	OutcomeSuccess    = "success"
+	OutcomeHTTPError  = "http_error"
+	OutcomeTooLarge   = "too_large"
+	OutcomeTimeout    = "timeout"
+	OutcomeParseError = "parse_error"
+	OutcomeError      = "error"
+)
+
+// DefaultBuckets are the upper bounds in seconds of the fetch latency
+// histogram.
+var DefaultBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Metrics records the fetches of feeds as a rssreader.FetchObserver. Pass it
+// to rssreader.WithFetchObserver and serve it with Handler. All metrics are
+// labeled with the feed URL, which rssreader passes through RedactURL.
+type Metrics struct {
+	// Buckets are the upper bounds of the latency histogram in seconds,
+	// DefaultBuckets if nil. They must not change once fetches are recorded.
+	Buckets []float64
+
+	mu       sync.Mutex
+	now      func() time.Time
+	inFlight int
+	feeds    map[string]*feedMetrics
+}
+
+// feedMetrics are the metrics of a single feed.
+type feedMetrics struct {
+	outcomes map[string]uint64
+	// buckets counts the fetches per latency bucket, not cumulatively
+	buckets     []uint64
+	durationSum float64
+	fetches     uint64
+	bytes       int64
+	items       int
+	lastSuccess time.Time
+	newestItem  time.Time
+}
+
+// New returns empty metrics.
+func New() *Metrics {
+	return &Metrics{now: time.Now, feeds: map[string]*feedMetrics{}}
+}
+
+// FetchStarted counts the fetch as queued until it finishes.
+func (m *Metrics) FetchStarted(string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight++
+}
+
+// FetchFinished records the outcome, latency and size of a fetch, and for
+// successful fetches the items of the feed.
+func (m *Metrics) FetchFinished(result rssreader.FetchResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight--
+
+	feed := m.feeds[result.FeedURL]
+	if feed == nil {
+		feed = &feedMetrics{outcomes: map[string]uint64{}, buckets: make([]uint64, len(m.buckets()))}
+		m.feeds[result.FeedURL] = feed
+	}
+	feed.outcomes[Outcome(result)]++
+	seconds := result.Duration.Seconds()
+	if i, _ := slices.BinarySearch(m.buckets(), seconds); i < len(feed.buckets) {
+		feed.buckets[i]++
+	}
+	feed.durationSum += seconds
+	feed.fetches++
+	feed.bytes += result.Bytes
+
+	if result.Err != nil {
+		return
+	}
+	feed.items = len(result.Items)
+	feed.lastSuccess = m.now()
+	for _, item := range result.Items {
+		if item.PublishDate.After(feed.newestItem) {
+			feed.newestItem = item.PublishDate
+		}
+	}
+}
+
+// Outcome classifies a fetch as one of the Outcome constants.
+func Outcome(result rssreader.FetchResult) string {
+	var httpErr gofeed.HTTPError
+	var tooLarge *rssreader.FeedTooLargeError
+	switch err := result.Err; {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.As(err, &httpErr):
+		return OutcomeHTTPError
+	case errors.As(err, &tooLarge):
+		return OutcomeTooLarge
+	case errors.Is(err, context.DeadlineExceeded):
+		return OutcomeTimeout
+	case result.Bytes > 0:
+		// The feed was read, so it failed to parse
+		return OutcomeParseError
+	default:
+		return OutcomeError
+	}
+}
+
+func (m *Metrics) buckets() []float64 {
+	if m.Buckets == nil {
+		return DefaultBuckets
+	}
+	return m.Buckets
+}
+
+// WriteTo writes the metrics to w in the text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := &countingWriter{w: bufio.NewWriter(w)}
+	now := m.now()
+	urls := slices.Sorted(maps.Keys(m.feeds))
+
+	header(out, "rssreader_fetch_queue_depth", "gauge", "Feed fetches started and not finished yet.")
+	fmt.Fprintf(out, "rssreader_fetch_queue_depth %d\n", m.inFlight)
+
+	header(out, "rssreader_fetches_total", "counter", "Feed fetches by outcome.")
+	for _, url := range urls {
+		feed := m.feeds[url]
+		for _, outcome := range slices.Sorted(maps.Keys(feed.outcomes)) {
+			fmt.Fprintf(out, "rssreader_fetches_total{feed=%s,outcome=%s} %d\n", quote(url), quote(outcome), feed.outcomes[outcome])
+		}
+	}
+
+	header(out, "rssreader_fetch_duration_seconds", "histogram", "Time taken to fetch and parse feeds.")
+	for _, url := range urls {
+		feed := m.feeds[url]
+		var cumulative uint64
+		for i, bound := range m.buckets() {
+			cumulative += feed.buckets[i]
+			fmt.Fprintf(out, "rssreader_fetch_duration_seconds_bucket{feed=%s,le=%s} %d\n", quote(url), quote(formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(out, "rssreader_fetch_duration_seconds_bucket{feed=%s,le=\"+Inf\"} %d\n", quote(url), feed.fetches)
+		fmt.Fprintf(out, "rssreader_fetch_duration_seconds_sum{feed=%s} %s\n", quote(url), formatFloat(feed.durationSum))
+		fmt.Fprintf(out, "rssreader_fetch_duration_seconds_count{feed=%s} %d\n", quote(url), feed.fetches)
+	}
+
+	header(out, "rssreader_fetch_bytes_total", "counter", "Bytes of feed bodies read.")
+	for _, url := range urls {
+		fmt.Fprintf(out, "rssreader_fetch_bytes_total{feed=%s} %d\n", quote(url), m.feeds[url].bytes)
+	}
+
+	header(out, "rssreader_feed_items", "gauge", "Items in the feed at its last successful fetch.")
+	for _, url := range urls {
+		if feed := m.feeds[url]; !feed.lastSuccess.IsZero() {
+			fmt.Fprintf(out, "rssreader_feed_items{feed=%s} %d\n", quote(url), feed.items)
+		}
+	}
+
+	header(out, "rssreader_feed_last_success_timestamp_seconds", "gauge", "Unix time of the last successful fetch of the feed.")
+	for _, url := range urls {
+		if feed := m.feeds[url]; !feed.lastSuccess.IsZero() {
+			fmt.Fprintf(out, "rssreader_feed_last_success_timestamp_seconds{feed=%s} %s\n", quote(url), formatTime(feed.lastSuccess))
+		}
+	}
+
+	header(out, "rssreader_feed_staleness_seconds", "gauge", "Time since the newest item of the feed was published.")
+	for _, url := range urls {
+		if feed := m.feeds[url]; !feed.newestItem.IsZero() {
+			fmt.Fprintf(out, "rssreader_feed_staleness_seconds{feed=%s} %s\n", quote(url), formatFloat(now.Sub(feed.newestItem).Seconds()))
+		}
+	}
+
+	if err := out.w.Flush(); err != nil {
+		return out.n, err
+	}
+	return out.n, out.err
+}
+
+// Handler serves the metrics in the text exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_, _ = m.WriteTo(w)
+	})
+}
+
+func header(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// quote quotes a label value, escaping backslashes, quotes and newlines.
+func quote(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func formatTime(t time.Time) string {
+	return formatFloat(float64(t.UnixMilli()) / 1000)
+}
+
+// countingWriter counts the bytes written and keeps the first error.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
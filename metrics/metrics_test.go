@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+	"github.com/mmcdole/gofeed"
+)
+
+func TestMetrics(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	m := New()
+	m.now = func() time.Time { return now }
+
+	feed := "https://example.com/feed.xml"
+	m.FetchStarted(feed)
+	m.FetchStarted(feed)
+	m.FetchStarted("https://example.com/\"odd\"")
+	m.FetchFinished(rssreader.FetchResult{
+		FeedURL:  feed,
+		Status:   200,
+		Duration: 300 * time.Millisecond,
+		Bytes:    1200,
+		Items: []rssreader.RssItem{
+			{PublishDate: now.Add(-2 * time.Hour)},
+			{PublishDate: now.Add(-time.Hour)},
+		},
+	})
+	m.FetchFinished(rssreader.FetchResult{
+		FeedURL:  feed,
+		Status:   503,
+		Duration: 2 * time.Second,
+		Err:      gofeed.HTTPError{StatusCode: 503, Status: "503 Service Unavailable"},
+	})
+
+	var out strings.Builder
+	if _, err := m.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, line := range []string{
+		"# TYPE rssreader_fetch_queue_depth gauge",
+		"rssreader_fetch_queue_depth 1",
+		`rssreader_fetches_total{feed="https://example.com/feed.xml",outcome="http_error"} 1`,
+		`rssreader_fetches_total{feed="https://example.com/feed.xml",outcome="success"} 1`,
+		"# TYPE rssreader_fetch_duration_seconds histogram",
+		`rssreader_fetch_duration_seconds_bucket{feed="https://example.com/feed.xml",le="0.25"} 0`,
+		`rssreader_fetch_duration_seconds_bucket{feed="https://example.com/feed.xml",le="0.5"} 1`,
+		`rssreader_fetch_duration_seconds_bucket{feed="https://example.com/feed.xml",le="2.5"} 2`,
+		`rssreader_fetch_duration_seconds_bucket{feed="https://example.com/feed.xml",le="+Inf"} 2`,
+		`rssreader_fetch_duration_seconds_sum{feed="https://example.com/feed.xml"} 2.3`,
+		`rssreader_fetch_duration_seconds_count{feed="https://example.com/feed.xml"} 2`,
+		`rssreader_fetch_bytes_total{feed="https://example.com/feed.xml"} 1200`,
+		`rssreader_feed_items{feed="https://example.com/feed.xml"} 2`,
+		fmt.Sprintf(`rssreader_feed_last_success_timestamp_seconds{feed="https://example.com/feed.xml"} %d`, now.Unix()),
+		`rssreader_feed_staleness_seconds{feed="https://example.com/feed.xml"} 3600`,
+	} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("Expected line %q, got:\n%s", line, got)
+		}
+	}
+
+	// The unfinished fetch has no metrics yet, but its label is escaped
+	m.FetchFinished(rssreader.FetchResult{FeedURL: "https://example.com/\"odd\"", Err: errors.New("connection refused")})
+	out.Reset()
+	_, _ = m.WriteTo(&out)
+	if want := `rssreader_fetches_total{feed="https://example.com/\"odd\"",outcome="error"} 1`; !strings.Contains(out.String(), want) {
+		t.Errorf("Expected line %q, got:\n%s", want, out.String())
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		result rssreader.FetchResult
+		want   string
+	}{
+		{rssreader.FetchResult{}, OutcomeSuccess},
+		{rssreader.FetchResult{Err: fmt.Errorf("fetch: %w", gofeed.HTTPError{StatusCode: 404})}, OutcomeHTTPError},
+		{rssreader.FetchResult{Err: &rssreader.FeedTooLargeError{Limit: "items", Max: 10}}, OutcomeTooLarge},
+		{rssreader.FetchResult{Err: context.DeadlineExceeded}, OutcomeTimeout},
+		{rssreader.FetchResult{Bytes: 10, Err: errors.New("XML syntax error")}, OutcomeParseError},
+		{rssreader.FetchResult{Err: errors.New("no such host")}, OutcomeError},
+	}
+	for _, tt := range tests {
+		if got := Outcome(tt.result); got != tt.want {
+			t.Errorf("Expected outcome %s for %v, got %s", tt.want, tt.result.Err, got)
+		}
+	}
+}
+
+func TestMetricsObserveParse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`<rss version="2.0"><channel><title>T</title><item><title>A</title></item></channel></rss>`))
+	}))
+	defer server.Close()
+
+	m := New()
+	if _, err := rssreader.Parse(context.Background(), []string{server.URL + "/feed?token=s3cret"}, rssreader.WithFetchObserver(m)); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if got := rec.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("Expected content type %s, got %s", ContentType, got)
+	}
+	body := rec.Body.String()
+	want := fmt.Sprintf(`rssreader_fetches_total{feed="%s/feed?token=REDACTED",outcome="success"} 1`, server.URL)
+	if !strings.Contains(body, want) {
+		t.Errorf("Expected line %q, got:\n%s", want, body)
+	}
+	if !strings.Contains(body, "rssreader_fetch_queue_depth 0\n") {
+		t.Errorf("Expected an empty queue, got:\n%s", body)
+	}
+	if strings.Contains(body, "s3cret") {
+		t.Errorf("Expected the token to be redacted, got:\n%s", body)
+	}
+}
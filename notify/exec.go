@@ -0,0 +1,217 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+)
+
+// ExecPlaceholder is replaced by the item link in every argument of Exec.Command.
+const ExecPlaceholder = "{}"
+
+// maxStderrReport limits how much of a failed command's stderr is kept for the report.
+const maxStderrReport = 512
+
+// maxEnvValue limits each RSS_* variable, so long descriptions cannot exceed
+// the operating system's limit on the environment. The full item is on stdin.
+const maxEnvValue = 32 << 10
+
+// Exec runs a command once per item, similar to find -exec. The item is
+// passed as JSON on stdin and its fields as RSS_* environment variables.
+// Commands are executed directly, without a shell. Since feeds control the
+// item link, ExecPlaceholder is only replaced by absolute http(s) URLs, which
+// cannot be mistaken for options; the command fails for other links.
+type Exec struct {
+	Command     []string      // program and arguments; ExecPlaceholder expands to the item link
+	Concurrency int           // maximum commands running at once, defaults to 1
+	Timeout     time.Duration // per-command limit, 0 means no limit besides the context
+	Stdout      io.Writer     // receives each command's output in one piece; nil discards it
+}
+
+// ExecFailure describes a command that failed for one item.
+type ExecFailure struct {
+	Item   rssreader.RssItem
+	Err    error
+	Stderr string
+}
+
+// ExecError reports every item whose command failed.
+type ExecError struct {
+	Total    int
+	Failures []ExecFailure
+}
+
+// Error returns a multi-line failure report.
+func (e *ExecError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "exec: %d of %d commands failed", len(e.Failures), e.Total)
+	for _, f := range e.Failures {
+		fmt.Fprintf(&b, "\n  %s (%s): %v", f.Item.Title, f.Item.Link, f.Err)
+		if f.Stderr != "" {
+			fmt.Fprintf(&b, ": %s", f.Stderr)
+		}
+	}
+	return b.String()
+}
+
+// Unwrap returns the errors of the individual commands.
+func (e *ExecError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f.Err
+	}
+	return errs
+}
+
+// FailedItems returns the items whose command failed.
+func (e *ExecError) FailedItems() []rssreader.RssItem {
+	items := make([]rssreader.RssItem, len(e.Failures))
+	for i, f := range e.Failures {
+		items[i] = f.Item
+	}
+	return items
+}
+
+// Notify runs the command for every item and waits for all of them. It
+// returns an *ExecError if any command failed.
+func (e *Exec) Notify(ctx context.Context, items []rssreader.RssItem) error {
+	if len(e.Command) == 0 {
+		return errors.New("exec: no command configured")
+	}
+	concurrency := e.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures = make([]*ExecFailure, len(items))
+		slots    = make(chan struct{}, concurrency)
+	)
+	for i, item := range items {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, item rssreader.RssItem) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			stdout, stderr, err := e.run(ctx, item)
+			if e.Stdout != nil && len(stdout) > 0 {
+				mu.Lock()
+				_, _ = e.Stdout.Write(stdout)
+				mu.Unlock()
+			}
+			if err != nil {
+				failures[i] = &ExecFailure{Item: item, Err: err, Stderr: tail(stderr, maxStderrReport)}
+			}
+		}(i, item)
+	}
+	wg.Wait()
+
+	report := &ExecError{Total: len(items)}
+	for _, f := range failures {
+		if f != nil {
+			report.Failures = append(report.Failures, *f)
+		}
+	}
+	if len(report.Failures) > 0 {
+		return report
+	}
+	return nil
+}
+
+// run executes the command for a single item and returns its captured output.
+func (e *Exec) run(ctx context.Context, item rssreader.RssItem) ([]byte, []byte, error) {
+	if e.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.Timeout)
+		defer cancel()
+	}
+
+	input, err := json.Marshal(item)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	args := make([]string, len(e.Command))
+	for i, arg := range e.Command {
+		if strings.Contains(arg, ExecPlaceholder) && !isWebURL(item.Link) {
+			return nil, nil, fmt.Errorf("exec: refusing to pass link %q as an argument: not an absolute http(s) URL", item.Link)
+		}
+		args[i] = strings.ReplaceAll(arg, ExecPlaceholder, item.Link)
+	}
+
+	// #nosec G204 -- running the user's configured command is the purpose of Exec
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), itemEnv(item)...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.WaitDelay = time.Second
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if err != nil && ctx.Err() != nil {
+		err = fmt.Errorf("%w: %w", ctx.Err(), err)
+	}
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+// isWebURL reports whether link is an absolute http(s) URL.
+func isWebURL(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// itemEnv returns the environment variables describing item.
+func itemEnv(item rssreader.RssItem) []string {
+	date := ""
+	if !item.PublishDate.IsZero() {
+		date = item.PublishDate.Format(time.RFC3339)
+	}
+	return []string{
+		"RSS_TITLE=" + envValue(item.Title),
+		"RSS_SOURCE=" + envValue(item.Source),
+		"RSS_SOURCE_URL=" + envValue(item.SourceURL),
+		"RSS_LINK=" + envValue(item.Link),
+		"RSS_PUBLISH_DATE=" + date,
+		"RSS_DESCRIPTION=" + envValue(item.Description),
+		"RSS_URL=" + envValue(item.RssURL),
+	}
+}
+
+// envValue strips NUL bytes, which environment variables cannot hold, and
+// truncates value to maxEnvValue bytes without splitting a character.
+func envValue(value string) string {
+	value = strings.ReplaceAll(value, "\x00", "")
+	if len(value) <= maxEnvValue {
+		return value
+	}
+	end := maxEnvValue
+	for end > 0 && !utf8.RuneStart(value[end]) {
+		end--
+	}
+	return value[:end]
+}
+
+// tail returns the last n bytes of b as trimmed text.
+func tail(b []byte, n int) string {
+	if len(b) > n {
+		b = b[len(b)-n:]
+	}
+	return strings.TrimSpace(string(b))
+}
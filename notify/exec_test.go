@@ -0,0 +1,185 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+)
+
+// TestExecHelperProcess is not a real test. It is the command run by the
+// Exec tests, re-executing the test binary with RSSREADER_EXEC_HELPER set.
+func TestExecHelperProcess(t *testing.T) {
+	mode := os.Getenv("RSSREADER_EXEC_HELPER")
+	if mode == "" {
+		return
+	}
+	defer os.Exit(0)
+
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	if len(args) > 0 {
+		args = args[1:]
+	}
+
+	switch mode {
+	case "echo":
+		stdin, _ := io.ReadAll(os.Stdin)
+		var item rssreader.RssItem
+		if err := json.Unmarshal(stdin, &item); err != nil {
+			fmt.Fprintf(os.Stderr, "bad stdin: %v", err)
+			os.Exit(2)
+		}
+		fmt.Printf("%s|%s|%s|%s\n", strings.Join(args, " "), os.Getenv("RSS_TITLE"), os.Getenv("RSS_PUBLISH_DATE"), item.Source)
+	case "fail-koeln":
+		if strings.Contains(os.Getenv("RSS_LINK"), "koeln") {
+			fmt.Fprint(os.Stderr, "cannot handle this one")
+			os.Exit(3)
+		}
+	case "sleep":
+		time.Sleep(10 * time.Second)
+	}
+}
+
+// helperCommand returns a command that runs TestExecHelperProcess in mode.
+func helperCommand(t *testing.T, mode string, args ...string) []string {
+	t.Setenv("RSSREADER_EXEC_HELPER", mode)
+	return append([]string{os.Args[0], "-test.run=^TestExecHelperProcess$", "--"}, args...)
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent writes.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestExec_PassesItemAsArgsEnvAndStdin(t *testing.T) {
+	var out lockedBuffer
+	hook := &Exec{Command: helperCommand(t, "echo", "open", "{}"), Stdout: &out}
+	if err := hook.Notify(context.Background(), notifyTestItems()[:1]); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	want := "open http://go.example.com/you|Go & you|2006-01-02T15:04:05Z|Go Blog\n"
+	if got := out.String(); got != want {
+		t.Errorf("Expected output %q, got %q", want, got)
+	}
+}
+
+func TestExec_RunsEveryItemConcurrently(t *testing.T) {
+	var out lockedBuffer
+	items := append(notifyTestItems(), notifyTestItems()...)
+	hook := &Exec{Command: helperCommand(t, "echo", "{}"), Concurrency: 3, Stdout: &out}
+	if err := hook.Notify(context.Background(), items); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(items) {
+		t.Errorf("Expected %d output lines, got %d: %q", len(items), len(lines), lines)
+	}
+}
+
+func TestExec_ReportsFailedItems(t *testing.T) {
+	hook := &Exec{Command: helperCommand(t, "fail-koeln")}
+	err := hook.Notify(context.Background(), notifyTestItems())
+
+	var execErr *ExecError
+	if !errors.As(err, &execErr) {
+		t.Fatalf("Expected *ExecError, got: %v", err)
+	}
+	if execErr.Total != 2 || len(execErr.Failures) != 1 {
+		t.Fatalf("Expected 1 of 2 commands to fail, got %d of %d", len(execErr.Failures), execErr.Total)
+	}
+	if failed := execErr.FailedItems(); failed[0].Link != "http://koeln.example.com/gruesse" {
+		t.Errorf("Expected the Köln item to fail, got %q", failed[0].Link)
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "1 of 2 commands failed") || !strings.Contains(msg, "cannot handle this one") {
+		t.Errorf("Expected report to include counts and stderr, got: %s", msg)
+	}
+}
+
+func TestExec_Timeout(t *testing.T) {
+	hook := &Exec{Command: helperCommand(t, "sleep"), Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	err := hook.Notify(context.Background(), notifyTestItems()[:1])
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected command to be killed, took %v", elapsed)
+	}
+}
+
+func TestExec_NoCommand(t *testing.T) {
+	hook := &Exec{}
+	if err := hook.Notify(context.Background(), notifyTestItems()); err == nil {
+		t.Error("Expected error without a command, got nil")
+	}
+}
+
+func TestExec_RefusesLinksThatAreNotWebURLs(t *testing.T) {
+	var out lockedBuffer
+	items := notifyTestItems()
+	items[1].Link = "-oProxyCommand=touch /tmp/pwned"
+	hook := &Exec{Command: helperCommand(t, "echo", "{}"), Stdout: &out}
+
+	err := hook.Notify(context.Background(), items)
+	var execErr *ExecError
+	if !errors.As(err, &execErr) || len(execErr.Failures) != 1 || execErr.Failures[0].Item.Link != items[1].Link {
+		t.Fatalf("Expected only the option-like link to fail, got: %v", err)
+	}
+	if got := out.String(); strings.Contains(got, "ProxyCommand") || !strings.Contains(got, items[0].Link) {
+		t.Errorf("Expected the command to run for the web link only, got %q", got)
+	}
+
+	// Without the placeholder the link is only passed in the environment
+	hook = &Exec{Command: helperCommand(t, "echo")}
+	if err := hook.Notify(context.Background(), items[1:]); err != nil {
+		t.Errorf("Expected commands without the placeholder to run, got: %v", err)
+	}
+}
+
+func TestItemEnv_SanitizesValues(t *testing.T) {
+	item := rssreader.RssItem{Title: "nul\x00byte", Description: strings.Repeat("ä", maxEnvValue)}
+	env := itemEnv(item)
+	for _, v := range env {
+		if strings.Contains(v, "\x00") {
+			t.Errorf("Expected NUL bytes to be stripped, got %q", v)
+		}
+		if len(v) > len("RSS_DESCRIPTION=")+maxEnvValue {
+			t.Errorf("Expected values to be capped at %d bytes, got %d", maxEnvValue, len(v))
+		}
+	}
+	if env[0] != "RSS_TITLE=nulbyte" {
+		t.Errorf("Expected the title without the NUL byte, got %q", env[0])
+	}
+	if desc := strings.TrimPrefix(env[5], "RSS_DESCRIPTION="); !utf8.ValidString(desc) || len(desc) != maxEnvValue {
+		t.Errorf("Expected the description cut at a character boundary to %d bytes, got %d", maxEnvValue, len(desc))
+	}
+}
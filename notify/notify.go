@@ -0,0 +1,21 @@
+// Package notify delivers newly fetched feed items to external systems.
+package notify
+
+import (
+	"context"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+)
+
+// Notifier delivers a batch of items somewhere outside the process.
+type Notifier interface {
+	Notify(ctx context.Context, items []rssreader.RssItem) error
+}
+
+// PartialError is implemented by the errors of notifiers that may have
+// delivered some of the items, such as *ExecError and *WebhookError.
+// FailedItems returns the items that were not delivered.
+type PartialError interface {
+	error
+	FailedItems() []rssreader.RssItem
+}
@@ -0,0 +1,235 @@
+package notify
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+)
+
+// SMTP mails a digest of items as a multipart message with plain text and
+// HTML alternatives.
+type SMTP struct {
+	Addr     string // host:port of the mail server
+	Username string // enables AUTH PLAIN when set
+	Password string
+	From     string
+	To       []string
+	Subject  string      // defaults to "RSS digest: N new items"
+	TLS      *tls.Config // used for STARTTLS; defaults to verifying the Addr host
+}
+
+// Notify sends one message containing all items. It does nothing when
+// there are no items.
+func (s *SMTP) Notify(ctx context.Context, items []rssreader.RssItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	if len(s.To) == 0 {
+		return errors.New("smtp: no recipients configured")
+	}
+
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return fmt.Errorf("smtp: invalid sender %q: %w", s.From, err)
+	}
+	to := make([]*mail.Address, len(s.To))
+	for i, rcpt := range s.To {
+		if to[i], err = mail.ParseAddress(rcpt); err != nil {
+			return fmt.Errorf("smtp: invalid recipient %q: %w", rcpt, err)
+		}
+	}
+
+	msg, err := s.buildMessage(from, to, items, time.Now())
+	if err != nil {
+		return fmt.Errorf("smtp: building message: %w", err)
+	}
+	if err := s.send(ctx, from, to, msg); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	return nil
+}
+
+// send delivers msg over a single SMTP session, upgrading to TLS when the
+// server offers STARTTLS.
+func (s *SMTP) send(ctx context.Context, from *mail.Address, to []*mail.Address, msg []byte) error {
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %w", s.Addr, err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer func() { _ = c.Close() }()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		config := s.TLS
+		if config == nil {
+			config = &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+		}
+		if err := c.StartTLS(config); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt.Address); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		_ = w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// buildMessage renders the RFC 5322 message including headers.
+func (s *SMTP) buildMessage(from *mail.Address, to []*mail.Address, items []rssreader.RssItem, now time.Time) ([]byte, error) {
+	subject := s.Subject
+	if subject == "" {
+		subject = fmt.Sprintf("RSS digest: %d new items", len(items))
+	}
+	recipients := make([]string, len(to))
+	for i, addr := range to {
+		recipients[i] = addr.String()
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	var buf bytes.Buffer
+	buf.WriteString("From: " + from.String() + "\r\n")
+	buf.WriteString("To: " + strings.Join(recipients, ", ") + "\r\n")
+	buf.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	buf.WriteString("Date: " + now.Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("Message-ID: " + messageID(from) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: multipart/alternative; boundary=" + mw.Boundary() + "\r\n")
+	buf.WriteString("\r\n")
+
+	var text bytes.Buffer
+	writeTextDigest(&text, items)
+	if err := writeQuotedPrintablePart(mw, "text/plain; charset=utf-8", text.Bytes()); err != nil {
+		return nil, err
+	}
+
+	var htmlBody bytes.Buffer
+	if err := htmlDigestTemplate.Execute(&htmlBody, rssreader.GroupBySource(items)); err != nil {
+		return nil, err
+	}
+	if err := writeQuotedPrintablePart(mw, "text/html; charset=utf-8", htmlBody.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintablePart(mw *multipart.Writer, contentType string, content []byte) error {
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write(content); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// messageID generates a unique Message-ID in the sender's domain.
+func messageID(from *mail.Address) string {
+	domain := "rssreader.localhost"
+	if at := strings.LastIndex(from.Address, "@"); at >= 0 {
+		domain = from.Address[at+1:]
+	}
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return "<" + hex.EncodeToString(b[:]) + "@" + domain + ">"
+}
+
+func writeTextDigest(buf *bytes.Buffer, items []rssreader.RssItem) {
+	for i, group := range rssreader.GroupBySource(items) {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		source := cmp.Or(group.Source, group.RssURL)
+		buf.WriteString(source + "\n")
+		buf.WriteString(strings.Repeat("=", len([]rune(source))) + "\n\n")
+		for _, item := range group.Items {
+			buf.WriteString("* " + item.Title + "\n")
+			if !item.PublishDate.IsZero() {
+				buf.WriteString("  " + item.PublishDate.Format(time.RFC1123) + "\n")
+			}
+			if item.Link != "" {
+				buf.WriteString("  " + item.Link + "\n")
+			}
+		}
+	}
+}
+
+var htmlDigestTemplate = template.Must(template.New("email").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: Helvetica, Arial, sans-serif;">
+{{- range . }}
+<h2>{{ or .Source .RssURL }}</h2>
+<ul>
+{{- range .Items }}
+<li>{{ if .Link }}<a href="{{ .Link }}">{{ .Title }}</a>{{ else }}{{ .Title }}{{ end }}
+{{- if not .PublishDate.IsZero }} <small>{{ .PublishDate.Format "Mon, 02 Jan 2006 15:04 MST" }}</small>{{ end }}</li>
+{{- end }}
+</ul>
+{{- end }}
+</body>
+</html>
+`))
@@ -0,0 +1,270 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+)
+
+// fakeMessage is a message accepted by fakeSMTPServer.
+type fakeMessage struct {
+	From string
+	To   []string
+	Data string
+	Auth string
+}
+
+// fakeSMTPServer is a minimal in-process SMTP server accepting AUTH PLAIN.
+type fakeSMTPServer struct {
+	ln       net.Listener
+	password string
+
+	mu       sync.Mutex
+	messages []fakeMessage
+}
+
+func newFakeSMTPServer(t *testing.T, password string) *fakeSMTPServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &fakeSMTPServer{ln: ln, password: password}
+	t.Cleanup(func() { _ = ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTPServer) Addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *fakeSMTPServer) Messages() []fakeMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeMessage(nil), s.messages...)
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	tp := textproto.NewConn(conn)
+	reply := func(format string, args ...any) { _ = tp.PrintfLine(format, args...) }
+
+	var msg fakeMessage
+	reply("220 localhost ESMTP fake")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250-8BITMIME")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			mech, payload, _ := strings.Cut(arg, " ")
+			decoded, err := base64.StdEncoding.DecodeString(payload)
+			parts := strings.Split(string(decoded), "\x00")
+			if mech != "PLAIN" || err != nil || len(parts) != 3 || parts[2] != s.password {
+				reply("535 authentication failed")
+				continue
+			}
+			msg.Auth = parts[1]
+			reply("235 authenticated")
+		case "MAIL":
+			addr, _, _ := strings.Cut(strings.TrimPrefix(arg, "FROM:"), " ")
+			msg.From = strings.Trim(addr, "<>")
+			reply("250 OK")
+		case "RCPT":
+			msg.To = append(msg.To, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			reply("250 OK")
+		case "DATA":
+			reply("354 go ahead")
+			data, err := io.ReadAll(tp.DotReader())
+			if err != nil {
+				return
+			}
+			msg.Data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			msg = fakeMessage{Auth: msg.Auth}
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func notifyTestItems() []rssreader.RssItem {
+	return []rssreader.RssItem{
+		{
+			Title:       "Go & you",
+			Source:      "Go Blog",
+			Link:        "http://go.example.com/you",
+			PublishDate: time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+			RssURL:      "http://go.example.com/feed",
+		},
+		{
+			Title:  "Grüße <aus> Köln",
+			Source: "Köln Blog",
+			Link:   "http://koeln.example.com/gruesse",
+			RssURL: "http://koeln.example.com/feed",
+		},
+	}
+}
+
+// readParts parses a multipart/alternative message and returns its parts by content type.
+func readParts(t *testing.T, data string) (*mail.Message, map[string]string) {
+	t.Helper()
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(data)))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Expected multipart/alternative, got %q (%v)", mediaType, err)
+	}
+
+	parts := make(map[string]string)
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read part: %v", err)
+		}
+		body, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("Failed to read part body: %v", err)
+		}
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[contentType] = string(body)
+	}
+	return msg, parts
+}
+
+func TestSMTP_SendsMultipartDigest(t *testing.T) {
+	server := newFakeSMTPServer(t, "s3cret")
+	notifier := &SMTP{
+		Addr:     server.Addr(),
+		Username: "bot",
+		Password: "s3cret",
+		From:     "RSS Reader <rss@example.com>",
+		To:       []string{"team@example.com", "Ops <ops@example.com>"},
+	}
+
+	if err := notifier.Notify(context.Background(), notifyTestItems()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+	got := messages[0]
+	if got.Auth != "bot" {
+		t.Errorf("Expected AUTH as 'bot', got %q", got.Auth)
+	}
+	if got.From != "rss@example.com" {
+		t.Errorf("Expected envelope sender rss@example.com, got %q", got.From)
+	}
+	if len(got.To) != 2 || got.To[1] != "ops@example.com" {
+		t.Errorf("Expected two envelope recipients, got %v", got.To)
+	}
+
+	msg, parts := readParts(t, got.Data)
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "RSS digest: 2 new items" {
+		t.Errorf("Unexpected subject %q (%v)", subject, err)
+	}
+
+	text := parts["text/plain"]
+	for _, want := range []string{"Go Blog\n=======", "* Go & you", "http://go.example.com/you", "* Grüße <aus> Köln"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected plain text part to contain %q, got:\n%s", want, text)
+		}
+	}
+
+	htmlPart := parts["text/html"]
+	for _, want := range []string{`<a href="http://go.example.com/you">Go &amp; you</a>`, "Grüße &lt;aus&gt; Köln", "<h2>Köln Blog</h2>"} {
+		if !strings.Contains(htmlPart, want) {
+			t.Errorf("Expected HTML part to contain %q, got:\n%s", want, htmlPart)
+		}
+	}
+}
+
+func TestSMTP_NoItemsSendsNothing(t *testing.T) {
+	server := newFakeSMTPServer(t, "")
+	notifier := &SMTP{Addr: server.Addr(), From: "rss@example.com", To: []string{"team@example.com"}}
+
+	if err := notifier.Notify(context.Background(), nil); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if n := len(server.Messages()); n != 0 {
+		t.Errorf("Expected no messages, got %d", n)
+	}
+}
+
+func TestSMTP_AuthFailure(t *testing.T) {
+	server := newFakeSMTPServer(t, "right")
+	notifier := &SMTP{
+		Addr:     server.Addr(),
+		Username: "bot",
+		Password: "wrong",
+		From:     "rss@example.com",
+		To:       []string{"team@example.com"},
+	}
+
+	err := notifier.Notify(context.Background(), notifyTestItems())
+	if err == nil {
+		t.Fatal("Expected authentication error, got nil")
+	}
+	if strings.Contains(err.Error(), "wrong") {
+		t.Errorf("Expected password not to leak into error, got: %v", err)
+	}
+}
+
+func TestSMTP_InvalidConfiguration(t *testing.T) {
+	tests := map[string]*SMTP{
+		"no recipients":     {Addr: "127.0.0.1:25", From: "rss@example.com"},
+		"invalid sender":    {Addr: "127.0.0.1:25", From: "not an address", To: []string{"a@example.com"}},
+		"invalid recipient": {Addr: "127.0.0.1:25", From: "rss@example.com", To: []string{"nope"}},
+	}
+	for name, notifier := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := notifier.Notify(context.Background(), notifyTestItems()); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+)
+
+// SignatureHeader carries the hex-encoded HMAC-SHA256 of the request body,
+// prefixed with "sha256=", when a Webhook has a Secret.
+const SignatureHeader = "X-RSSReader-Signature-256"
+
+// Payload presets understood by Webhook.
+const (
+	PresetJSON       = "json"
+	PresetSlack      = "slack"
+	PresetDiscord    = "discord"
+	PresetMattermost = "mattermost"
+)
+
+// ValidatePreset returns an error unless preset is empty or one of the
+// payload presets.
+func ValidatePreset(preset string) error {
+	switch preset {
+	case "", PresetJSON, PresetSlack, PresetDiscord, PresetMattermost:
+		return nil
+	default:
+		return fmt.Errorf("unknown webhook preset %q", preset)
+	}
+}
+
+// discordMaxEmbeds is the number of embeds Discord accepts per message.
+const discordMaxEmbeds = 10
+
+// Webhook POSTs items as JSON to an HTTP endpoint, optionally shaped for a
+// chat service, retrying transient failures with exponential backoff.
+type Webhook struct {
+	URL        string
+	Preset     string        // PresetJSON (default), PresetSlack, PresetDiscord or PresetMattermost
+	Secret     string        // signs each request body into SignatureHeader when set
+	BatchSize  int           // maximum items per request, 0 sends all items at once
+	MaxRetries int           // retries after the first attempt; 0 means 3, negative disables retries
+	Backoff    time.Duration // delay before the first retry, doubled for each further retry; defaults to 1s
+	Client     *http.Client  // defaults to http.DefaultClient
+}
+
+// webhookPayload is the body sent with PresetJSON, matching the item shape of
+// the CLI's JSON output.
+type webhookPayload struct {
+	Items []rssreader.RssItem
+}
+
+// WebhookError reports a batch that still failed after all retries, along
+// with the items of that batch and the batches after it.
+type WebhookError struct {
+	URL    string // redacted, see Webhook.RedactedURL
+	Total  int
+	Failed []rssreader.RssItem
+	Err    error
+}
+
+// Error reports the redacted URL and the number of undelivered items.
+func (e *WebhookError) Error() string {
+	return fmt.Sprintf("webhook %s: %d of %d items not delivered: %v", e.URL, len(e.Failed), e.Total, e.Err)
+}
+
+// Unwrap returns the error of the failed batch.
+func (e *WebhookError) Unwrap() error {
+	return e.Err
+}
+
+// FailedItems returns the items that were not delivered.
+func (e *WebhookError) FailedItems() []rssreader.RssItem {
+	return e.Failed
+}
+
+// Notify sends the items in one or more batches. It stops at the first batch
+// that still fails after all retries and returns a *WebhookError with the
+// items that were not delivered.
+func (h *Webhook) Notify(ctx context.Context, items []rssreader.RssItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	size := h.BatchSize
+	if h.Preset == PresetDiscord && (size <= 0 || size > discordMaxEmbeds) {
+		size = discordMaxEmbeds
+	}
+	if size <= 0 {
+		size = len(items)
+	}
+
+	for start := 0; start < len(items); start += size {
+		batch := items[start:min(start+size, len(items))]
+		body, err := h.payload(batch)
+		if err != nil {
+			return fmt.Errorf("webhook: encoding payload: %w", err)
+		}
+		if err := h.deliver(ctx, body); err != nil {
+			return &WebhookError{URL: h.RedactedURL(), Total: len(items), Failed: items[start:], Err: err}
+		}
+	}
+	return nil
+}
+
+// RedactedURL returns the URL of the webhook for errors and logs, with
+// credentials and sensitive query parameters redacted by rssreader.RedactURL.
+// Chat services carry the secret of their webhooks in the path, so the path
+// is redacted too for the chat presets.
+func (h *Webhook) RedactedURL() string {
+	redacted := rssreader.RedactURL(h.URL)
+	if h.Preset == "" || h.Preset == PresetJSON {
+		return redacted
+	}
+	u, err := url.Parse(redacted)
+	if err != nil || u.Host == "" {
+		return "REDACTED"
+	}
+	u.Path, u.RawPath = "/REDACTED", ""
+	return u.String()
+}
+
+// redactError replaces the URL that net/http quotes in its errors with the
+// redacted one.
+func (h *Webhook) redactError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return &url.Error{Op: urlErr.Op, URL: h.RedactedURL(), Err: urlErr.Err}
+	}
+	return err
+}
+
+// payload renders a batch according to the configured preset.
+func (h *Webhook) payload(items []rssreader.RssItem) ([]byte, error) {
+	switch h.Preset {
+	case "", PresetJSON:
+		return json.Marshal(webhookPayload{Items: items})
+	case PresetSlack:
+		return json.Marshal(map[string]string{"text": chatText(items, slackLink)})
+	case PresetMattermost:
+		return json.Marshal(map[string]string{"text": chatText(items, markdownLink)})
+	case PresetDiscord:
+		return json.Marshal(discordPayload(items))
+	default:
+		return nil, fmt.Errorf("unknown preset %q", h.Preset)
+	}
+}
+
+// deliver POSTs body, retrying network errors, 429 and 5xx responses.
+func (h *Webhook) deliver(ctx context.Context, body []byte) error {
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	retries := h.MaxRetries
+	switch {
+	case retries == 0:
+		retries = 3
+	case retries < 0:
+		retries = 0
+	}
+	backoff := h.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return errors.Join(lastErr, ctx.Err())
+			case <-timer.C:
+			}
+			backoff *= 2
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+		if err != nil {
+			return h.redactError(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "RssReader-Webhook")
+		if h.Secret != "" {
+			req.Header.Set(SignatureHeader, Sign(h.Secret, body))
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			err = h.redactError(err)
+			if ctx.Err() != nil {
+				return err
+			}
+			lastErr = err
+			continue
+		}
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		_ = resp.Body.Close()
+
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			return nil
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			lastErr = fmt.Errorf("unexpected status %s", resp.Status)
+			if wait, ok := retryAfter(resp.Header.Get("Retry-After")); ok && wait > backoff {
+				backoff = wait
+			}
+		default:
+			// Other client errors will not succeed on retry
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", retries+1, lastErr)
+}
+
+// Sign returns the SignatureHeader value for body, for receivers to compare
+// against with hmac.Equal.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryAfter parses a Retry-After header given in seconds.
+func retryAfter(v string) (time.Duration, bool) {
+	seconds, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// chatText renders items as one line each, using link to format titles.
+func chatText(items []rssreader.RssItem, link func(title, url string) string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d new items", len(items))
+	for _, item := range items {
+		b.WriteString("\n• ")
+		if item.Link != "" {
+			b.WriteString(link(item.Title, item.Link))
+		} else {
+			b.WriteString(item.Title)
+		}
+		if item.Source != "" {
+			b.WriteString(" (" + item.Source + ")")
+		}
+	}
+	return b.String()
+}
+
+// slackLink formats a link in Slack's mrkdwn syntax.
+func slackLink(title, url string) string {
+	escape := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	return "<" + escape.Replace(url) + "|" + escape.Replace(title) + ">"
+}
+
+// markdownLink formats a link in the Markdown dialect used by Mattermost.
+func markdownLink(title, url string) string {
+	escape := strings.NewReplacer("[", `\[`, "]", `\]`)
+	return "[" + escape.Replace(title) + "](" + strings.ReplaceAll(url, ")", "%29") + ")"
+}
+
+type discordMessage struct {
+	Content string         `json:"content"`
+	Embeds  []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title     string         `json:"title"`
+	URL       string         `json:"url,omitempty"`
+	Timestamp string         `json:"timestamp,omitempty"`
+	Footer    *discordFooter `json:"footer,omitempty"`
+}
+
+type discordFooter struct {
+	Text string `json:"text"`
+}
+
+// discordPayload renders one embed per item.
+func discordPayload(items []rssreader.RssItem) discordMessage {
+	msg := discordMessage{Content: fmt.Sprintf("%d new items", len(items))}
+	for _, item := range items {
+		embed := discordEmbed{Title: truncateRunes(item.Title, 256), URL: item.Link}
+		if !item.PublishDate.IsZero() {
+			embed.Timestamp = item.PublishDate.UTC().Format(time.RFC3339)
+		}
+		if item.Source != "" {
+			embed.Footer = &discordFooter{Text: item.Source}
+		}
+		msg.Embeds = append(msg.Embeds, embed)
+	}
+	return msg
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,330 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+)
+
+// webhookReceiver records the bodies and headers POSTed to it.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	bodies   [][]byte
+	headers  []http.Header
+	statuses []int // responses to return in order, then 200
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	r.bodies = append(r.bodies, body)
+	r.headers = append(r.headers, req.Header.Clone())
+	status := http.StatusOK
+	if n := len(r.bodies); n <= len(r.statuses) {
+		status = r.statuses[n-1]
+	}
+	r.mu.Unlock()
+
+	w.WriteHeader(status)
+}
+
+func (r *webhookReceiver) requests() ([][]byte, []http.Header) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.bodies, r.headers
+}
+
+func TestWebhook_JSONPayloadAndSignature(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	hook := &Webhook{URL: server.URL, Secret: "topsecret"}
+	if err := hook.Notify(context.Background(), notifyTestItems()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	bodies, headers := receiver.requests()
+	if len(bodies) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(bodies))
+	}
+	if ct := headers[0].Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON content type, got %q", ct)
+	}
+
+	want := Sign("topsecret", bodies[0])
+	if got := headers[0].Get(SignatureHeader); !hmac.Equal([]byte(got), []byte(want)) {
+		t.Errorf("Expected signature %q, got %q", want, got)
+	}
+	if !strings.HasPrefix(want, "sha256=") {
+		t.Errorf("Expected sha256= prefix, got %q", want)
+	}
+
+	var payload struct {
+		Items []rssreader.RssItem
+	}
+	if err := json.Unmarshal(bodies[0], &payload); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	if len(payload.Items) != 2 || payload.Items[0].Title != "Go & you" {
+		t.Errorf("Unexpected payload items: %+v", payload.Items)
+	}
+}
+
+func TestWebhook_NoSignatureWithoutSecret(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	hook := &Webhook{URL: server.URL}
+	if err := hook.Notify(context.Background(), notifyTestItems()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	_, headers := receiver.requests()
+	if sig := headers[0].Get(SignatureHeader); sig != "" {
+		t.Errorf("Expected no signature header, got %q", sig)
+	}
+}
+
+func TestWebhook_Batching(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	items := append(notifyTestItems(), notifyTestItems()...)
+	items = append(items, notifyTestItems()[0])
+	hook := &Webhook{URL: server.URL, BatchSize: 2}
+	if err := hook.Notify(context.Background(), items); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	bodies, _ := receiver.requests()
+	if len(bodies) != 3 {
+		t.Fatalf("Expected 3 batches for 5 items, got %d", len(bodies))
+	}
+	var last webhookPayload
+	if err := json.Unmarshal(bodies[2], &last); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	if len(last.Items) != 1 {
+		t.Errorf("Expected last batch to hold 1 item, got %d", len(last.Items))
+	}
+}
+
+func TestWebhook_ReportsUndeliveredBatches(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{http.StatusOK, http.StatusBadRequest}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	items := append(notifyTestItems(), notifyTestItems()...)
+	items = append(items, notifyTestItems()[0])
+	hook := &Webhook{URL: server.URL, BatchSize: 2}
+	err := hook.Notify(context.Background(), items)
+
+	var hookErr *WebhookError
+	if !errors.As(err, &hookErr) {
+		t.Fatalf("Expected a *WebhookError, got %v", err)
+	}
+	if failed := hookErr.FailedItems(); len(failed) != 3 || failed[0].Title != items[2].Title {
+		t.Errorf("Expected the last 3 items to be reported, got %d", len(failed))
+	}
+	if bodies, _ := receiver.requests(); len(bodies) != 2 {
+		t.Errorf("Expected to stop after the failed batch, got %d requests", len(bodies))
+	}
+}
+
+func TestWebhook_RetriesTransientFailures(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	hook := &Webhook{URL: server.URL, Backoff: time.Millisecond}
+	if err := hook.Notify(context.Background(), notifyTestItems()); err != nil {
+		t.Fatalf("Expected delivery to succeed after retries, got: %v", err)
+	}
+	if bodies, _ := receiver.requests(); len(bodies) != 3 {
+		t.Errorf("Expected 3 attempts, got %d", len(bodies))
+	}
+}
+
+func TestWebhook_GivesUpAfterMaxRetries(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	hook := &Webhook{URL: server.URL, MaxRetries: 2, Backoff: time.Millisecond}
+	err := hook.Notify(context.Background(), notifyTestItems())
+	if err == nil {
+		t.Fatal("Expected error after exhausting retries, got nil")
+	}
+	if attempts.Load() != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts.Load())
+	}
+	if !strings.Contains(err.Error(), "502") {
+		t.Errorf("Expected last status in error, got: %v", err)
+	}
+}
+
+func TestWebhook_DoesNotRetryClientErrors(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{http.StatusBadRequest}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	hook := &Webhook{URL: server.URL, Backoff: time.Millisecond}
+	if err := hook.Notify(context.Background(), notifyTestItems()); err == nil {
+		t.Fatal("Expected error for 400 response, got nil")
+	}
+	if bodies, _ := receiver.requests(); len(bodies) != 1 {
+		t.Errorf("Expected a single attempt, got %d", len(bodies))
+	}
+}
+
+func TestWebhook_ContextCancelledDuringBackoff(t *testing.T) {
+	receiver := &webhookReceiver{statuses: []int{http.StatusInternalServerError}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	hook := &Webhook{URL: server.URL, Backoff: time.Minute}
+	start := time.Now()
+	if err := hook.Notify(ctx, notifyTestItems()); err == nil {
+		t.Fatal("Expected error when context expires, got nil")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected backoff to be interrupted, took %v", elapsed)
+	}
+}
+
+func TestWebhook_Presets(t *testing.T) {
+	tests := []struct {
+		preset string
+		check  func(t *testing.T, body map[string]any)
+	}{
+		{
+			preset: PresetSlack,
+			check: func(t *testing.T, body map[string]any) {
+				text, _ := body["text"].(string)
+				if !strings.Contains(text, "<http://go.example.com/you|Go &amp; you> (Go Blog)") {
+					t.Errorf("Unexpected Slack text: %q", text)
+				}
+			},
+		},
+		{
+			preset: PresetMattermost,
+			check: func(t *testing.T, body map[string]any) {
+				text, _ := body["text"].(string)
+				if !strings.Contains(text, "[Go & you](http://go.example.com/you) (Go Blog)") {
+					t.Errorf("Unexpected Mattermost text: %q", text)
+				}
+			},
+		},
+		{
+			preset: PresetDiscord,
+			check: func(t *testing.T, body map[string]any) {
+				embeds, _ := body["embeds"].([]any)
+				if len(embeds) != 2 {
+					t.Fatalf("Expected 2 embeds, got %v", body["embeds"])
+				}
+				first, _ := embeds[0].(map[string]any)
+				if first["title"] != "Go & you" || first["url"] != "http://go.example.com/you" || first["timestamp"] != "2006-01-02T15:04:05Z" {
+					t.Errorf("Unexpected Discord embed: %v", first)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.preset, func(t *testing.T) {
+			receiver := &webhookReceiver{}
+			server := httptest.NewServer(receiver)
+			defer server.Close()
+
+			hook := &Webhook{URL: server.URL, Preset: tt.preset}
+			if err := hook.Notify(context.Background(), notifyTestItems()); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			bodies, _ := receiver.requests()
+			var body map[string]any
+			if err := json.Unmarshal(bodies[0], &body); err != nil {
+				t.Fatalf("Failed to decode payload: %v", err)
+			}
+			tt.check(t, body)
+		})
+	}
+}
+
+func TestWebhook_DiscordBatchesAtEmbedLimit(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	items := make([]rssreader.RssItem, 25)
+	for i := range items {
+		items[i] = rssreader.RssItem{Title: "item"}
+	}
+	hook := &Webhook{URL: server.URL, Preset: PresetDiscord}
+	if err := hook.Notify(context.Background(), items); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if bodies, _ := receiver.requests(); len(bodies) != 3 {
+		t.Errorf("Expected 25 items to be split into 3 Discord messages, got %d", len(bodies))
+	}
+}
+
+func TestWebhook_UnknownPreset(t *testing.T) {
+	hook := &Webhook{URL: "http://127.0.0.1:1", Preset: "teams"}
+	if err := hook.Notify(context.Background(), notifyTestItems()); err == nil {
+		t.Error("Expected error for unknown preset, got nil")
+	}
+}
+
+func TestValidatePreset(t *testing.T) {
+	for _, preset := range []string{"", PresetJSON, PresetSlack, PresetDiscord, PresetMattermost} {
+		if err := ValidatePreset(preset); err != nil {
+			t.Errorf("Expected preset %q to be valid, got %v", preset, err)
+		}
+	}
+	if err := ValidatePreset("teams"); err == nil {
+		t.Error("Expected an error for an unknown preset")
+	}
+}
+
+func TestWebhook_ErrorsDoNotLeakTheURL(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	for _, hook := range []*Webhook{
+		{URL: server.URL + "/services/T0001/B0001/s3cret", Preset: PresetSlack},
+		{URL: server.URL + "/api/webhooks/1/s3cret", Preset: PresetDiscord},
+		{URL: server.URL + "/hook?token=s3cret"},
+	} {
+		hook.MaxRetries = -1
+		err := hook.Notify(context.Background(), notifyTestItems())
+		if err == nil {
+			t.Fatalf("Expected an error for %s, got nil", hook.Preset)
+		}
+		if strings.Contains(err.Error(), hook.URL) || strings.Contains(err.Error(), "s3cret") {
+			t.Errorf("Expected the webhook URL to be redacted, got: %v", err)
+		}
+		if !strings.Contains(err.Error(), server.URL) {
+			t.Errorf("Expected the host of the webhook in the error, got: %v", err)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package rssreader
+
+import "time"
+
+// FetchObserver is notified of the fetch of every feed by Parse and
+// ParseReader, such as to record metrics. Its methods are called
+// concurrently for different feeds.
+type FetchObserver interface {
+	// FetchStarted is called before the feed at feedURL is fetched.
+	FetchStarted(feedURL string)
+	// FetchFinished is called once the feed is parsed or has failed.
+	FetchFinished(result FetchResult)
+}
+
+// FetchResult describes the fetch of a single feed.
+type FetchResult struct {
+	// FeedURL is the URL of the feed passed through RedactURL, the same as
+	// passed to FetchStarted.
+	FeedURL string
+	// Status is the HTTP status code, or 0 for local files and requests
+	// that got no response.
+	Status   int
+	Duration time.Duration
+	// Bytes is the size of the body read.
+	Bytes int64
+	// Items are the items of the feed, unless Err is set.
+	Items []RssItem
+	Err   error
+}
@@ -0,0 +1,143 @@
+package rssreader
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// Option configures Parse and ParseReader.
+type Option func(*options)
+
+// options holds the settings collected from Options.
+type options struct {
+	client *http.Client
+	limits Limits
+	logger *slog.Logger
+	tracer Tracer
+	// noLocalFiles refuses local paths and file:// URLs.
+	noLocalFiles bool
+	// observers are notified of every feed fetch.
+	observers []FetchObserver
+	// auth maps feed URLs to the credentials sent when fetching them.
+	auth map[string]FeedAuth
+	// sensitiveParams are redacted from URLs besides DefaultSensitiveParams.
+	sensitiveParams []string
+	redactURLs      bool
+	// lenient repairs malformed XML feeds, reporting what it did to warn.
+	lenient bool
+	warn    func(feedURL string, warnings []string)
+	// scraped maps page URLs to the selectors used instead of feed parsing.
+	scraped map[string]ScrapedSource
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{client: http.DefaultClient, limits: DefaultLimits, logger: slog.New(slog.DiscardHandler), tracer: noopTracer{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithHTTPClient makes Parse fetch feeds with client instead of
+// http.DefaultClient, e.g. the client of an AddressGuard.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithFeedAuth makes Parse send the credentials of auth when fetching
+// feedURL. They are only sent to feedURL and same-host redirects.
+func WithFeedAuth(feedURL string, auth FeedAuth) Option {
+	return func(o *options) {
+		if o.auth == nil {
+			o.auth = map[string]FeedAuth{}
+		}
+		o.auth[feedURL] = auth
+	}
+}
+
+// WithLenientParsing makes Parse and ParseReader repair malformed XML
+// feeds instead of failing: text before the document, characters XML does
+// not allow and stray < characters are removed or escaped, and if the feed
+// still doesn't parse, the items that do are kept, which salvages truncated
+// bodies too. warn, if not nil, is called with the feed URL and a
+// description of each repair. It may be called from several goroutines at
+// once.
+func WithLenientParsing(warn func(feedURL string, warnings []string)) Option {
+	return func(o *options) {
+		o.lenient = true
+		o.warn = warn
+	}
+}
+
+// WithLimits replaces DefaultLimits with limits. Fields left zero are not
+// limited.
+func WithLimits(limits Limits) Option {
+	return func(o *options) {
+		o.limits = limits
+	}
+}
+
+// WithoutLocalFiles makes Parse refuse local paths and file:// URLs, so
+// only http(s) feeds are read. Together with an AddressGuard client, it lets
+// untrusted users configure feeds without reaching local files.
+func WithoutLocalFiles() Option {
+	return func(o *options) {
+		o.noLocalFiles = true
+	}
+}
+
+// WithLogger makes Parse and ParseReader log the fetch of every feed to
+// logger: the start at debug level, and when done the duration, bytes read,
+// HTTP status, redirects followed and item count at info level, or the
+// error at error level. Feed URLs are logged through RedactURL. Nothing is
+// logged by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithTracer traces Parse with tracer, which records nothing by default.
+func WithTracer(tracer Tracer) Option {
+	return func(o *options) {
+		o.tracer = tracer
+	}
+}
+
+// WithFetchObserver notifies observer of the start and end of the fetch of
+// every feed by Parse and ParseReader, such as to record metrics. It can be given more than once.
+func WithFetchObserver(observer FetchObserver) Option {
+	return func(o *options) {
+		o.observers = append(o.observers, observer)
+	}
+}
+
+// WithRedactedURLs makes Parse redact the SourceURL and RssURL of items
+// with RedactURL, for output that must not reveal credentials embedded in
+// feed URLs. Errors are always redacted.
+func WithRedactedURLs() Option {
+	return func(o *options) {
+		o.redactURLs = true
+	}
+}
+
+// WithSensitiveParams adds query parameter names whose values are redacted
+// from URLs in errors and, with WithRedactedURLs, items.
+func WithSensitiveParams(names ...string) Option {
+	return func(o *options) {
+		o.sensitiveParams = append(o.sensitiveParams, names...)
+	}
+}
+
+// WithScrapedSource makes Parse scrape the HTML page at pageURL with the
+// selectors of source whenever pageURL is among the URLs it is given.
+func WithScrapedSource(pageURL string, source ScrapedSource) Option {
+	return func(o *options) {
+		if o.scraped == nil {
+			o.scraped = map[string]ScrapedSource{}
+		}
+		o.scraped[pageURL] = source
+	}
+}
@@ -0,0 +1,78 @@
+// Package otelrss traces rssreader.Parse with OpenTelemetry.
+//
+//	items, err := rssreader.Parse(ctx, urls, rssreader.WithTracer(otelrss.NewTracer(nil)))
+package otelrss
+
+import (
+	"context"
+	"log/slog"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the spans.
+const ScopeName = "github.com/RssReaderProject/RssReader"
+
+// NewTracer returns a rssreader.Tracer that starts OpenTelemetry spans with
+// a tracer of provider, or of the global provider if provider is nil.
+func NewTracer(provider trace.TracerProvider) rssreader.Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return tracer{provider.Tracer(ScopeName)}
+}
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+func (t tracer) Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, rssreader.Span) {
+	kind := trace.SpanKindInternal
+	if name == rssreader.SpanFetch {
+		kind = trace.SpanKindClient
+	}
+	ctx, s := t.tracer.Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(convert(attrs)...))
+	return ctx, span{s}
+}
+
+type span struct {
+	span trace.Span
+}
+
+func (s span) SetAttributes(attrs ...slog.Attr) {
+	s.span.SetAttributes(convert(attrs)...)
+}
+
+func (s span) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
+
+// convert turns slog attributes into OpenTelemetry ones, formatting values
+// of other kinds as strings.
+func convert(attrs []slog.Attr) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		v := a.Value.Resolve()
+		switch v.Kind() {
+		case slog.KindString:
+			kvs = append(kvs, attribute.String(a.Key, v.String()))
+		case slog.KindInt64:
+			kvs = append(kvs, attribute.Int64(a.Key, v.Int64()))
+		case slog.KindBool:
+			kvs = append(kvs, attribute.Bool(a.Key, v.Bool()))
+		case slog.KindFloat64:
+			kvs = append(kvs, attribute.Float64(a.Key, v.Float64()))
+		default:
+			kvs = append(kvs, attribute.String(a.Key, v.String()))
+		}
+	}
+	return kvs
+}
@@ -0,0 +1,92 @@
+package otelrss
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	rssreader "github.com/RssReaderProject/RssReader"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewTracer(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`<rss version="2.0"><channel><title>T</title><item><title>A</title></item><item><title>B</title></item></channel></rss>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	okURL := server.URL + "/feed?token=s3cret"
+	missingURL := server.URL + "/missing"
+	_, err := rssreader.Parse(context.Background(), []string{okURL, missingURL}, rssreader.WithTracer(NewTracer(provider)))
+	if err == nil {
+		t.Fatal("Expected an error for the missing feed")
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range recorder.Ended() {
+		key := s.Name()
+		for _, attr := range s.Attributes() {
+			if attr.Key == rssreader.AttrURL {
+				key += " " + attr.Value.AsString()
+			}
+		}
+		spans[key] = s
+	}
+	if len(spans) != 3 {
+		t.Fatalf("Expected a parse span and 2 fetch spans, got %v", spans)
+	}
+
+	parse := spans[rssreader.SpanParse]
+	if parse == nil {
+		t.Fatalf("Expected a %s span, got %v", rssreader.SpanParse, spans)
+	}
+	if parse.Status().Code != codes.Error {
+		t.Errorf("Expected the parse span to fail, got %v", parse.Status())
+	}
+	assertAttrs(t, parse, attribute.Int(rssreader.AttrFeedCount, 2), attribute.Int(rssreader.AttrItemCount, 2))
+
+	fetched := spans[rssreader.SpanFetch+" "+rssreader.RedactURL(okURL)]
+	if fetched == nil {
+		t.Fatalf("Expected a span for the redacted feed URL, got %v", spans)
+	}
+	if fetched.Parent().SpanID() != parse.SpanContext().SpanID() || fetched.SpanKind() != trace.SpanKindClient {
+		t.Errorf("Expected a client span under the parse span, got parent %v and kind %v", fetched.Parent(), fetched.SpanKind())
+	}
+	if fetched.Status().Code == codes.Error {
+		t.Errorf("Expected the fetch to succeed, got %v", fetched.Status())
+	}
+	assertAttrs(t, fetched, attribute.Int(rssreader.AttrStatusCode, 200), attribute.Int(rssreader.AttrItemCount, 2))
+
+	missing := spans[rssreader.SpanFetch+" "+missingURL]
+	if missing == nil {
+		t.Fatalf("Expected a span for the missing feed, got %v", spans)
+	}
+	if missing.Status().Code != codes.Error || len(missing.Events()) != 1 || missing.Events()[0].Name != "exception" {
+		t.Errorf("Expected the error to be recorded, got status %v and events %v", missing.Status(), missing.Events())
+	}
+	assertAttrs(t, missing, attribute.Int(rssreader.AttrStatusCode, 404))
+}
+
+func assertAttrs(t *testing.T, s sdktrace.ReadOnlySpan, want ...attribute.KeyValue) {
+	t.Helper()
+	for _, w := range want {
+		found := false
+		for _, attr := range s.Attributes() {
+			if attr == w {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected attribute %s=%s on %s, got %v", w.Key, w.Value.Emit(), s.Name(), s.Attributes())
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package rssreader
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// redacted replaces credentials in redacted URLs.
+const redacted = "REDACTED"
+
+// DefaultSensitiveParams are the query parameters whose values RedactURL
+// always hides. Names are matched case-insensitively.
+var DefaultSensitiveParams = []string{
+	"access_token", "api_key", "apikey", "auth", "code", "key", "password",
+	"private_token", "secret", "sig", "signature", "token",
+}
+
+// RedactURL hides the credentials in rawURL so it can be logged or shown:
+// the userinfo and the values of DefaultSensitiveParams and params in the
+// query are replaced with REDACTED. Anything that is not an absolute URL,
+// such as a local path, is returned unchanged.
+func RedactURL(rawURL string, params ...string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return redactInvalidURL(rawURL)
+	}
+	if u.Scheme == "" || (u.User == nil && u.RawQuery == "") {
+		return rawURL
+	}
+	if u.User != nil {
+		u.User = url.User(redacted)
+	}
+
+	// Rewrite the query by hand to keep the order and encoding of the
+	// parameters that are not redacted
+	pairs := strings.Split(u.RawQuery, "&")
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if sensitiveParam(name, params) {
+			pairs[i] = key + "=" + redacted
+		}
+	}
+	u.RawQuery = strings.Join(pairs, "&")
+	return u.String()
+}
+
+// redactInvalidURL redacts a URL that cannot be parsed, such as one with an
+// invalid port, which net/url quotes in its error. The userinfo and the
+// whole query are hidden, since their parameters cannot be told apart.
+func redactInvalidURL(rawURL string) string {
+	if scheme, rest, ok := strings.Cut(rawURL, "://"); ok {
+		authority, path, hasPath := strings.Cut(rest, "/")
+		if i := strings.LastIndex(authority, "@"); i >= 0 {
+			authority = redacted + authority[i:]
+		}
+		rawURL = scheme + "://" + authority
+		if hasPath {
+			rawURL += "/" + path
+		}
+	}
+	if base, _, ok := strings.Cut(rawURL, "?"); ok {
+		rawURL = base + "?" + redacted
+	}
+	return rawURL
+}
+
+// sensitiveParam reports whether the query parameter name holds a secret.
+func sensitiveParam(name string, params []string) bool {
+	match := func(param string) bool { return strings.EqualFold(param, name) }
+	return slices.ContainsFunc(DefaultSensitiveParams, match) || slices.ContainsFunc(params, match)
+}
+
+// redactError redacts the URL net/http puts into its errors, which it only
+// strips of passwords.
+func redactError(err error, params []string) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = RedactURL(urlErr.URL, params...)
+	}
+	return err
+}
@@ -0,0 +1,201 @@
+package rssreader
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/mmcdole/gofeed"
+)
+
+var (
+	// documentStarts are the ways a feed document begins.
+	documentStarts = [][]byte{[]byte("<?xml"), []byte("<rss"), []byte("<feed"), []byte("<rdf:RDF")}
+	// charRef matches numeric character references.
+	charRef = regexp.MustCompile(`&#([xX][0-9a-fA-F]+|[0-9]+);`)
+	// strayLessThan matches a < that cannot start markup, as in "a < b".
+	strayLessThan = regexp.MustCompile(`<([^a-zA-Z_:/!?])`)
+	// cdataSection matches CDATA sections, in which < needs no escaping.
+	cdataSection = regexp.MustCompile(`(?s)<!\[CDATA\[.*?\]\]>`)
+	// feedEntries match the items of RSS and the entries of Atom feeds.
+	feedEntries = []*regexp.Regexp{
+		regexp.MustCompile(`(?s)<item[\s>].*?</item>`),
+		regexp.MustCompile(`(?s)<entry[\s>].*?</entry>`),
+	}
+)
+
+// repairFeed salvages the items of an XML feed that failed to parse with
+// parseErr. It removes common breakage first, and if the feed still doesn't
+// parse, parses each item on its own, dropping those that are malformed or
+// cut off by a truncated body. The warnings describe what was repaired.
+func repairFeed(data []byte, sourceURL string, parseErr error) ([]RssItem, []string, error) {
+	warnings := []string{parseErr.Error()}
+	data, sanitized := sanitizeXML(data)
+	warnings = append(warnings, sanitized...)
+
+	if feed, err := newFeedParser().Parse(bytes.NewReader(data)); err == nil {
+		return mapFeed(feed, data, sourceURL), warnings, nil
+	}
+
+	items, dropped, err := salvageItems(data, sourceURL)
+	if err != nil {
+		return nil, nil, parseErr
+	}
+	warnings = append(warnings, fmt.Sprintf("parsed items one by one, keeping %d and dropping %d", len(items), dropped))
+	return items, warnings, nil
+}
+
+// sanitizeXML removes text before the document, characters XML does not
+// allow and escapes stray < characters.
+func sanitizeXML(data []byte) ([]byte, []string) {
+	var warnings []string
+
+	start := -1
+	for _, prefix := range documentStarts {
+		if i := bytes.Index(data, prefix); i >= 0 && (start < 0 || i < start) {
+			start = i
+		}
+	}
+	if start > 0 && len(bytes.TrimSpace(data[:start])) > 0 {
+		warnings = append(warnings, fmt.Sprintf("removed %d bytes before the document", start))
+		data = data[start:]
+	}
+
+	invalid := 0
+	clean := make([]byte, 0, len(data))
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if isXMLChar(r) && (r != utf8.RuneError || size > 1) {
+			clean = append(clean, data[:size]...)
+		} else {
+			invalid++
+		}
+		data = data[size:]
+	}
+	clean = charRef.ReplaceAllFunc(clean, func(ref []byte) []byte {
+		digits := string(ref[2 : len(ref)-1])
+		base := 10
+		if digits[0] == 'x' || digits[0] == 'X' {
+			digits, base = digits[1:], 16
+		}
+		if n, err := strconv.ParseUint(digits, base, 32); err == nil && isXMLChar(rune(n)) {
+			return ref
+		}
+		invalid++
+		return nil
+	})
+	if invalid > 0 {
+		warnings = append(warnings, fmt.Sprintf("removed %d invalid characters", invalid))
+	}
+
+	// Escape stray < outside of CDATA sections
+	var escaped []byte
+	stray := 0
+	for {
+		section := cdataSection.FindIndex(clean)
+		if section == nil {
+			section = []int{len(clean), len(clean)}
+		}
+		text := clean[:section[0]]
+		stray += len(strayLessThan.FindAllIndex(text, -1))
+		escaped = append(escaped, strayLessThan.ReplaceAll(text, []byte("&lt;$1"))...)
+		escaped = append(escaped, clean[section[0]:section[1]]...)
+		if section[1] == len(clean) {
+			break
+		}
+		clean = clean[section[1]:]
+	}
+	if stray > 0 {
+		warnings = append(warnings, fmt.Sprintf("escaped %d stray '<' characters", stray))
+	}
+	return escaped, warnings
+}
+
+// isXMLChar reports whether r may appear in an XML 1.0 document.
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		(r >= 0x20 && r <= 0xd7ff) || (r >= 0xe000 && r <= 0xfffd) || (r >= 0x10000 && r <= 0x10ffff)
+}
+
+// salvageItems parses every complete item of data on its own, wrapped in
+// the part of the document before the first item. It returns the items
+// that parse and the number dropped.
+func salvageItems(data []byte, sourceURL string) ([]RssItem, int, error) {
+	var matches [][]int
+	for _, pattern := range feedEntries {
+		if matches = pattern.FindAllIndex(data, -1); matches != nil {
+			break
+		}
+	}
+	if matches == nil {
+		return nil, 0, errors.New("no complete items")
+	}
+
+	head := data[:matches[0][0]]
+	tail := closingTags(head)
+	var merged *gofeed.Feed
+	var bases []*url.URL
+	dropped := 0
+	for _, match := range matches {
+		doc := slices.Concat(head, data[match[0]:match[1]], tail)
+		feed, err := newFeedParser().Parse(bytes.NewReader(doc))
+		if err != nil || len(feed.Items) == 0 {
+			dropped++
+			continue
+		}
+		if merged == nil {
+			merged = feed
+		} else {
+			merged.Items = append(merged.Items, feed.Items...)
+		}
+		bases = append(bases, itemBases(feed, doc, sourceURL)...)
+	}
+	if merged == nil {
+		return nil, dropped, errors.New("no item could be parsed")
+	}
+
+	items := feedItems(merged, sourceURL)
+	resolveItemURLs(items, bases)
+	return items, dropped, nil
+}
+
+// closingTags returns the end tags of the elements left open at the end of
+// head, innermost first.
+func closingTags(head []byte) []byte {
+	decoder := xml.NewDecoder(bytes.NewReader(head))
+	decoder.Strict = false
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+
+	var open []xml.Name
+	for {
+		token, err := decoder.RawToken()
+		if err != nil {
+			break
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			open = append(open, token.Name)
+		case xml.EndElement:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		}
+	}
+
+	var tags bytes.Buffer
+	for i := len(open) - 1; i >= 0; i-- {
+		name := open[i].Local
+		if open[i].Space != "" {
+			name = open[i].Space + ":" + name
+		}
+		tags.WriteString("</" + name + ">")
+	}
+	return tags.Bytes()
+}
@@ -0,0 +1,141 @@
+package rssreader
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// lenientParse parses feed leniently and returns its items and warnings.
+func lenientParse(t *testing.T, feed string) ([]RssItem, []string) {
+	t.Helper()
+	var warnings []string
+	items, err := ParseReader(context.Background(), strings.NewReader(feed), "https://example.com/feed",
+		WithLenientParsing(func(feedURL string, w []string) {
+			if feedURL != "https://example.com/feed" {
+				t.Errorf("Expected warnings for the feed URL, got %q", feedURL)
+			}
+			warnings = append(warnings, w...)
+		}))
+	if err != nil {
+		t.Fatalf("Expected the feed to be repaired, got: %v", err)
+	}
+	return items, warnings
+}
+
+func containsWarning(warnings []string, want string) bool {
+	for _, warning := range warnings {
+		if strings.Contains(warning, want) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLenientParsing_Repairs(t *testing.T) {
+	tests := map[string]struct {
+		feed    string
+		titles  []string
+		warning string
+	}{
+		"control characters": {
+			feed:    "<rss version=\"2.0\"><channel><title>Feed</title><item><title>Bad\x01 title\x0b</title><link>/1</link></item><item><title>Ref &#1;</title></item></channel></rss>",
+			titles:  []string{"Bad title", "Ref"},
+			warning: "removed 3 invalid characters",
+		},
+		"text before the document": {
+			feed:    "Warning: Undefined variable $x in feed.php on line 3\n<?xml version=\"1.0\"?><rss version=\"2.0\"><channel><title>Feed</title><item><title>One</title></item></channel></rss>",
+			titles:  []string{"One"},
+			warning: "bytes before the document",
+		},
+		"stray less-than": {
+			feed:    `<rss version="2.0"><channel><title>Feed</title><item><title>1 < 2</title><description><![CDATA[<p>3 < 4</p>]]></description></item></channel></rss>`,
+			titles:  []string{"1 < 2"},
+			warning: "escaped 1 stray '<'",
+		},
+		"truncated body": {
+			feed:    `<rss version="2.0"><channel><title>Feed</title><item><title>One</title><link>/1</link></item><item><title>Two</title><link>/2</link></item><item><title>Thr`,
+			titles:  []string{"One", "Two"},
+			warning: "keeping 2 and dropping 0",
+		},
+		"broken item": {
+			feed:    `<rss version="2.0"><channel><title>Feed</title><item><title>One</title></item><item><title>Two</title><guid isPermaLink="false"">2</guid></item><item><title>Three</title></item></channel></rss>`,
+			titles:  []string{"One", "Three"},
+			warning: "keeping 2 and dropping 1",
+		},
+		"truncated atom": {
+			feed:    `<feed xmlns="http://www.w3.org/2005/Atom" xml:base="https://example.org/blog/"><title>Atom</title><entry><title>One</title><link href="one"/></entry><entry><title>Tw`,
+			titles:  []string{"One"},
+			warning: "keeping 1 and dropping 0",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			items, warnings := lenientParse(t, tt.feed)
+			var titles []string
+			for _, item := range items {
+				titles = append(titles, item.Title)
+			}
+			if strings.Join(titles, "|") != strings.Join(tt.titles, "|") {
+				t.Errorf("Expected titles %q, got %q", tt.titles, titles)
+			}
+			if !containsWarning(warnings, tt.warning) {
+				t.Errorf("Expected a warning containing %q, got %q", tt.warning, warnings)
+			}
+			if len(items) > 0 && items[0].Source == "" {
+				t.Error("Expected the feed title as source")
+			}
+		})
+	}
+}
+
+func TestLenientParsing_ResolvesSalvagedLinks(t *testing.T) {
+	items, _ := lenientParse(t, `<feed xmlns="http://www.w3.org/2005/Atom" xml:base="https://example.org/blog/"><title>Atom</title><entry><title>One</title><link href="one"/></entry><entry><title>Tw`)
+	if items[0].Link != "https://example.org/blog/one" {
+		t.Errorf("Expected the link resolved against xml:base, got %q", items[0].Link)
+	}
+
+	items, _ = lenientParse(t, `<rss version="2.0"><channel><title>Feed</title><item><title>One</title><link>/1</link></item><item><title>Tw`)
+	if items[0].Link != "https://example.com/1" {
+		t.Errorf("Expected the link resolved against the feed URL, got %q", items[0].Link)
+	}
+}
+
+func TestLenientParsing_OptIn(t *testing.T) {
+	feed := "<rss version=\"2.0\"><channel><title>Feed</title><item><title>Bad\x01</title></item></channel></rss>"
+	if _, err := ParseReader(context.Background(), strings.NewReader(feed), "feed"); err == nil {
+		t.Error("Expected malformed feeds to fail without lenient parsing")
+	}
+	if _, err := ParseReader(context.Background(), strings.NewReader("This is not valid XML"), "feed", WithLenientParsing(nil)); err == nil {
+		t.Error("Expected documents without items to fail even when lenient")
+	}
+}
+
+func TestLenientParsing_WarningsPerFeed(t *testing.T) {
+	good := testServer(readTestdata(t, "rss2.xml"), "application/rss+xml")
+	defer good.Close()
+	broken := testServer("<rss version=\"2.0\"><channel><title>Broken</title><item><title>Bad\x01</title></item></channel></rss>", "application/rss+xml")
+	defer broken.Close()
+
+	var mu sync.Mutex
+	warned := map[string][]string{}
+	items, err := Parse(context.Background(), []string{good.URL, broken.URL}, WithLenientParsing(func(feedURL string, warnings []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		warned[feedURL] = warnings
+	}))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(warned) != 1 || len(warned[broken.URL]) == 0 {
+		t.Errorf("Expected warnings for the broken feed only, got %v", warned)
+	}
+	sources := map[string]bool{}
+	for _, item := range items {
+		sources[item.Source] = true
+	}
+	if !sources["Broken"] || len(sources) != 2 {
+		t.Errorf("Expected items from both feeds, got %v", sources)
+	}
+}
@@ -2,10 +2,21 @@
 package rssreader
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mmcdole/gofeed"
@@ -20,14 +31,79 @@ type RssItem struct {
 	PublishDate time.Time
 	Description string
 	RssURL      string
+
+	// Content is the full HTML content when the feed provides it separately
+	// from the summary in Description (content:encoded in RSS, content in
+	// Atom, content_html or content_text in JSON Feed).
+	Content string `json:",omitempty"`
+	// FullContent is the article body extracted from the page at Link by a
+	// FullTextFetcher, for feeds that only ship a teaser.
+	FullContent string `json:",omitempty"`
+	// Updated is when the item was last modified, if the feed says so.
+	Updated time.Time `json:",omitzero"`
+	// Enclosures are the media files attached to the item.
+	Enclosures []Enclosure `json:",omitempty"`
+
+	// ITunes holds the podcast details of the itunes: namespace.
+	ITunes *ITunes `json:",omitempty"`
+	// Media holds the Media RSS (media:) content and thumbnails.
+	Media *Media `json:",omitempty"`
+	// YouTube holds the yt: details of YouTube channel and playlist feeds.
+	YouTube *YouTube `json:",omitempty"`
+}
+
+// Enclosure is a media file attached to an item, such as a podcast episode.
+type Enclosure struct {
+	URL    string
+	Type   string `json:",omitempty"`
+	Length int64  `json:",omitempty"` // size in bytes, 0 if unknown
+}
+
+// SourceGroup holds the items of a single feed, as returned by GroupBySource.
+type SourceGroup struct {
+	Source    string
+	SourceURL string
+	RssURL    string
+	Items     []RssItem
+}
+
+// GroupBySource groups items by feed in order of first appearance, keeping
+// the order of the items within each feed.
+func GroupBySource(items []RssItem) []SourceGroup {
+	var groups []SourceGroup
+	index := make(map[string]int)
+	for _, item := range items {
+		key := item.RssURL + "\x00" + item.Source
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, SourceGroup{
+				Source:    item.Source,
+				SourceURL: item.SourceURL,
+				RssURL:    item.RssURL,
+			})
+		}
+		groups[i].Items = append(groups[i].Items, item)
+	}
+	return groups
 }
 
 // Parse fetches and parses RSS feeds from the provided URLs asynchronously.
+// Besides http(s) URLs, file:// URLs and local paths are read from disk.
+// Local paths must be absolute or start with ./ or ../; other URLs without a
+// scheme fail rather than being read as files.
 // It returns a slice of RssItem and any error encountered during parsing.
-func Parse(ctx context.Context, urls []string) ([]RssItem, error) {
+func Parse(ctx context.Context, urls []string, opts ...Option) (items []RssItem, err error) {
 	if len(urls) == 0 {
 		return []RssItem{}, nil
 	}
+	o := newOptions(opts)
+	ctx, span := o.tracer.Start(ctx, SpanParse, slog.Int(AttrFeedCount, len(urls)))
+	defer func() {
+		span.SetAttributes(slog.Int(AttrItemCount, len(items)))
+		span.End(err)
+	}()
 
 	// Create channels to collect results from goroutines
 	resultChan := make(chan []RssItem, len(urls))
@@ -42,9 +118,9 @@ func Parse(ctx context.Context, urls []string) ([]RssItem, error) {
 		go func(feedURL string) {
 			defer wg.Done()
 
-			items, err := parseSingleFeed(ctx, feedURL)
+			items, err := parseSingleFeed(ctx, feedURL, o)
 			if err != nil {
-				errorChan <- fmt.Errorf("failed to parse feed %s: %w", feedURL, err)
+				errorChan <- fmt.Errorf("failed to parse feed %s: %w", RedactURL(feedURL, o.sensitiveParams...), redactError(err, o.sensitiveParams))
 				return
 			}
 
@@ -75,7 +151,7 @@ func Parse(ctx context.Context, urls []string) ([]RssItem, error) {
 
 	// Return error if any occurred
 	if len(errors) > 0 {
-		return allItems, fmt.Errorf("encountered %d errors: %v", len(errors), errors)
+		return allItems, parseErrors(errors)
 	}
 
 	// Sort all items by PublishDate across all feeds
@@ -86,19 +162,253 @@ func Parse(ctx context.Context, urls []string) ([]RssItem, error) {
 	return allItems, nil
 }
 
-// parseSingleFeed parses a single RSS feed from the given URL
-func parseSingleFeed(ctx context.Context, url string) ([]RssItem, error) {
-	fp := gofeed.NewParser()
+// ParseReader parses a single feed read from r. Items are attributed to
+// sourceURL, which may be any identifier such as the URL the feed was
+// archived from or a file name. Reading stops once ctx is done.
+func ParseReader(ctx context.Context, r io.Reader, sourceURL string, opts ...Option) ([]RssItem, error) {
+	o := newOptions(opts)
+	return observeFeed(ctx, sourceURL, o, func(ctx context.Context, fetch *feedFetch) ([]RssItem, error) {
+		fetch.body.r = r
+		return parseDocument(ctx, &fetch.body, sourceURL, "", o)
+	})
+}
 
+// parseSingleFeed parses a single RSS feed from the given URL or local path
+func parseSingleFeed(ctx context.Context, url string, o *options) ([]RssItem, error) {
 	// Set timeout for the request
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	feed, err := fp.ParseURLWithContext(url, ctx)
+	return observeFeed(ctx, url, o, func(ctx context.Context, fetch *feedFetch) ([]RssItem, error) {
+		var rc io.ReadCloser
+		var contentType string
+		path, local, err := localPath(url)
+		switch {
+		case err != nil:
+		case local && o.noLocalFiles:
+			err = fmt.Errorf("%w: %s", ErrLocalFeed, path)
+		case local:
+			rc, err = os.Open(path) // #nosec G304 -- reading the feed files the caller asked for is the point
+		default:
+			var resp *http.Response
+			if resp, err = fetchFeed(ctx, url, o, fetch); err == nil {
+				rc, contentType = resp.Body, resp.Header.Get("Content-Type")
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = rc.Close() }()
+		fetch.body.r = rc
+
+		return parseDocument(ctx, &fetch.body, url, contentType, o)
+	})
+}
+
+// feedFetch records what reading a feed took, for logs, traces and
+// observers.
+type feedFetch struct {
+	body      countingReader
+	status    int
+	redirects int
+	// requests counts the HTTP requests sent, 0 for feeds not fetched over
+	// HTTP
+	requests atomic.Int32
+}
+
+// retries returns the number of requests that were neither the first nor
+// a redirect, which only a retrying HTTP client sends.
+func (f *feedFetch) retries() int {
+	return max(0, int(f.requests.Load())-1-f.redirects)
+}
+
+// observeFeed reads the feed at url with read, which reads the body through
+// fetch.body, and reports the fetch to the logger, tracer and observers of o.
+func observeFeed(ctx context.Context, url string, o *options, read func(context.Context, *feedFetch) ([]RssItem, error)) (items []RssItem, err error) {
+	feedURL := RedactURL(url, o.sensitiveParams...)
+	ctx, span := o.tracer.Start(ctx, SpanFetch, slog.String(AttrURL, feedURL))
+	logger := o.logger.With("feed", feedURL)
+	logger.DebugContext(ctx, "fetching feed")
+	for _, observer := range o.observers {
+		observer.FetchStarted(feedURL)
+	}
+	start := time.Now()
+	fetch := &feedFetch{}
+	defer func() {
+		duration := time.Since(start)
+		status := fetch.status
+		var httpErr gofeed.HTTPError
+		if errors.As(err, &httpErr) {
+			status = httpErr.StatusCode
+		}
+		spanAttrs := []slog.Attr{slog.Int64(AttrBytes, fetch.body.n)}
+		if status != 0 {
+			spanAttrs = append(spanAttrs, slog.Int(AttrStatusCode, status))
+		}
+		if err == nil {
+			spanAttrs = append(spanAttrs, slog.Int(AttrItemCount, len(items)))
+		}
+		span.SetAttributes(spanAttrs...)
+		span.End(redactError(err, o.sensitiveParams))
+
+		result := FetchResult{FeedURL: feedURL, Status: status, Duration: duration, Bytes: fetch.body.n, Items: items, Err: err}
+		for _, observer := range o.observers {
+			observer.FetchFinished(result)
+		}
+
+		attrs := []any{"duration", duration, "bytes", fetch.body.n}
+		if status != 0 {
+			attrs = append(attrs, "status", status)
+		}
+		if fetch.requests.Load() > 0 {
+			attrs = append(attrs, "retries", fetch.retries())
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "feed failed", append(attrs, "error", redactError(err, o.sensitiveParams))...)
+			return
+		}
+		if status != 0 {
+			attrs = append(attrs, "redirects", fetch.redirects)
+		}
+		logger.InfoContext(ctx, "fetched feed", append(attrs, "items", len(items))...)
+	}()
+
+	return read(ctx, fetch)
+}
+
+// parseDocument parses the document at sourceURL as a feed, or scrapes it
+// if it is configured as a scraped source. contentType, if known, names the
+// charset of the document. The document and its items are checked against
+// the limits of o.
+func parseDocument(ctx context.Context, r io.Reader, sourceURL, contentType string, o *options) ([]RssItem, error) {
+	r = o.limits.reader(r)
+	var items []RssItem
+	var err error
+	if source, ok := o.scraped[sourceURL]; ok {
+		items, err = scrapePage(ctx, r, sourceURL, contentType, source)
+	} else {
+		items, err = parseFeed(ctx, r, sourceURL, contentType, o)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if err := o.limits.checkItems(items); err != nil {
+		return nil, err
+	}
+	if o.redactURLs {
+		for i := range items {
+			items[i].SourceURL = RedactURL(items[i].SourceURL, o.sensitiveParams...)
+			items[i].RssURL = RedactURL(items[i].RssURL, o.sensitiveParams...)
+		}
+	}
+	return items, nil
+}
 
+// parseFeed reads a whole feed document from r and maps its items. Relative
+// item URLs are resolved against xml:base in Atom feeds and against
+// sourceURL when it is an absolute URL. The feed is converted to UTF-8
+// first, see toUTF8, and malformed XML is repaired if o asks for it.
+func parseFeed(ctx context.Context, r io.Reader, sourceURL, contentType string, o *options) ([]RssItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(&contextReader{ctx: ctx, r: r})
+	if err != nil {
+		return nil, err
+	}
+	// Convert first, since the depth check reads the markup as UTF-8
+	data = toUTF8(data, contentType)
+	if err := o.limits.checkDepth(data); err != nil {
+		return nil, err
+	}
+
+	feed, err := newFeedParser().Parse(bytes.NewReader(data))
+	if err != nil {
+		// Report cancellation rather than the parse error it caused
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if !o.lenient {
+			return nil, err
+		}
+		items, warnings, err := repairFeed(data, sourceURL, err)
+		if err != nil {
+			return nil, err
+		}
+		o.logger.WarnContext(ctx, "repaired malformed feed", "feed", RedactURL(sourceURL, o.sensitiveParams...), "warnings", warnings)
+		if o.warn != nil {
+			o.warn(sourceURL, warnings)
+		}
+		return items, nil
+	}
+	return mapFeed(feed, data, sourceURL), nil
+}
+
+// mapFeed maps the items of feed, parsed from data, and resolves their
+// relative URLs.
+func mapFeed(feed *gofeed.Feed, data []byte, sourceURL string) []RssItem {
+	items := feedItems(feed, sourceURL)
+	resolveItemURLs(items, itemBases(feed, data, sourceURL))
+	return items
+}
+
+// localPath reports whether feedURL refers to a local file and returns the
+// path. Local files are file:// URLs without a host other than localhost,
+// absolute paths and paths starting with ./ or ../. Other input without a
+// scheme, such as example.com/feed.xml or //example.com/feed.xml, is
+// rejected, so that a URL missing its scheme is not read from disk.
+func localPath(feedURL string) (string, bool, error) {
+	u, err := neturl.Parse(feedURL)
+	if err != nil {
+		return "", false, nil
+	}
+	switch {
+	case u.Scheme == "file":
+		if u.Host != "" && u.Host != "localhost" {
+			return "", false, fmt.Errorf("file URL %s has a host: only local files can be read", feedURL)
+		}
+		return filepath.FromSlash(u.Path), true, nil
+	case len(u.Scheme) == 1 && filepath.VolumeName(feedURL) != "":
+		// A Windows path such as C:\feeds\go.xml
+		return feedURL, true, nil
+	case u.Scheme != "":
+		return "", false, nil
+	case strings.HasPrefix(feedURL, "//") || strings.HasPrefix(feedURL, `\\`):
+		return "", false, fmt.Errorf("feed URL %s has no scheme: use http:// or https://", feedURL)
+	case filepath.IsAbs(feedURL) || isRelativePath(feedURL):
+		return feedURL, true, nil
+	}
+	return "", false, fmt.Errorf("feed URL %s has no scheme: use http://, https://, file:// or a path starting with /, ./ or ../", feedURL)
+}
+
+// isRelativePath reports whether path starts with ./ or ../, or with .\ or
+// ..\ on Windows.
+func isRelativePath(path string) bool {
+	for _, prefix := range []string{".", ".."} {
+		if strings.HasPrefix(path, prefix+"/") || strings.HasPrefix(path, prefix+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// contextReader stops reading once its context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// feedItems maps the items of a parsed feed to RssItems attributed to the
+// feed at url.
+func feedItems(feed *gofeed.Feed, url string) []RssItem {
 	var items []RssItem
 	for _, item := range feed.Items {
 		rssItem := RssItem{
@@ -108,6 +418,37 @@ func parseSingleFeed(ctx context.Context, url string) ([]RssItem, error) {
 			Link:        item.Link,
 			Description: item.Description,
 			RssURL:      url,
+			Content:     item.Content,
+		}
+
+		// Feeds that only provide full content still get a description
+		if rssItem.Description == "" {
+			rssItem.Description = item.Content
+		}
+
+		if item.UpdatedParsed != nil {
+			rssItem.Updated = *item.UpdatedParsed
+		}
+
+		for _, enclosure := range item.Enclosures {
+			if enclosure == nil || enclosure.URL == "" {
+				continue
+			}
+			length, _ := strconv.ParseInt(enclosure.Length, 10, 64)
+			rssItem.Enclosures = append(rssItem.Enclosures, Enclosure{
+				URL:    enclosure.URL,
+				Type:   enclosure.Type,
+				Length: max(length, 0),
+			})
+		}
+
+		rssItem.ITunes = itunesExtension(feed, item)
+		rssItem.Media = mediaExtension(item.Extensions)
+		rssItem.YouTube = youTubeExtension(item.Extensions)
+
+		// YouTube and other video feeds only describe items in media:description
+		if rssItem.Description == "" && rssItem.Media != nil && rssItem.Media.Description != "" {
+			rssItem.Description = textToHTML(rssItem.Media.Description)
 		}
 
 		// Handle publish date
@@ -123,5 +464,17 @@ func parseSingleFeed(ctx context.Context, url string) ([]RssItem, error) {
 		items = append(items, rssItem)
 	}
 
-	return items, nil
+	return items
+}
+
+// parseErrors is the error Parse returns when feeds fail. It unwraps to the
+// error of each feed, so errors.Is and errors.As see through it.
+type parseErrors []error
+
+func (e parseErrors) Error() string {
+	return fmt.Sprintf("encountered %d errors: %v", len(e), []error(e))
+}
+
+func (e parseErrors) Unwrap() []error {
+	return e
 }
@@ -1,10 +1,20 @@
 package rssreader
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -709,3 +719,271 @@ func TestParse_SortingWithMultipleFeeds(t *testing.T) {
 		}
 	}
 }
+
+func TestParseReader(t *testing.T) {
+	f, err := os.Open("./testdata/rss2.xml")
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	items, err := ParseReader(context.Background(), f, "http://archive.example.com/feed")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(items))
+	}
+	item := items[0]
+	if item.Title != "Second Post" || item.Source != "Archived Feed" {
+		t.Errorf("Unexpected item: %+v", item)
+	}
+	if item.RssURL != "http://archive.example.com/feed" || item.SourceURL != "http://archive.example.com/feed" {
+		t.Errorf("Expected items attributed to the source URL, got %+v", item)
+	}
+}
+
+func TestParseReader_CancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := ParseReader(ctx, strings.NewReader("<rss></rss>"), "stdin")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+}
+
+func TestParse_LocalFiles(t *testing.T) {
+	abs, err := filepath.Abs("./testdata/rss2.xml")
+	if err != nil {
+		t.Fatalf("Failed to resolve fixture path: %v", err)
+	}
+	fileURL := (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+
+	for _, source := range []string{"./testdata/rss2.xml", abs, fileURL} {
+		items, err := Parse(context.Background(), []string{source})
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", source, err)
+		}
+		if len(items) != 2 || items[0].Title != "First Post" {
+			t.Errorf("Parse(%q): expected 2 sorted items, got %+v", source, items)
+		}
+		if items[0].RssURL != source {
+			t.Errorf("Parse(%q): expected RssURL to be the given source, got %q", source, items[0].RssURL)
+		}
+	}
+}
+
+func TestParse_MissingLocalFile(t *testing.T) {
+	_, err := Parse(context.Background(), []string{"./testdata/does-not-exist.xml"})
+	if err == nil {
+		t.Fatal("Expected error for a missing file, got nil")
+	}
+	if !strings.Contains(err.Error(), "does-not-exist.xml") {
+		t.Errorf("Expected the path in the error, got: %v", err)
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	tests := []struct {
+		input   string
+		path    string
+		local   bool
+		wantErr bool
+	}{
+		{"./feeds/go.xml", "./feeds/go.xml", true, false},
+		{"../feeds/go.xml", "../feeds/go.xml", true, false},
+		{"/var/feeds/go.xml", "/var/feeds/go.xml", true, false},
+		{"file:///var/feeds/go.xml", filepath.FromSlash("/var/feeds/go.xml"), true, false},
+		{"file://localhost/var/feeds/go.xml", filepath.FromSlash("/var/feeds/go.xml"), true, false},
+		{"https://example.com/feed.xml", "", false, false},
+		{"file://server/share/go.xml", "", false, true},
+		{"feeds/go.xml", "", false, true},
+		{"example.com/feed.xml", "", false, true},
+		{"//example.com/feed.xml", "", false, true},
+	}
+	for _, tt := range tests {
+		path, local, err := localPath(tt.input)
+		if path != tt.path || local != tt.local || (err != nil) != tt.wantErr {
+			t.Errorf("localPath(%q) = %q, %v, %v; expected %q, %v, error %v", tt.input, path, local, err, tt.path, tt.local, tt.wantErr)
+		}
+	}
+}
+
+func TestParse_URLWithoutScheme(t *testing.T) {
+	_, err := Parse(context.Background(), []string{"example.com/feed.xml"})
+	if err == nil || !strings.Contains(err.Error(), "has no scheme") {
+		t.Errorf("Expected an error for the missing scheme, got %v", err)
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	feed := readTestdata(t, "rss2.xml")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/feed", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(feed))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var out bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	okURL := server.URL + "/old?token=s3cret"
+	missingURL := server.URL + "/missing"
+	_, _ = Parse(context.Background(), []string{okURL, missingURL}, WithLogger(logger))
+
+	records := map[string]map[string]any{}
+	for line := range strings.Lines(out.String()) {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Expected JSON log lines, got %q", line)
+		}
+		records[record["msg"].(string)+" "+record["feed"].(string)] = record
+	}
+	if strings.Contains(out.String(), "s3cret") {
+		t.Errorf("Expected the token to be redacted, got %s", out.String())
+	}
+
+	redactedURL := RedactURL(okURL)
+	if _, ok := records["fetching feed "+redactedURL]; !ok {
+		t.Errorf("Expected a debug record for the start of the fetch, got %v", records)
+	}
+	fetched := records["fetched feed "+redactedURL]
+	if fetched == nil {
+		t.Fatalf("Expected a record for the fetched feed, got %v", records)
+	}
+	if fetched["level"] != "INFO" || fetched["status"] != 200.0 || fetched["redirects"] != 1.0 ||
+		fetched["retries"] != 0.0 || fetched["bytes"] != float64(len(feed)) || fetched["items"] != 2.0 {
+		t.Errorf("Expected status 200, 1 redirect, no retries, %d bytes and 2 items, got %v", len(feed), fetched)
+	}
+	if _, ok := fetched["duration"]; !ok {
+		t.Errorf("Expected the duration, got %v", fetched)
+	}
+
+	failed := records["feed failed "+missingURL]
+	if failed == nil {
+		t.Fatalf("Expected a record for the failed feed, got %v", records)
+	}
+	if failed["level"] != "ERROR" || failed["status"] != 404.0 || failed["error"] == nil {
+		t.Errorf("Expected an error with status 404, got %v", failed)
+	}
+}
+
+// retryingTransport retries requests that fail with 503 once.
+type retryingTransport struct{}
+
+func (retryingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+		return resp, err
+	}
+	_ = resp.Body.Close()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestWithLogger_Retries(t *testing.T) {
+	feed := readTestdata(t, "rss2.xml")
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(feed))
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&out, nil))
+	client := &http.Client{Transport: retryingTransport{}}
+	if _, err := Parse(context.Background(), []string{server.URL}, WithLogger(logger), WithHTTPClient(client)); err != nil {
+		t.Fatal(err)
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a JSON log line, got %q", out.String())
+	}
+	if record["retries"] != 1.0 || record["redirects"] != 0.0 {
+		t.Errorf("Expected 1 retry and no redirects, got %v", record)
+	}
+}
+
+// recordingObserver records the fetches it is notified of.
+type recordingObserver struct {
+	mu       sync.Mutex
+	started  []string
+	finished []FetchResult
+}
+
+func (r *recordingObserver) FetchStarted(feedURL string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started = append(r.started, feedURL)
+}
+
+func (r *recordingObserver) FetchFinished(result FetchResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finished = append(r.finished, result)
+}
+
+func TestWithFetchObserver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	}))
+	defer server.Close()
+
+	observer := &recordingObserver{}
+	feedURL := server.URL + "/feed?token=s3cret"
+	_, err := Parse(context.Background(), []string{feedURL, "./testdata/rss2.xml"}, WithFetchObserver(observer))
+	if err == nil {
+		t.Fatal("Expected an error for the gone feed")
+	}
+
+	if len(observer.started) != 2 || len(observer.finished) != 2 {
+		t.Fatalf("Expected 2 started and finished fetches, got %v and %d", observer.started, len(observer.finished))
+	}
+	for _, result := range observer.finished {
+		switch result.FeedURL {
+		case RedactURL(feedURL):
+			if result.Status != http.StatusGone || result.Err == nil || result.Items != nil {
+				t.Errorf("Expected status 410 and an error, got %+v", result)
+			}
+		case "./testdata/rss2.xml":
+			if result.Status != 0 || result.Err != nil || len(result.Items) != 2 || result.Bytes == 0 {
+				t.Errorf("Expected 2 items from the local file, got %+v", result)
+			}
+		default:
+			t.Errorf("Expected a redacted feed URL, got %s", result.FeedURL)
+		}
+	}
+}
+
+func TestGroupBySource(t *testing.T) {
+	items := []RssItem{
+		{Title: "A1", Source: "A", RssURL: "https://a.example/feed"},
+		{Title: "B1", Source: "B", RssURL: "https://b.example/feed"},
+		{Title: "A2", Source: "A", RssURL: "https://a.example/feed"},
+		{Title: "C1", RssURL: "https://c.example/feed"},
+	}
+
+	groups := GroupBySource(items)
+	if len(groups) != 3 {
+		t.Fatalf("Expected 3 groups, got %d", len(groups))
+	}
+	for i, want := range []string{"A", "B", ""} {
+		if groups[i].Source != want {
+			t.Errorf("Expected group %d to be %q, got %q", i, want, groups[i].Source)
+		}
+	}
+	if len(groups[0].Items) != 2 || groups[0].Items[1].Title != "A2" {
+		t.Errorf("Expected A1 and A2 in the first group, got %v", groups[0].Items)
+	}
+	if groups[2].RssURL != "https://c.example/feed" {
+		t.Errorf("Expected the feed URL of the last group, got %q", groups[2].RssURL)
+	}
+}
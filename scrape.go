@@ -0,0 +1,188 @@
+package rssreader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html/charset"
+)
+
+// ScrapedSource describes how to turn an HTML page without a feed into
+// items with CSS selectors. Title, Link, Date and Summary are matched within
+// each Item element.
+type ScrapedSource struct {
+	Name string // Source of the items, the page title if empty
+	Item string // selects the element holding each item
+	// Title selects the item title, the text of the link if empty.
+	Title string
+	// Link selects the item link. The href of the element or of the first
+	// link inside it is used, and the first link of the item if empty.
+	Link string
+	// Date selects the publish date, read from a datetime or content
+	// attribute if present and from the text otherwise.
+	Date string
+	// DateLayout is the time.Parse layout of the date. If empty, RFC 3339,
+	// RFC 1123, 2006-01-02 and a few written out forms are tried.
+	DateLayout string
+	Summary    string // selects the HTML used as the item description
+}
+
+// scrapeDateLayouts are tried in order when a ScrapedSource has no layout.
+var scrapeDateLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"02.01.2006",
+}
+
+// scrapePage extracts the items of an HTML page with the selectors of
+// source. Relative links are resolved against the page's <base> or
+// pageURL. It fails if the Item selector matches nothing, which usually
+// means the page layout changed.
+func scrapePage(ctx context.Context, r io.Reader, pageURL, contentType string, source ScrapedSource) ([]RssItem, error) {
+	if err := source.validate(); err != nil {
+		return nil, err
+	}
+	// Use the charset of contentType or of the page's <meta> tag
+	body, err := charset.NewReader(&contextReader{ctx: ctx, r: r}, contentType)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+
+	name := source.Name
+	if name == "" {
+		name = collapseSpace(doc.Find("title").First().Text())
+	}
+
+	var items []RssItem
+	doc.Find(source.Item).Each(func(_ int, s *goquery.Selection) {
+		item := RssItem{
+			Source:    name,
+			SourceURL: pageURL,
+			RssURL:    pageURL,
+		}
+
+		link := s
+		if source.Link != "" {
+			link = s.Find(source.Link).First()
+		}
+		if goquery.NodeName(link) != "a" || link.AttrOr("href", "") == "" {
+			link = link.Find("a[href]").First()
+		}
+		item.Link = strings.TrimSpace(link.AttrOr("href", ""))
+
+		if source.Title != "" {
+			item.Title = collapseSpace(s.Find(source.Title).First().Text())
+		} else {
+			item.Title = collapseSpace(link.Text())
+		}
+		if source.Date != "" {
+			item.PublishDate = scrapeDate(s.Find(source.Date).First(), source.DateLayout)
+		}
+		if source.Summary != "" {
+			summary, _ := s.Find(source.Summary).First().Html()
+			item.Description = strings.TrimSpace(summary)
+		}
+
+		if item.Title != "" || item.Link != "" {
+			items = append(items, item)
+		}
+	})
+	if len(items) == 0 {
+		return nil, fmt.Errorf("item selector %q matched no items", source.Item)
+	}
+
+	if base := pageBase(doc, pageURL); base != nil {
+		bases := make([]*url.URL, len(items))
+		for i := range bases {
+			bases[i] = base
+		}
+		resolveItemURLs(items, bases)
+	}
+	return items, nil
+}
+
+// validate checks that all selectors of the source compile, since goquery
+// silently matches nothing for invalid selectors.
+func (s ScrapedSource) validate() error {
+	if s.Item == "" {
+		return errors.New("scraped source has no item selector")
+	}
+	selectors := []struct{ name, value string }{
+		{"item", s.Item}, {"title", s.Title}, {"link", s.Link}, {"date", s.Date}, {"summary", s.Summary},
+	}
+	for _, selector := range selectors {
+		if selector.value == "" {
+			continue
+		}
+		if _, err := cascadia.Compile(selector.value); err != nil {
+			return fmt.Errorf("invalid %s selector %q: %w", selector.name, selector.value, err)
+		}
+	}
+	return nil
+}
+
+// scrapeDate parses the date held by s. Dates that cannot be parsed are
+// left zero.
+func scrapeDate(s *goquery.Selection, layout string) time.Time {
+	value, ok := s.Attr("datetime")
+	if !ok {
+		value, ok = s.Attr("content")
+	}
+	if !ok {
+		value = s.Text()
+	}
+	value = collapseSpace(value)
+
+	layouts := scrapeDateLayouts
+	if layout != "" {
+		layouts = []string{layout}
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// pageBase returns the URL relative links of the page are resolved against,
+// or nil if pageURL is not an absolute http(s) URL.
+func pageBase(doc *goquery.Document, pageURL string) *url.URL {
+	base, err := url.Parse(pageURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") {
+		return nil
+	}
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if ref, err := url.Parse(strings.TrimSpace(href)); err == nil {
+			base = base.ResolveReference(ref)
+		}
+	}
+	return base
+}
+
+// collapseSpace trims s and replaces runs of whitespace with single spaces.
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
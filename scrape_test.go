@@ -0,0 +1,129 @@
+package rssreader
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// councilSource scrapes testdata/scrape.html.
+var councilSource = ScrapedSource{
+	Item:    "article.news-item",
+	Title:   ".headline",
+	Link:    ".headline a",
+	Date:    "time, .date",
+	Summary: ".teaser",
+}
+
+func TestScrape_Fixture(t *testing.T) {
+	data, err := os.ReadFile("./testdata/scrape.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := testServer(string(data), "text/html; charset=utf-8")
+	defer server.Close()
+
+	pageURL := server.URL + "/council"
+	items, err := Parse(context.Background(), []string{pageURL}, WithScrapedSource(pageURL, councilSource))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 items, got %d", len(items))
+	}
+
+	byTitle := map[string]RssItem{}
+	for _, item := range items {
+		byTitle[item.Title] = item
+		if item.Source != "Town Council News" || item.RssURL != pageURL || item.SourceURL != pageURL {
+			t.Errorf("Expected items attributed to the page, got %+v", item)
+		}
+	}
+
+	budget := byTitle["Budget approved for 2006"]
+	if budget.Link != server.URL+"/news/budget-2006" {
+		t.Errorf("Expected the link resolved against <base>, got %q", budget.Link)
+	}
+	if !budget.PublishDate.Equal(time.Date(2006, 1, 4, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the date from the datetime attribute, got %v", budget.PublishDate)
+	}
+	if budget.Description != "<p>The council approved the <em>budget</em> after a long debate.</p>" {
+		t.Errorf("Unexpected description %q", budget.Description)
+	}
+
+	market := byTitle["Winter market opens"]
+	if market.Link != server.URL+"/events/market" {
+		t.Errorf("Unexpected link %q", market.Link)
+	}
+	if !market.PublishDate.Equal(time.Date(2006, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the date parsed from the text, got %v", market.PublishDate)
+	}
+	if !strings.Contains(market.Description, `href="`+server.URL+`/news/market-map"`) {
+		t.Errorf("Expected links in the description to be resolved, got %q", market.Description)
+	}
+
+	roadWorks := byTitle["Road works on Main Street"]
+	if roadWorks.Link != "" || !roadWorks.PublishDate.IsZero() {
+		t.Errorf("Expected an item without link and date, got %+v", roadWorks)
+	}
+}
+
+func TestScrape_AlongsideFeeds(t *testing.T) {
+	// Without a title selector items are named after their link, so the
+	// road works item without one is skipped
+	source := ScrapedSource{Name: "Council", Item: "article.news-item", Date: "time, .date"}
+	items, err := Parse(context.Background(), []string{"./testdata/rss2.xml", "./testdata/scrape.html"},
+		WithScrapedSource("./testdata/scrape.html", source))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var titles []string
+	for _, item := range items {
+		titles = append(titles, item.Source+": "+item.Title)
+	}
+	want := []string{
+		"Council: Winter market opens",
+		"Archived Feed: First Post",
+		"Archived Feed: Second Post",
+		"Council: Budget approved for 2006",
+	}
+	if strings.Join(titles, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected scraped and feed items sorted together as %v, got %v", want, titles)
+	}
+	for _, item := range items {
+		if item.Title == "Budget approved for 2006" && item.Link != "budget-2006" {
+			t.Errorf("Expected relative links to be kept for local files, got %q", item.Link)
+		}
+	}
+}
+
+func TestScrape_NoMatches(t *testing.T) {
+	source := ScrapedSource{Item: ".does-not-exist"}
+	_, err := Parse(context.Background(), []string{"./testdata/scrape.html"}, WithScrapedSource("./testdata/scrape.html", source))
+	if err == nil || !strings.Contains(err.Error(), "matched no items") {
+		t.Errorf("Expected an error for a selector without matches, got: %v", err)
+	}
+}
+
+func TestScrape_InvalidSelector(t *testing.T) {
+	source := ScrapedSource{Item: "article", Title: "h2[["}
+	_, err := ParseReader(context.Background(), strings.NewReader("<article>x</article>"), "page", WithScrapedSource("page", source))
+	if err == nil || !strings.Contains(err.Error(), "invalid title selector") {
+		t.Errorf("Expected an invalid selector error, got: %v", err)
+	}
+}
+
+func TestScrape_DateLayout(t *testing.T) {
+	page := `<ul><li><a href="/a">A</a> <span>03/02/2024</span></li></ul>`
+	source := ScrapedSource{Item: "li", Date: "span", DateLayout: "02/01/2006"}
+	items, err := ParseReader(context.Background(), strings.NewReader(page), "page", WithScrapedSource("page", source))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(items) != 1 || !items[0].PublishDate.Equal(time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the date parsed with the layout, got %+v", items)
+	}
+}
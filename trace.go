@@ -0,0 +1,50 @@
+package rssreader
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Span names and attribute keys of the spans Parse starts. The keys follow
+// the OpenTelemetry semantic conventions where there is one.
+const (
+	SpanParse = "rssreader.Parse"
+	SpanFetch = "rssreader.fetch"
+
+	AttrURL        = "url.full"
+	AttrStatusCode = "http.response.status_code"
+	AttrFeedCount  = "rssreader.feed_count"
+	AttrItemCount  = "rssreader.item_count"
+	AttrBytes      = "rssreader.bytes"
+)
+
+// Tracer starts the spans of a tracing system, such as OpenTelemetry through
+// the otelrss package. Parse starts a SpanParse span around the whole call
+// and a SpanFetch span for each feed as its child, and ParseReader starts a
+// SpanFetch span. Feed URLs are passed through RedactURL.
+type Tracer interface {
+	// Start starts a span as a child of the span in ctx and returns the
+	// context carrying the new span.
+	Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// SetAttributes adds attributes to the span.
+	SetAttributes(attrs ...slog.Attr)
+	// End ends the span, marking it failed if err is not nil.
+	End(err error)
+}
+
+// noopTracer is the default Tracer, which records nothing.
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string, _ ...slog.Attr) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...slog.Attr) {}
+
+func (noopSpan) End(error) {}
@@ -0,0 +1,139 @@
+package rssreader
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memoryTracer records the spans it starts in memory.
+type memoryTracer struct {
+	mu    sync.Mutex
+	spans []*memorySpan
+}
+
+type memorySpan struct {
+	tracer *memoryTracer
+	name   string
+	parent *memorySpan
+	attrs  map[string]slog.Value
+	ended  bool
+	err    error
+}
+
+type spanKey struct{}
+
+func (m *memoryTracer) Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span) {
+	parent, _ := ctx.Value(spanKey{}).(*memorySpan)
+	span := &memorySpan{tracer: m, name: name, parent: parent, attrs: map[string]slog.Value{}}
+	span.SetAttributes(attrs...)
+	m.mu.Lock()
+	m.spans = append(m.spans, span)
+	m.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (s *memorySpan) SetAttributes(attrs ...slog.Attr) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *memorySpan) End(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.ended, s.err = true, err
+}
+
+func TestWithTracer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/feed" {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(readTestdata(t, "rss2.xml")))
+	}))
+	defer server.Close()
+
+	tracer := &memoryTracer{}
+	okURL := server.URL + "/feed?api_key=s3cret"
+	badURL := server.URL + "/bad"
+	_, err := Parse(context.Background(), []string{okURL, badURL}, WithTracer(tracer))
+	if err == nil {
+		t.Fatal("Expected an error for the bad feed")
+	}
+
+	spans := map[string]*memorySpan{}
+	for _, span := range tracer.spans {
+		if !span.ended {
+			t.Errorf("Expected span %s to be ended", span.name)
+		}
+		key := span.name
+		if url, ok := span.attrs[AttrURL]; ok {
+			key += " " + url.String()
+		}
+		spans[key] = span
+	}
+	if len(spans) != 3 {
+		t.Fatalf("Expected a parse span and 2 fetch spans, got %d", len(spans))
+	}
+
+	parse := spans[SpanParse]
+	if parse == nil || parse.parent != nil || parse.err == nil {
+		t.Fatalf("Expected a failed root parse span, got %+v", parse)
+	}
+	if parse.attrs[AttrFeedCount].Int64() != 2 || parse.attrs[AttrItemCount].Int64() != 2 {
+		t.Errorf("Expected 2 feeds and 2 items, got %v", parse.attrs)
+	}
+
+	ok := spans[SpanFetch+" "+RedactURL(okURL)]
+	if ok == nil || ok.parent != parse || ok.err != nil {
+		t.Fatalf("Expected a successful fetch span with a redacted URL under the parse span, got %+v", ok)
+	}
+	if ok.attrs[AttrStatusCode].Int64() != http.StatusOK || ok.attrs[AttrItemCount].Int64() != 2 || ok.attrs[AttrBytes].Int64() == 0 {
+		t.Errorf("Expected status 200, 2 items and the bytes read, got %v", ok.attrs)
+	}
+
+	bad := spans[SpanFetch+" "+badURL]
+	if bad == nil || bad.parent != parse || bad.err == nil {
+		t.Fatalf("Expected a failed fetch span under the parse span, got %+v", bad)
+	}
+	if bad.attrs[AttrStatusCode].Int64() != http.StatusBadGateway {
+		t.Errorf("Expected status 502, got %v", bad.attrs)
+	}
+	if _, ok := bad.attrs[AttrItemCount]; ok {
+		t.Errorf("Expected no item count for a failed fetch, got %v", bad.attrs)
+	}
+}
+
+func TestNoopTracer(t *testing.T) {
+	ctx := context.Background()
+	got, span := newOptions(nil).tracer.Start(ctx, SpanParse, slog.Int(AttrFeedCount, 1))
+	if got != ctx {
+		t.Error("Expected the no-op tracer to return the context unchanged")
+	}
+	span.SetAttributes(slog.Int(AttrItemCount, 0))
+	span.End(nil)
+}
+
+func TestParseReader_Traced(t *testing.T) {
+	tracer := &memoryTracer{}
+	items, err := ParseReader(context.Background(), strings.NewReader(readTestdata(t, "rss2.xml")), "archive.xml", WithTracer(tracer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracer.spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(tracer.spans))
+	}
+	span := tracer.spans[0]
+	if span.name != SpanFetch || span.attrs[AttrURL].String() != "archive.xml" || !span.ended ||
+		span.attrs[AttrItemCount].Int64() != int64(len(items)) {
+		t.Errorf("Expected an ended fetch span for archive.xml with %d items, got %+v", len(items), span)
+	}
+}
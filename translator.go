@@ -0,0 +1,286 @@
+package rssreader
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"html"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/atom"
+	jsonfeed "github.com/mmcdole/gofeed/json"
+	nethtml "golang.org/x/net/html"
+)
+
+// newFeedParser returns a gofeed parser whose Atom and JSON Feed translators
+// keep the format-specific details that the generic translation loses.
+func newFeedParser() *gofeed.Parser {
+	fp := gofeed.NewParser()
+	fp.AtomTranslator = &atomTranslator{}
+	fp.JSONTranslator = &jsonTranslator{}
+	return fp
+}
+
+// atomTranslator refines gofeed's Atom translation:
+//   - the item link is the alternate link for HTML, not just the first
+//     alternate link, so alternates for other media types are skipped
+//   - content of type "text" is escaped, so Content always holds HTML
+//
+// gofeed already resolves xml:base for links and HTML content.
+type atomTranslator struct {
+	gofeed.DefaultAtomTranslator
+}
+
+func (t *atomTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	result, err := t.DefaultAtomTranslator.Translate(feed)
+	if err != nil {
+		return nil, err
+	}
+	atomFeed, ok := feed.(*atom.Feed)
+	if !ok || len(atomFeed.Entries) != len(result.Items) {
+		return result, nil
+	}
+
+	for i, entry := range atomFeed.Entries {
+		item := result.Items[i]
+		if link := atomAlternateLink(entry.Links); link != "" {
+			item.Link = link
+		}
+		if entry.Content != nil && isAtomText(entry.Content.Type) {
+			item.Content = textToHTML(entry.Content.Value)
+		}
+	}
+	return result, nil
+}
+
+// atomAlternateLink returns the alternate link best suited as the item link,
+// preferring HTML over other media types.
+func atomAlternateLink(links []*atom.Link) string {
+	fallback := ""
+	for _, link := range links {
+		if link.Rel != "alternate" || link.Href == "" {
+			continue
+		}
+		mediaType := strings.ToLower(link.Type)
+		if mediaType == "" || mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+			return link.Href
+		}
+		if fallback == "" {
+			fallback = link.Href
+		}
+	}
+	return fallback
+}
+
+// isAtomText reports whether an Atom text construct of the given type holds
+// plain text. Atom defaults to text when the type is omitted.
+func isAtomText(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return contentType == "" || contentType == "text" || strings.HasPrefix(contentType, "text/plain")
+}
+
+// jsonTranslator refines gofeed's JSON Feed translation:
+//   - content_text is escaped when it is the only content, so Content always
+//     holds HTML
+//   - attachment lengths are taken from size_in_bytes, where gofeed uses the
+//     duration
+type jsonTranslator struct {
+	gofeed.DefaultJSONTranslator
+}
+
+func (t *jsonTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	result, err := t.DefaultJSONTranslator.Translate(feed)
+	if err != nil {
+		return nil, err
+	}
+	jsonFeed, ok := feed.(*jsonfeed.Feed)
+	if !ok || len(jsonFeed.Items) != len(result.Items) {
+		return result, nil
+	}
+
+	for i, entry := range jsonFeed.Items {
+		item := result.Items[i]
+		if entry.ContentHTML == "" && entry.ContentText != "" {
+			item.Content = textToHTML(entry.ContentText)
+		}
+		if entry.Attachments == nil {
+			continue
+		}
+		item.Enclosures = nil
+		for _, attachment := range *entry.Attachments {
+			enclosure := &gofeed.Enclosure{URL: attachment.URL, Type: attachment.MimeType}
+			if attachment.SizeInBytes > 0 {
+				enclosure.Length = strconv.FormatInt(attachment.SizeInBytes, 10)
+			}
+			item.Enclosures = append(item.Enclosures, enclosure)
+		}
+	}
+	return result, nil
+}
+
+// textToHTML escapes plain text for use as HTML, keeping line breaks.
+func textToHTML(text string) string {
+	escaped := html.EscapeString(strings.TrimSpace(text))
+	return strings.ReplaceAll(escaped, "\n", "<br>\n")
+}
+
+// xmlNamespace is the namespace of the predefined xml: prefix.
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// itemBases returns the base URL for resolving relative URLs of every item
+// in feed. For Atom feeds that is the xml:base in scope of each entry, for
+// other feeds the document URL. Entries are nil when no base is known.
+func itemBases(feed *gofeed.Feed, data []byte, sourceURL string) []*url.URL {
+	var docBase *url.URL
+	if u, err := url.Parse(sourceURL); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		docBase = u
+	}
+
+	bases := make([]*url.URL, len(feed.Items))
+	for i := range bases {
+		bases[i] = docBase
+	}
+	if feed.FeedType != "atom" {
+		return bases
+	}
+
+	entryBases := atomEntryBases(data, docBase)
+	for i := range bases {
+		if i < len(entryBases) {
+			bases[i] = entryBases[i]
+		}
+	}
+	return bases
+}
+
+// atomEntryBases scans an Atom document and returns the xml:base in scope
+// of each entry, in document order.
+func atomEntryBases(data []byte, docBase *url.URL) []*url.URL {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+
+	var bases []*url.URL
+	stack := []*url.URL{docBase}
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return bases
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			base := stack[len(stack)-1]
+			for _, attr := range tok.Attr {
+				if attr.Name.Local != "base" || (attr.Name.Space != xmlNamespace && attr.Name.Space != "xml") {
+					continue
+				}
+				ref, err := url.Parse(strings.TrimSpace(attr.Value))
+				if err != nil {
+					break
+				}
+				if base != nil {
+					ref = base.ResolveReference(ref)
+				}
+				base = ref
+			}
+			stack = append(stack, base)
+			// Entries are the children of the root feed element
+			if len(stack) == 3 && tok.Name.Local == "entry" {
+				bases = append(bases, base)
+			}
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+}
+
+// resolveItemURLs makes the links, enclosure and media URLs and the URLs
+// inside the HTML of each item absolute, using the base at the same index.
+func resolveItemURLs(items []RssItem, bases []*url.URL) {
+	for i := range items {
+		if i >= len(bases) || bases[i] == nil || !bases[i].IsAbs() {
+			continue
+		}
+		base := bases[i]
+		item := &items[i]
+		item.Link = resolveURL(base, item.Link)
+		for j := range item.Enclosures {
+			item.Enclosures[j].URL = resolveURL(base, item.Enclosures[j].URL)
+		}
+		if item.ITunes != nil {
+			item.ITunes.Image = resolveURL(base, item.ITunes.Image)
+		}
+		if item.Media != nil {
+			for j := range item.Media.Contents {
+				item.Media.Contents[j].URL = resolveURL(base, item.Media.Contents[j].URL)
+			}
+			for j := range item.Media.Thumbnails {
+				item.Media.Thumbnails[j].URL = resolveURL(base, item.Media.Thumbnails[j].URL)
+			}
+		}
+		item.Content = resolveHTML(base, item.Content)
+		item.Description = resolveHTML(base, item.Description)
+	}
+}
+
+// resolveURL resolves ref against base, leaving it unchanged if it is empty
+// or not a valid URL.
+func resolveURL(base *url.URL, ref string) string {
+	if ref == "" {
+		return ref
+	}
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil || u.IsAbs() {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+// resolveHTML rewrites relative href and src attributes in an HTML fragment.
+// Everything else is copied through unchanged.
+func resolveHTML(base *url.URL, fragment string) string {
+	if !strings.Contains(fragment, "<") {
+		return fragment
+	}
+
+	var b strings.Builder
+	tokenizer := nethtml.NewTokenizer(strings.NewReader(fragment))
+	for {
+		tt := tokenizer.Next()
+		if tt == nethtml.ErrorToken {
+			if errors.Is(tokenizer.Err(), io.EOF) {
+				return b.String()
+			}
+			return fragment
+		}
+		// Token lowercases names in the tokenizer's buffer, so copy the raw bytes first
+		raw := append([]byte(nil), tokenizer.Raw()...)
+		if tt != nethtml.StartTagToken && tt != nethtml.SelfClosingTagToken {
+			b.Write(raw)
+			continue
+		}
+
+		token := tokenizer.Token()
+		changed := false
+		for j, attr := range token.Attr {
+			if attr.Namespace != "" || (attr.Key != "href" && attr.Key != "src") {
+				continue
+			}
+			if resolved := resolveURL(base, attr.Val); resolved != attr.Val {
+				token.Attr[j].Val = resolved
+				changed = true
+			}
+		}
+		if changed {
+			b.WriteString(token.String())
+		} else {
+			b.Write(raw)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package rssreader
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// parseFixture parses a feed from testdata and returns its items by title.
+func parseFixture(t *testing.T, name string) map[string]RssItem {
+	t.Helper()
+	items, err := Parse(context.Background(), []string{"./testdata/" + name})
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", name, err)
+	}
+	byTitle := make(map[string]RssItem, len(items))
+	for _, item := range items {
+		byTitle[item.Title] = item
+	}
+	return byTitle
+}
+
+func TestAtom_LinksWithRel(t *testing.T) {
+	item := parseFixture(t, "atom.xml")["Links with rel"]
+
+	if item.Link != "http://atom.example.com/blog/entries/1" {
+		t.Errorf("Expected the HTML alternate link resolved against xml:base, got %q", item.Link)
+	}
+	if len(item.Enclosures) != 1 {
+		t.Fatalf("Expected 1 enclosure, got %d", len(item.Enclosures))
+	}
+	want := Enclosure{URL: "http://atom.example.com/blog/media/1.mp3", Type: "audio/mpeg", Length: 12345}
+	if item.Enclosures[0] != want {
+		t.Errorf("Expected enclosure %+v, got %+v", want, item.Enclosures[0])
+	}
+}
+
+func TestAtom_SummaryVersusContent(t *testing.T) {
+	items := parseFixture(t, "atom.xml")
+
+	item := items["Links with rel"]
+	if item.Description != "Short summary" {
+		t.Errorf("Expected summary as description, got %q", item.Description)
+	}
+	if item.Content != `<p>Full <a href="http://atom.example.com/blog/more">content</a></p>` {
+		t.Errorf("Expected HTML content with resolved links, got %q", item.Content)
+	}
+	if !item.PublishDate.Equal(time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected published date, got %v", item.PublishDate)
+	}
+	if !item.Updated.Equal(time.Date(2025, 3, 2, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected updated date, got %v", item.Updated)
+	}
+
+	text := items["Entry base and text content"]
+	if text.Content != "1 &lt; 2 &amp; plain<br>\nsecond line" {
+		t.Errorf("Expected text content to be escaped as HTML, got %q", text.Content)
+	}
+	if text.Description != text.Content {
+		t.Errorf("Expected description to fall back to content, got %q", text.Description)
+	}
+	if !text.PublishDate.Equal(text.Updated) || text.Updated.IsZero() {
+		t.Errorf("Expected publish date to fall back to updated, got %v and %v", text.PublishDate, text.Updated)
+	}
+
+	xhtml := items["XHTML content without summary"]
+	if xhtml.Content != `<p>XHTML <img src="http://atom.example.com/blog/img/3.png"/></p>` {
+		t.Errorf("Expected unwrapped XHTML content, got %q", xhtml.Content)
+	}
+}
+
+func TestAtom_XMLBase(t *testing.T) {
+	items := parseFixture(t, "atom.xml")
+
+	if link := items["Entry base and text content"].Link; link != "http://other.example.com/posts/2" {
+		t.Errorf("Expected the entry's own xml:base to apply, got %q", link)
+	}
+	if link := items["XHTML content without summary"].Link; link != "http://atom.example.com/blog/posts/3" {
+		t.Errorf("Expected the feed's xml:base to apply, got %q", link)
+	}
+}
+
+func TestJSONFeed_11(t *testing.T) {
+	items := parseFixture(t, "jsonfeed-1.1.json")
+
+	item := items["HTML and text"]
+	if item.Source != "JSON Feed 1.1" || item.Link != "https://json.example.com/1" {
+		t.Errorf("Unexpected source or link: %+v", item)
+	}
+	if item.Description != "Summary one" {
+		t.Errorf("Expected summary as description, got %q", item.Description)
+	}
+	if item.Content != "<p>Rich <em>content</em></p>" {
+		t.Errorf("Expected content_html to win over content_text, got %q", item.Content)
+	}
+	if !item.PublishDate.Equal(time.Date(2025, 4, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected date_published, got %v", item.PublishDate)
+	}
+	if !item.Updated.Equal(time.Date(2025, 4, 2, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("Expected date_modified, got %v", item.Updated)
+	}
+
+	wantEnclosures := []Enclosure{
+		{URL: "https://json.example.com/1.mp3", Type: "audio/mpeg", Length: 2048},
+		{URL: "https://json.example.com/1.m4a", Type: "audio/mp4"},
+	}
+	if len(item.Enclosures) != len(wantEnclosures) {
+		t.Fatalf("Expected %d attachments, got %d", len(wantEnclosures), len(item.Enclosures))
+	}
+	for i, want := range wantEnclosures {
+		if item.Enclosures[i] != want {
+			t.Errorf("Attachment %d: expected %+v, got %+v", i, want, item.Enclosures[i])
+		}
+	}
+
+	text := items["Text only"]
+	if text.Content != "Tom &amp; Jerry &lt;3<br>\nNew line" {
+		t.Errorf("Expected content_text escaped as HTML, got %q", text.Content)
+	}
+	if text.Description != text.Content {
+		t.Errorf("Expected description to fall back to content, got %q", text.Description)
+	}
+}
+
+func TestJSONFeed_10(t *testing.T) {
+	item := parseFixture(t, "jsonfeed-1.0.json")["Modified only"]
+
+	if item.Content != "<p>Only HTML</p>" || item.Description != "<p>Only HTML</p>" {
+		t.Errorf("Expected content_html as content and description, got %+v", item)
+	}
+	want := time.Date(2020, 5, 6, 5, 8, 9, 0, time.UTC)
+	if !item.Updated.Equal(want) || !item.PublishDate.Equal(want) {
+		t.Errorf("Expected date_modified as updated and publish date, got %v and %v", item.Updated, item.PublishDate)
+	}
+}
+
+func TestParse_ResolvesRelativeURLsAgainstFeedURL(t *testing.T) {
+	server := testServer(`<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Relative</title>
+    <item>
+      <title>Relative link</title>
+      <link>/posts/1</link>
+      <description>&lt;a href="../about"&gt;About&lt;/a&gt;</description>
+      <enclosure url="media/1.mp3" type="audio/mpeg" length="99"/>
+    </item>
+  </channel>
+</rss>`, "application/rss+xml")
+	defer server.Close()
+
+	items, err := Parse(context.Background(), []string{server.URL + "/blog/feed.xml"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	item := items[0]
+	if item.Link != server.URL+"/posts/1" {
+		t.Errorf("Expected link resolved against the feed URL, got %q", item.Link)
+	}
+	if item.Description != `<a href="`+server.URL+`/about">About</a>` {
+		t.Errorf("Expected description links resolved, got %q", item.Description)
+	}
+	if len(item.Enclosures) != 1 || item.Enclosures[0].URL != server.URL+"/blog/media/1.mp3" || item.Enclosures[0].Length != 99 {
+		t.Errorf("Unexpected enclosures: %+v", item.Enclosures)
+	}
+}
+
+func TestResolveHTML(t *testing.T) {
+	base, _ := url.Parse("https://example.com/a/")
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"plain text", "plain text"},
+		{`<P CLASS="x">Keep <B>case</B></P>`, `<P CLASS="x">Keep <B>case</B></P>`},
+		{`<a href="b">b</a> <img src="/c.png" alt="c">`, `<a href="https://example.com/a/b">b</a> <img src="https://example.com/c.png" alt="c">`},
+		{`<a href="https://other.test/">abs</a>`, `<a href="https://other.test/">abs</a>`},
+	}
+	for _, tt := range tests {
+		if got := resolveHTML(base, tt.input); got != tt.want {
+			t.Errorf("resolveHTML(%q) = %q, expected %q", tt.input, got, tt.want)
+		}
+	}
+}
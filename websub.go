@@ -0,0 +1,515 @@
+package rssreader
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" // #nosec G505 -- WebSub hubs may sign with sha1, which is still verified as HMAC
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// ErrNoHub is returned when a feed does not advertise a WebSub hub.
+var ErrNoHub = errors.New("feed does not advertise a WebSub hub")
+
+// maxPushSize limits the size of content a hub may push to the subscriber.
+const maxPushSize = 10 << 20
+
+// HubLinks are the WebSub links advertised by a feed.
+type HubLinks struct {
+	Hubs []string // hubs the feed publishes to
+	Self string   // canonical topic URL, empty if the feed doesn't declare one
+}
+
+// DiscoverHub fetches the feed at feedURL and returns its WebSub links. Link
+// headers take precedence over <link rel="hub"> and <atom:link rel="hub">
+// elements in the document, as the WebSub specification requires.
+func DiscoverHub(ctx context.Context, feedURL string) (HubLinks, error) {
+	return discoverHub(ctx, http.DefaultClient, feedURL, FeedAuth{})
+}
+
+// discoverHub fetches the feed at feedURL with client and the credentials
+// of auth, if any.
+func discoverHub(ctx context.Context, client *http.Client, feedURL string, auth FeedAuth) (HubLinks, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return HubLinks{}, err
+	}
+	auth.apply(req)
+	resp, err := auth.client(client).Do(req)
+	if err != nil {
+		return HubLinks{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return HubLinks{}, gofeed.HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	links := headerHubLinks(resp.Header)
+	if len(links.Hubs) == 0 {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxPushSize))
+		if err != nil {
+			return HubLinks{}, err
+		}
+		links = documentHubLinks(body)
+	}
+	if len(links.Hubs) == 0 {
+		return HubLinks{}, ErrNoHub
+	}
+	return links, nil
+}
+
+// headerHubLinks extracts hub and self links from HTTP Link headers.
+func headerHubLinks(header http.Header) HubLinks {
+	var links HubLinks
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			target, params, ok := strings.Cut(link, ";")
+			if !ok {
+				continue
+			}
+			target = strings.Trim(strings.TrimSpace(target), "<>")
+			for _, param := range strings.Split(params, ";") {
+				name, val, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(name, "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(val, `"`)) {
+					links.add(rel, target)
+				}
+			}
+		}
+	}
+	return links
+}
+
+// documentHubLinks extracts hub and self links from <link> elements of an
+// RSS or Atom document.
+func documentHubLinks(body []byte) HubLinks {
+	var links HubLinks
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) { return input, nil }
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return links
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "link" {
+			continue
+		}
+		var rel, href string
+		for _, attr := range start.Attr {
+			switch attr.Name.Local {
+			case "rel":
+				rel = attr.Value
+			case "href":
+				href = attr.Value
+			}
+		}
+		for _, r := range strings.Fields(rel) {
+			links.add(r, href)
+		}
+	}
+}
+
+func (l *HubLinks) add(rel, href string) {
+	if href == "" {
+		return
+	}
+	switch strings.ToLower(rel) {
+	case "hub":
+		l.Hubs = append(l.Hubs, href)
+	case "self":
+		if l.Self == "" {
+			l.Self = href
+		}
+	}
+}
+
+// Subscriber receives feed updates pushed by WebSub hubs. It is an
+// http.Handler that must be reachable by the hubs at CallbackURL; pushed
+// content is mapped to RssItems the same way Parse maps fetched feeds.
+type Subscriber struct {
+	CallbackURL string        // public URL the handler is served at
+	Secret      string        // if set, pushes from https hubs must carry a valid X-Hub-Signature
+	Lease       time.Duration // requested lease, the hub's default if 0
+	Client      *http.Client  // defaults to http.DefaultClient
+
+	// Options are applied to pushed content as Parse applies them to
+	// fetched feeds, such as limits, lenient parsing and URL redaction.
+	// Credentials set with WithFeedAuth are sent when discovering hubs.
+	Options []Option
+
+	// OnItems is called with the items of every accepted push. The push is
+	// acknowledged before, so OnItems is called from a separate goroutine,
+	// one push at a time in the order they arrived.
+	OnItems func(feedURL string, items []RssItem)
+	// QueueSize is the number of pushes that may wait for OnItems. Pushes
+	// beyond it are answered with 503 Service Unavailable so that the hub
+	// delivers them again later. 0 means DefaultPushQueueSize.
+	QueueSize int
+
+	mu   sync.Mutex
+	subs map[string]*subscription // keyed by callback ID
+
+	queueOnce sync.Once
+	queue     chan pushedItems
+}
+
+// DefaultPushQueueSize is the QueueSize of a Subscriber if it is 0.
+const DefaultPushQueueSize = 64
+
+// pushedItems are the items of a push waiting for OnItems.
+type pushedItems struct {
+	feedURL string
+	items   []RssItem
+}
+
+// subscription tracks a single topic at a hub.
+type subscription struct {
+	feedURL string
+	topic   string
+	hub     string
+	pending string // mode awaiting verification, empty once verified
+	expires time.Time
+	// signed is whether the hub was given the secret and must sign its
+	// pushes, which is only done for https hubs
+	signed bool
+}
+
+// Subscribe discovers the hub of the feed at feedURL and asks it to push
+// updates to the callback. The hub confirms asynchronously by calling the
+// handler; use Subscribed to check the result.
+func (s *Subscriber) Subscribe(ctx context.Context, feedURL string) error {
+	links, err := discoverHub(ctx, s.client(), feedURL, newOptions(s.Options).auth[feedURL])
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", RedactURL(feedURL), redactError(err, nil))
+	}
+	topic := links.Self
+	if topic == "" {
+		topic = feedURL
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return err
+	}
+	hub := links.Hubs[0]
+	sub := &subscription{feedURL: feedURL, topic: topic, hub: hub, pending: "subscribe"}
+	// The secret would be readable on the way to an http hub, so such hubs
+	// are not asked to sign their pushes
+	sub.signed = s.Secret != "" && strings.HasPrefix(strings.ToLower(hub), "https://")
+
+	s.mu.Lock()
+	if s.subs == nil {
+		s.subs = map[string]*subscription{}
+	}
+	// A verified subscription keeps receiving pushes until the new one is
+	// verified, but an unverified attempt is superseded
+	for existing, old := range s.subs {
+		if old.feedURL == feedURL && old.pending == "subscribe" {
+			delete(s.subs, existing)
+		}
+	}
+	s.subs[id] = sub
+	s.mu.Unlock()
+
+	if err := s.request(ctx, sub, id, "subscribe"); err != nil {
+		s.mu.Lock()
+		delete(s.subs, id)
+		s.mu.Unlock()
+		return fmt.Errorf("failed to subscribe to %s: %w", RedactURL(feedURL), redactError(err, nil))
+	}
+	return nil
+}
+
+// Unsubscribe asks the hub to stop pushing updates for feedURL.
+func (s *Subscriber) Unsubscribe(ctx context.Context, feedURL string) error {
+	s.mu.Lock()
+	var id string
+	var sub *subscription
+	for key, candidate := range s.subs {
+		if candidate.feedURL == feedURL && (sub == nil || sub.pending == "subscribe") {
+			id, sub = key, candidate
+		}
+	}
+	if sub != nil {
+		sub.pending = "unsubscribe"
+	}
+	s.mu.Unlock()
+
+	if sub == nil {
+		return fmt.Errorf("not subscribed to %s", RedactURL(feedURL))
+	}
+	return s.request(ctx, sub, id, "unsubscribe")
+}
+
+// Subscribed reports whether the hub verified the subscription to feedURL
+// and its lease has not expired.
+func (s *Subscriber) Subscribed(feedURL string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sub := range s.subs {
+		if sub.feedURL == feedURL && sub.pending != "subscribe" && time.Now().Before(sub.expires) {
+			return true
+		}
+	}
+	return false
+}
+
+// Renew resubscribes to every feed whose lease expires within the given
+// duration, or whose verification never arrived.
+func (s *Subscriber) Renew(ctx context.Context, within time.Duration) error {
+	s.mu.Lock()
+	var feeds []string
+	deadline := time.Now().Add(within)
+	for _, sub := range s.subs {
+		if (sub.pending == "subscribe" || (sub.pending == "" && sub.expires.Before(deadline))) && !slices.Contains(feeds, sub.feedURL) {
+			feeds = append(feeds, sub.feedURL)
+		}
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	for _, feedURL := range feeds {
+		if err := s.Subscribe(ctx, feedURL); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// request sends a subscribe or unsubscribe request for sub to its hub.
+func (s *Subscriber) request(ctx context.Context, sub *subscription, id, mode string) error {
+	callback, err := url.JoinPath(s.CallbackURL, id)
+	if err != nil {
+		return fmt.Errorf("invalid callback URL: %w", err)
+	}
+	form := url.Values{
+		"hub.callback": {callback},
+		"hub.mode":     {mode},
+		"hub.topic":    {sub.topic},
+	}
+	if s.Lease > 0 {
+		form.Set("hub.lease_seconds", strconv.Itoa(int(s.Lease.Seconds())))
+	}
+	if sub.signed {
+		form.Set("hub.secret", s.Secret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("hub %s rejected %s: %s: %s", RedactURL(sub.hub), mode, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (s *Subscriber) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+// ServeHTTP handles hub verification requests (GET) and content pushes (POST).
+func (s *Subscriber) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+	switch r.Method {
+	case http.MethodGet:
+		s.verify(w, r, id)
+	case http.MethodPost:
+		s.receive(w, r, id)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// verify answers the hub's intent verification by echoing the challenge,
+// but only for requests this subscriber actually made.
+func (s *Subscriber) verify(w http.ResponseWriter, r *http.Request, id string) {
+	q := r.URL.Query()
+	mode := q.Get("hub.mode")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.subs[id]
+	if !ok || q.Get("hub.topic") != sub.topic {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch mode {
+	case "denied":
+		delete(s.subs, id)
+		w.WriteHeader(http.StatusOK)
+		return
+	case sub.pending:
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if mode == "unsubscribe" {
+		delete(s.subs, id)
+	} else {
+		lease, err := strconv.Atoi(q.Get("hub.lease_seconds"))
+		if err != nil || lease <= 0 {
+			// Without a lease the subscription lasts until the hub says otherwise
+			lease = int((10 * 365 * 24 * time.Hour).Seconds())
+		}
+		sub.pending = ""
+		sub.expires = time.Now().Add(time.Duration(lease) * time.Second)
+		// The verified subscription replaces any earlier one
+		for existing, old := range s.subs {
+			if existing != id && old.feedURL == sub.feedURL {
+				delete(s.subs, existing)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = io.WriteString(w, q.Get("hub.challenge"))
+}
+
+// receive parses pushed content and queues its items for OnItems. Pushes
+// with a missing or invalid signature are acknowledged but ignored, as the
+// WebSub specification requires.
+func (s *Subscriber) receive(w http.ResponseWriter, r *http.Request, id string) {
+	s.mu.Lock()
+	sub, ok := s.subs[id]
+	var feedURL string
+	var signed bool
+	if ok {
+		feedURL, signed = sub.feedURL, sub.signed
+	}
+	s.mu.Unlock()
+	if !ok {
+		// Tell the hub to stop delivering to an unknown callback
+		http.Error(w, "unknown subscription", http.StatusGone)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPushSize+1))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxPushSize {
+		http.Error(w, "content too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if signed && !validSignature(s.Secret, r.Header.Get("X-Hub-Signature"), body) {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	items, err := parseDocument(r.Context(), bytes.NewReader(body), feedURL, r.Header.Get("Content-Type"), newOptions(s.Options))
+	var tooLarge *FeedTooLargeError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to parse content: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if s.OnItems != nil && !s.enqueue(pushedItems{feedURL: feedURL, items: items}) {
+		http.Error(w, "too many pushes", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// enqueue queues pushed items for OnItems, starting the goroutine calling it
+// on the first push. It reports false if the queue is full.
+func (s *Subscriber) enqueue(push pushedItems) bool {
+	s.queueOnce.Do(func() {
+		size := s.QueueSize
+		if size <= 0 {
+			size = DefaultPushQueueSize
+		}
+		s.queue = make(chan pushedItems, size)
+		go func() {
+			for push := range s.queue {
+				s.OnItems(push.feedURL, push.items)
+			}
+		}()
+	})
+	select {
+	case s.queue <- push:
+		return true
+	default:
+		return false
+	}
+}
+
+// validSignature checks an X-Hub-Signature header of the form "method=hex".
+func validSignature(secret, header string, body []byte) bool {
+	method, signature, ok := strings.Cut(header, "=")
+	if !ok {
+		return false
+	}
+	var newHash func() hash.Hash
+	switch method {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+	want, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
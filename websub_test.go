@@ -0,0 +1,466 @@
+package rssreader
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeHub is a minimal WebSub hub that verifies subscriptions and can push content.
+type fakeHub struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu       sync.Mutex
+	callback string
+	topic    string
+	secret   string
+	reject   bool        // answer subscription requests with 503
+	verified chan string // receives the challenge response of each verification
+}
+
+func newFakeHub(t *testing.T) *fakeHub {
+	hub := &fakeHub{t: t, verified: make(chan string, 4)}
+	hub.server = httptest.NewServer(http.HandlerFunc(hub.handleSubscribe))
+	t.Cleanup(hub.server.Close)
+	return hub
+}
+
+// newFakeTLSHub returns a fakeHub served over https, which is given the
+// secret of a subscriber using the client of the hub's server.
+func newFakeTLSHub(t *testing.T) *fakeHub {
+	hub := &fakeHub{t: t, verified: make(chan string, 4)}
+	hub.server = httptest.NewTLSServer(http.HandlerFunc(hub.handleSubscribe))
+	t.Cleanup(hub.server.Close)
+	return hub
+}
+
+func (h *fakeHub) handleSubscribe(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.mu.Lock()
+	if h.reject {
+		h.mu.Unlock()
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+		return
+	}
+	h.callback = r.Form.Get("hub.callback")
+	h.topic = r.Form.Get("hub.topic")
+	h.secret = r.Form.Get("hub.secret")
+	h.mu.Unlock()
+	w.WriteHeader(http.StatusAccepted)
+
+	// Verify the intent asynchronously, like a real hub
+	go func(mode string) {
+		h.verified <- h.sendVerification(mode, h.topic, "challenge-"+mode)
+	}(r.Form.Get("hub.mode"))
+}
+
+// sendVerification calls the subscriber's callback and returns its response body.
+func (h *fakeHub) sendVerification(mode, topic, challenge string) string {
+	h.mu.Lock()
+	callback := h.callback
+	h.mu.Unlock()
+
+	q := url.Values{
+		"hub.mode":          {mode},
+		"hub.topic":         {topic},
+		"hub.challenge":     {challenge},
+		"hub.lease_seconds": {"3600"},
+	}
+	resp, err := http.Get(callback + "?" + q.Encode())
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Sprintf("status %d", resp.StatusCode)
+	}
+	return string(body)
+}
+
+// push delivers content to the subscriber, signed with signingSecret unless it is empty.
+func (h *fakeHub) push(content, signingSecret string) int {
+	h.mu.Lock()
+	callback := h.callback
+	h.mu.Unlock()
+
+	req, _ := http.NewRequest(http.MethodPost, callback, strings.NewReader(content))
+	req.Header.Set("Content-Type", "application/atom+xml")
+	if signingSecret != "" {
+		mac := hmac.New(sha256.New, []byte(signingSecret))
+		mac.Write([]byte(content))
+		req.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		h.t.Fatalf("Push failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
+
+func websubFeed(hubURL string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Pushed Blog</title>
+  <link rel="hub" href="` + hubURL + `"/>
+  <link rel="self" href="http://topic.test/feed"/>
+  <entry>
+    <title>Pushed entry</title>
+    <link href="http://topic.test/entry"/>
+    <updated>2025-01-02T15:04:05Z</updated>
+    <summary>Fresh from the hub</summary>
+  </entry>
+</feed>`
+}
+
+// newTestSubscriber returns a subscriber served by an httptest server and a
+// channel receiving its pushed items.
+func newTestSubscriber(t *testing.T, secret string) (*Subscriber, chan []RssItem) {
+	pushed := make(chan []RssItem, 4)
+	sub := &Subscriber{
+		Secret: secret,
+		OnItems: func(_ string, items []RssItem) {
+			pushed <- items
+		},
+	}
+	server := httptest.NewServer(sub)
+	t.Cleanup(server.Close)
+	sub.CallbackURL = server.URL + "/websub"
+	return sub, pushed
+}
+
+func waitVerified(t *testing.T, hub *fakeHub) string {
+	t.Helper()
+	select {
+	case response := <-hub.verified:
+		return response
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for verification")
+		return ""
+	}
+}
+
+func TestSubscriber_SubscribeVerifyAndPush(t *testing.T) {
+	hub := newFakeTLSHub(t)
+	feedServer := testServer(websubFeed(hub.server.URL), "application/atom+xml")
+	defer feedServer.Close()
+
+	sub, pushed := newTestSubscriber(t, "hush")
+	sub.Client = hub.server.Client()
+	if err := sub.Subscribe(context.Background(), feedServer.URL); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if response := waitVerified(t, hub); response != "challenge-subscribe" {
+		t.Fatalf("Expected the challenge to be echoed, got %q", response)
+	}
+	if !sub.Subscribed(feedServer.URL) {
+		t.Error("Expected subscription to be active after verification")
+	}
+	if hub.topic != "http://topic.test/feed" || hub.secret != "hush" {
+		t.Errorf("Expected self link as topic and the secret, got topic %q secret %q", hub.topic, hub.secret)
+	}
+
+	if status := hub.push(websubFeed(hub.server.URL), "hush"); status/100 != 2 {
+		t.Fatalf("Expected push to be accepted, got %d", status)
+	}
+	items := <-pushed
+	if len(items) != 1 {
+		t.Fatalf("Expected 1 pushed item, got %d", len(items))
+	}
+	item := items[0]
+	if item.Title != "Pushed entry" || item.Source != "Pushed Blog" || item.Link != "http://topic.test/entry" {
+		t.Errorf("Unexpected item: %+v", item)
+	}
+	if item.RssURL != feedServer.URL || item.SourceURL != feedServer.URL {
+		t.Errorf("Expected items attributed to the subscribed feed, got %+v", item)
+	}
+	if !item.PublishDate.Equal(time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Errorf("Unexpected publish date %v", item.PublishDate)
+	}
+}
+
+func TestSubscriber_IgnoresInvalidSignatures(t *testing.T) {
+	hub := newFakeTLSHub(t)
+	feedServer := testServer(websubFeed(hub.server.URL), "application/atom+xml")
+	defer feedServer.Close()
+
+	sub, pushed := newTestSubscriber(t, "hush")
+	sub.Client = hub.server.Client()
+	if err := sub.Subscribe(context.Background(), feedServer.URL); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	waitVerified(t, hub)
+
+	for _, secret := range []string{"", "wrong"} {
+		if status := hub.push(websubFeed(hub.server.URL), secret); status/100 != 2 {
+			t.Errorf("Expected push to be acknowledged, got %d", status)
+		}
+	}
+	select {
+	case items := <-pushed:
+		t.Errorf("Expected unsigned pushes to be ignored, got %d items", len(items))
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSubscriber_NoSecretForHTTPHubs(t *testing.T) {
+	hub := newFakeHub(t)
+	feedServer := testServer(websubFeed(hub.server.URL), "application/atom+xml")
+	defer feedServer.Close()
+
+	sub, pushed := newTestSubscriber(t, "hush")
+	if err := sub.Subscribe(context.Background(), feedServer.URL); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	waitVerified(t, hub)
+	if hub.secret != "" {
+		t.Errorf("Expected no secret to be sent to an http hub, got %q", hub.secret)
+	}
+
+	// The hub cannot sign without the secret
+	if status := hub.push(websubFeed(hub.server.URL), ""); status/100 != 2 {
+		t.Fatalf("Expected push to be accepted, got %d", status)
+	}
+	if items := <-pushed; len(items) != 1 {
+		t.Errorf("Expected 1 pushed item, got %d", len(items))
+	}
+}
+
+func TestSubscriber_AcknowledgesBeforeOnItems(t *testing.T) {
+	hub := newFakeHub(t)
+	feedServer := testServer(websubFeed(hub.server.URL), "application/atom+xml")
+	defer feedServer.Close()
+
+	release := make(chan struct{})
+	delivered := make(chan []RssItem, 4)
+	sub, _ := newTestSubscriber(t, "")
+	sub.QueueSize = 1
+	sub.OnItems = func(_ string, items []RssItem) {
+		<-release
+		delivered <- items
+	}
+	if err := sub.Subscribe(context.Background(), feedServer.URL); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	waitVerified(t, hub)
+
+	// The first push is taken by the blocked OnItems and the second one
+	// waits in the queue, so the third one finds the queue full
+	var statuses []int
+	for range 3 {
+		statuses = append(statuses, hub.push(websubFeed(hub.server.URL), ""))
+		time.Sleep(20 * time.Millisecond)
+	}
+	if statuses[0] != http.StatusAccepted || statuses[1] != http.StatusAccepted || statuses[2] != http.StatusServiceUnavailable {
+		t.Errorf("Expected 202, 202 and 503 while OnItems is blocked, got %v", statuses)
+	}
+
+	close(release)
+	for range 2 {
+		select {
+		case <-delivered:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the queued pushes")
+		}
+	}
+}
+
+func TestSubscriber_RejectsUnexpectedVerification(t *testing.T) {
+	hub := newFakeHub(t)
+	feedServer := testServer(websubFeed(hub.server.URL), "application/atom+xml")
+	defer feedServer.Close()
+
+	sub, _ := newTestSubscriber(t, "")
+	if err := sub.Subscribe(context.Background(), feedServer.URL); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	waitVerified(t, hub)
+
+	if response := hub.sendVerification("subscribe", "http://other.test/feed", "x"); response != "status 404" {
+		t.Errorf("Expected 404 for a foreign topic, got %q", response)
+	}
+	if response := hub.sendVerification("unsubscribe", "http://topic.test/feed", "x"); response != "status 404" {
+		t.Errorf("Expected 404 for an unsubscribe that was never requested, got %q", response)
+	}
+}
+
+func TestSubscriber_Unsubscribe(t *testing.T) {
+	hub := newFakeHub(t)
+	feedServer := testServer(websubFeed(hub.server.URL), "application/atom+xml")
+	defer feedServer.Close()
+
+	sub, _ := newTestSubscriber(t, "")
+	if err := sub.Subscribe(context.Background(), feedServer.URL); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	waitVerified(t, hub)
+
+	if err := sub.Unsubscribe(context.Background(), feedServer.URL); err != nil {
+		t.Fatalf("Unsubscribe failed: %v", err)
+	}
+	if response := waitVerified(t, hub); response != "challenge-unsubscribe" {
+		t.Fatalf("Expected the unsubscribe challenge to be echoed, got %q", response)
+	}
+	if sub.Subscribed(feedServer.URL) {
+		t.Error("Expected no subscription after unsubscribing")
+	}
+	if status := hub.push(websubFeed(hub.server.URL), ""); status != http.StatusGone {
+		t.Errorf("Expected 410 for pushes after unsubscribing, got %d", status)
+	}
+}
+
+func TestSubscriber_FeedWithoutHub(t *testing.T) {
+	feedServer := testServer(`<?xml version="1.0"?><rss version="2.0"><channel><title>No hub</title></channel></rss>`, "application/rss+xml")
+	defer feedServer.Close()
+
+	sub, _ := newTestSubscriber(t, "")
+	err := sub.Subscribe(context.Background(), feedServer.URL)
+	if !errors.Is(err, ErrNoHub) {
+		t.Errorf("Expected ErrNoHub, got: %v", err)
+	}
+}
+
+func TestSubscriber_PushUsesOptions(t *testing.T) {
+	hub := newFakeHub(t)
+	feedServer := testServer(websubFeed(hub.server.URL), "application/atom+xml")
+	defer feedServer.Close()
+
+	sub, pushed := newTestSubscriber(t, "")
+	sub.Options = []Option{WithRedactedURLs(), WithLimits(Limits{MaxItems: 1})}
+	feedURL := feedServer.URL + "/?token=s3cret"
+	if err := sub.Subscribe(context.Background(), feedURL); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	waitVerified(t, hub)
+
+	relative := strings.Replace(websubFeed(hub.server.URL), `<feed xmlns="http://www.w3.org/2005/Atom">`,
+		`<feed xmlns="http://www.w3.org/2005/Atom" xml:base="http://topic.test/blog/">`, 1)
+	relative = strings.Replace(relative, `href="http://topic.test/entry"`, `href="entry"`, 1)
+	if status := hub.push(relative, ""); status/100 != 2 {
+		t.Fatalf("Expected push to be accepted, got %d", status)
+	}
+	items := <-pushed
+	if len(items) != 1 || items[0].Link != "http://topic.test/blog/entry" {
+		t.Fatalf("Expected the relative link resolved against xml:base, got %+v", items)
+	}
+	if items[0].RssURL != RedactURL(feedURL) {
+		t.Errorf("Expected the redacted feed URL, got %s", items[0].RssURL)
+	}
+
+	twoEntries := strings.Replace(websubFeed(hub.server.URL), "</feed>", "<entry><title>Second</title></entry></feed>", 1)
+	if status := hub.push(twoEntries, ""); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a push over the item limit, got %d", status)
+	}
+}
+
+func TestSubscriber_FailedResubscribeKeepsSubscription(t *testing.T) {
+	hub := newFakeHub(t)
+	feedServer := testServer(websubFeed(hub.server.URL), "application/atom+xml")
+	defer feedServer.Close()
+
+	sub, pushed := newTestSubscriber(t, "")
+	if err := sub.Subscribe(context.Background(), feedServer.URL); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	waitVerified(t, hub)
+
+	hub.mu.Lock()
+	hub.reject = true
+	hub.mu.Unlock()
+	if err := sub.Subscribe(context.Background(), feedServer.URL); err == nil {
+		t.Fatal("Expected the rejected resubscription to fail")
+	}
+	if !sub.Subscribed(feedServer.URL) {
+		t.Error("Expected the verified subscription to survive a failed resubscription")
+	}
+	if status := hub.push(websubFeed(hub.server.URL), ""); status/100 != 2 {
+		t.Fatalf("Expected pushes to still be accepted, got %d", status)
+	}
+	if items := <-pushed; len(items) != 1 {
+		t.Errorf("Expected 1 pushed item, got %d", len(items))
+	}
+}
+
+func TestSubscriber_DiscoverWithFeedAuth(t *testing.T) {
+	hub := newFakeHub(t)
+	feed := websubFeed(hub.server.URL)
+	feedServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0ken" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		_, _ = io.WriteString(w, feed)
+	}))
+	defer feedServer.Close()
+
+	sub, _ := newTestSubscriber(t, "")
+	if err := sub.Subscribe(context.Background(), feedServer.URL); err == nil {
+		t.Fatal("Expected discovery without credentials to fail")
+	}
+	sub.Options = []Option{WithFeedAuth(feedServer.URL, FeedAuth{Token: "t0ken"})}
+	if err := sub.Subscribe(context.Background(), feedServer.URL); err != nil {
+		t.Fatalf("Expected discovery with the feed's credentials, got: %v", err)
+	}
+	waitVerified(t, hub)
+}
+
+func TestDiscoverHub_LinkHeaderAndRSS(t *testing.T) {
+	header := http.Header{}
+	header.Add("Link", `<https://hub.test/>; rel="hub", <https://example.test/feed>; rel="self"`)
+	links := headerHubLinks(header)
+	if len(links.Hubs) != 1 || links.Hubs[0] != "https://hub.test/" || links.Self != "https://example.test/feed" {
+		t.Errorf("Unexpected links from header: %+v", links)
+	}
+
+	rss := `<?xml version="1.0"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+  <channel>
+    <title>RSS with hub</title>
+    <link>https://example.test/</link>
+    <atom:link rel="hub" href="https://pubsubhubbub.appspot.com/"/>
+    <atom:link rel="self" href="https://example.test/rss"/>
+  </channel>
+</rss>`
+	server := testServer(rss, "application/rss+xml")
+	defer server.Close()
+
+	links, err := DiscoverHub(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(links.Hubs) != 1 || links.Hubs[0] != "https://pubsubhubbub.appspot.com/" || links.Self != "https://example.test/rss" {
+		t.Errorf("Unexpected links from document: %+v", links)
+	}
+}
+
+func TestValidSignature(t *testing.T) {
+	body := []byte("payload")
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write(body)
+	sig := hex.EncodeToString(mac.Sum(nil))
+
+	if !validSignature("key", "sha256="+sig, body) {
+		t.Error("Expected valid sha256 signature to pass")
+	}
+	for _, header := range []string{"", "sha256=" + sig[:10], "md5=" + sig, "sha256=zz"} {
+		if validSignature("key", header, body) {
+			t.Errorf("Expected signature %q to be rejected", header)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package rssreader
+
+import (
+	"cmp"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// FeedInfo describes the aggregated feed produced by WriteRSS, WriteAtom and
+// WriteJSONFeed.
+type FeedInfo struct {
+	Title       string
+	Link        string // URL of the website the feed belongs to
+	FeedURL     string // URL the feed itself is published at
+	Description string
+	Author      string    // defaults to Title
+	Updated     time.Time // defaults to the newest item's PublishDate
+}
+
+// updated returns the feed's last-modified time, falling back to the newest
+// item and finally to the current time.
+func (info FeedInfo) updated(items []RssItem) time.Time {
+	if !info.Updated.IsZero() {
+		return info.Updated
+	}
+	var newest time.Time
+	for _, item := range items {
+		if item.PublishDate.After(newest) {
+			newest = item.PublishDate
+		}
+	}
+	if newest.IsZero() {
+		return time.Now().UTC()
+	}
+	return newest
+}
+
+// id returns a stable identifier for the feed as required by Atom.
+func (info FeedInfo) id() string {
+	switch {
+	case info.FeedURL != "":
+		return info.FeedURL
+	case info.Link != "":
+		return info.Link
+	default:
+		return "urn:rssreader:" + info.Title
+	}
+}
+
+// itemID returns a stable identifier for an item, preferring its link.
+func itemID(item RssItem) string {
+	if item.Link != "" {
+		return item.Link
+	}
+	return fmt.Sprintf("urn:rssreader:%s:%d:%s", item.RssURL, item.PublishDate.Unix(), item.Title)
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr,omitempty"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string       `xml:"title"`
+	Link          string       `xml:"link"`
+	Description   string       `xml:"description"`
+	SelfLink      *rssAtomLink `xml:"atom:link,omitempty"`
+	LastBuildDate string       `xml:"lastBuildDate"`
+	Items         []rssItem    `xml:"item"`
+}
+
+type rssAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title,omitempty"`
+	Link        string        `xml:"link,omitempty"`
+	Description string        `xml:"description,omitempty"`
+	Enclosure   *rssEnclosure `xml:"enclosure,omitempty"`
+	PubDate     string        `xml:"pubDate,omitempty"`
+	GUID        *rssGUID      `xml:"guid,omitempty"`
+	Source      *rssSource    `xml:"source,omitempty"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssSource struct {
+	URL   string `xml:"url,attr"`
+	Title string `xml:",chardata"`
+}
+
+// WriteRSS serializes items as an RSS 2.0 document. Each item carries a
+// <source> element pointing back at the feed it was aggregated from.
+func WriteRSS(w io.Writer, info FeedInfo, items []RssItem) error {
+	doc := rssDocument{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         info.Title,
+			Link:          info.Link,
+			Description:   info.Description,
+			LastBuildDate: info.updated(items).Format(time.RFC1123Z),
+			Items:         make([]rssItem, 0, len(items)),
+		},
+	}
+	if info.FeedURL != "" {
+		doc.Atom = "http://www.w3.org/2005/Atom"
+		doc.Channel.SelfLink = &rssAtomLink{Href: info.FeedURL, Rel: "self", Type: "application/rss+xml"}
+	}
+
+	for _, item := range items {
+		out := rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			GUID:        &rssGUID{IsPermaLink: item.Link != "", Value: itemID(item)},
+		}
+		if !item.PublishDate.IsZero() {
+			out.PubDate = item.PublishDate.Format(time.RFC1123Z)
+		}
+		// RSS allows a single enclosure per item
+		if len(item.Enclosures) > 0 {
+			enclosure := item.Enclosures[0]
+			out.Enclosure = &rssEnclosure{URL: enclosure.URL, Length: enclosure.Length, Type: enclosure.Type}
+		}
+		if item.RssURL != "" {
+			out.Source = &rssSource{URL: item.RssURL, Title: item.Source}
+		}
+		doc.Channel.Items = append(doc.Channel.Items, out)
+	}
+
+	return writeXML(w, doc)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomPerson  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length string `xml:"length,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     atomText    `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published,omitempty"`
+	Links     []atomLink  `xml:"link"`
+	Summary   *atomText   `xml:"summary,omitempty"`
+	Content   *atomText   `xml:"content,omitempty"`
+	Source    *atomSource `xml:"source,omitempty"`
+}
+
+type atomSource struct {
+	ID    string     `xml:"id"`
+	Title string     `xml:"title"`
+	Links []atomLink `xml:"link"`
+}
+
+// WriteAtom serializes items as an Atom 1.0 document. Each entry carries an
+// <atom:source> element describing the feed it was aggregated from.
+func WriteAtom(w io.Writer, info FeedInfo, items []RssItem) error {
+	updated := info.updated(items)
+	author := info.Author
+	if author == "" {
+		author = info.Title
+	}
+
+	doc := atomFeed{
+		ID:      info.id(),
+		Title:   info.Title,
+		Updated: updated.Format(time.RFC3339),
+		Author:  atomPerson{Name: author},
+		Entries: make([]atomEntry, 0, len(items)),
+	}
+	if info.Link != "" {
+		doc.Links = append(doc.Links, atomLink{Href: info.Link, Rel: "alternate"})
+	}
+	if info.FeedURL != "" {
+		doc.Links = append(doc.Links, atomLink{Href: info.FeedURL, Rel: "self"})
+	}
+
+	for _, item := range items {
+		entry := atomEntry{
+			ID:      itemID(item),
+			Title:   atomText{Type: "text", Value: item.Title},
+			Updated: updated.Format(time.RFC3339),
+		}
+		if !item.PublishDate.IsZero() {
+			entry.Updated = item.PublishDate.Format(time.RFC3339)
+			entry.Published = entry.Updated
+		}
+		if !item.Updated.IsZero() {
+			entry.Updated = item.Updated.Format(time.RFC3339)
+		}
+		if item.Link != "" {
+			entry.Links = []atomLink{{Href: item.Link, Rel: "alternate"}}
+		}
+		for _, enclosure := range item.Enclosures {
+			link := atomLink{Href: enclosure.URL, Rel: "enclosure", Type: enclosure.Type}
+			if enclosure.Length > 0 {
+				link.Length = strconv.FormatInt(enclosure.Length, 10)
+			}
+			entry.Links = append(entry.Links, link)
+		}
+		if item.Description != "" {
+			entry.Summary = &atomText{Type: "html", Value: item.Description}
+		}
+		if content := cmp.Or(item.FullContent, item.Content); content != "" {
+			entry.Content = &atomText{Type: "html", Value: content}
+		}
+		if item.RssURL != "" {
+			entry.Source = &atomSource{
+				ID:    item.RssURL,
+				Title: item.Source,
+				Links: []atomLink{{Href: item.RssURL, Rel: "self"}},
+			}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+
+	return writeXML(w, doc)
+}
+
+func writeXML(w io.Writer, doc any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("encoding error: %w", err)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("encoding error: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("encoding error: %w", err)
+	}
+	return nil
+}
+
+type jsonFeed struct {
+	Version     string          `json:"version"`
+	Title       string          `json:"title"`
+	HomePageURL string          `json:"home_page_url,omitempty"`
+	FeedURL     string          `json:"feed_url,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Authors     []jsonFeedActor `json:"authors,omitempty"`
+	Items       []jsonFeedItem  `json:"items"`
+}
+
+type jsonFeedActor struct {
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+type jsonFeedItem struct {
+	ID            string               `json:"id"`
+	URL           string               `json:"url,omitempty"`
+	Title         string               `json:"title,omitempty"`
+	ContentHTML   string               `json:"content_html"`
+	Summary       string               `json:"summary,omitempty"`
+	DatePublished string               `json:"date_published,omitempty"`
+	DateModified  string               `json:"date_modified,omitempty"`
+	Authors       []jsonFeedActor      `json:"authors,omitempty"`
+	Attachments   []jsonFeedAttachment `json:"attachments,omitempty"`
+	Source        *jsonFeedSource      `json:"_source,omitempty"`
+}
+
+type jsonFeedAttachment struct {
+	URL         string `json:"url"`
+	MimeType    string `json:"mime_type"`
+	SizeInBytes int64  `json:"size_in_bytes,omitempty"`
+}
+
+// jsonFeedSource is a custom extension object (JSON Feed reserves keys
+// starting with an underscore for extensions) naming the original feed.
+type jsonFeedSource struct {
+	Title   string `json:"title,omitempty"`
+	FeedURL string `json:"feed_url"`
+}
+
+// WriteJSONFeed serializes items as a JSON Feed 1.1 document. The source feed
+// of each item is recorded as its author and in a "_source" extension object.
+func WriteJSONFeed(w io.Writer, info FeedInfo, items []RssItem) error {
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       info.Title,
+		HomePageURL: info.Link,
+		FeedURL:     info.FeedURL,
+		Description: info.Description,
+		Items:       make([]jsonFeedItem, 0, len(items)),
+	}
+	if info.Author != "" {
+		doc.Authors = []jsonFeedActor{{Name: info.Author}}
+	}
+
+	for _, item := range items {
+		out := jsonFeedItem{
+			ID:          itemID(item),
+			URL:         item.Link,
+			Title:       item.Title,
+			ContentHTML: item.Description,
+			Summary:     item.Description,
+		}
+		if content := cmp.Or(item.FullContent, item.Content); content != "" {
+			out.ContentHTML = content
+		}
+		if !item.PublishDate.IsZero() {
+			out.DatePublished = item.PublishDate.Format(time.RFC3339)
+		}
+		if !item.Updated.IsZero() {
+			out.DateModified = item.Updated.Format(time.RFC3339)
+		}
+		for _, enclosure := range item.Enclosures {
+			// url and mime_type are required, so guess the most generic type
+			if enclosure.URL == "" {
+				continue
+			}
+			out.Attachments = append(out.Attachments, jsonFeedAttachment{
+				URL:         enclosure.URL,
+				MimeType:    cmp.Or(enclosure.Type, "application/octet-stream"),
+				SizeInBytes: enclosure.Length,
+			})
+		}
+		if item.Source != "" {
+			out.Authors = []jsonFeedActor{{Name: item.Source}}
+		}
+		if item.RssURL != "" {
+			out.Source = &jsonFeedSource{Title: item.Source, FeedURL: item.RssURL}
+		}
+		doc.Items = append(doc.Items, out)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("encoding error: %w", err)
+	}
+	return nil
+}
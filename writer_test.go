@@ -0,0 +1,270 @@
+package rssreader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writerTestItems returns items aggregated from two sources, including
+// characters that need escaping in every output format.
+func writerTestItems() []RssItem {
+	return []RssItem{
+		{
+			Title:       "Fish & Chips <recipe>",
+			Source:      "Food Blog",
+			SourceURL:   "http://food.example.com/feed",
+			Link:        "http://food.example.com/fish-and-chips",
+			PublishDate: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+			Description: "<p>Crispy &amp; golden</p>",
+			RssURL:      "http://food.example.com/feed",
+		},
+		{
+			Title:       "Release notes",
+			Source:      "Dev Blog",
+			SourceURL:   "http://dev.example.com/rss",
+			Link:        "http://dev.example.com/releases/1",
+			PublishDate: time.Date(2024, 3, 2, 8, 30, 0, 0, time.UTC),
+			Description: "Version 1 is \"out\"",
+			RssURL:      "http://dev.example.com/rss",
+		},
+	}
+}
+
+func writerTestInfo() FeedInfo {
+	return FeedInfo{
+		Title:       "Planet Example",
+		Link:        "http://planet.example.com/",
+		FeedURL:     "http://planet.example.com/feed",
+		Description: "Everything from our friends",
+	}
+}
+
+// roundTrip serves the written document and parses it back through Parse.
+func roundTrip(t *testing.T, write func(io.Writer, FeedInfo, []RssItem) error, contentType string) ([]RssItem, string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := write(&buf, writerTestInfo(), writerTestItems()); err != nil {
+		t.Fatalf("Failed to write feed: %v", err)
+	}
+
+	server := testServer(buf.String(), contentType)
+	defer server.Close()
+
+	items, err := Parse(context.Background(), []string{server.URL})
+	if err != nil {
+		t.Fatalf("Expected written feed to parse, got: %v\n%s", err, buf.String())
+	}
+	return items, buf.String()
+}
+
+func assertRoundTripItems(t *testing.T, items []RssItem) {
+	t.Helper()
+
+	want := writerTestItems()
+	if len(items) != len(want) {
+		t.Fatalf("Expected %d items, got %d", len(want), len(items))
+	}
+	for i := range want {
+		if items[i].Title != want[i].Title {
+			t.Errorf("Item %d: expected title %q, got %q", i, want[i].Title, items[i].Title)
+		}
+		if items[i].Link != want[i].Link {
+			t.Errorf("Item %d: expected link %q, got %q", i, want[i].Link, items[i].Link)
+		}
+		if items[i].Description != want[i].Description {
+			t.Errorf("Item %d: expected description %q, got %q", i, want[i].Description, items[i].Description)
+		}
+		if !items[i].PublishDate.Equal(want[i].PublishDate) {
+			t.Errorf("Item %d: expected publish date %v, got %v", i, want[i].PublishDate, items[i].PublishDate)
+		}
+		if items[i].Source != "Planet Example" {
+			t.Errorf("Item %d: expected source 'Planet Example', got %q", i, items[i].Source)
+		}
+	}
+}
+
+func TestWriteRSS_RoundTrip(t *testing.T) {
+	items, doc := roundTrip(t, WriteRSS, "application/rss+xml")
+	assertRoundTripItems(t, items)
+
+	if !strings.Contains(doc, `<rss version="2.0"`) {
+		t.Errorf("Expected an RSS 2.0 root element, got:\n%s", doc)
+	}
+	if !strings.Contains(doc, `<source url="http://dev.example.com/rss">Dev Blog</source>`) {
+		t.Errorf("Expected source attribution for Dev Blog, got:\n%s", doc)
+	}
+	if !strings.Contains(doc, `<atom:link href="http://planet.example.com/feed" rel="self"`) {
+		t.Errorf("Expected atom:link self reference, got:\n%s", doc)
+	}
+}
+
+func TestWriteAtom_RoundTrip(t *testing.T) {
+	items, doc := roundTrip(t, WriteAtom, "application/atom+xml")
+	assertRoundTripItems(t, items)
+
+	// Decode the entry sources to check attribution survives serialization
+	var feed atomFeed
+	if err := xml.Unmarshal([]byte(doc), &feed); err != nil {
+		t.Fatalf("Failed to decode Atom output: %v", err)
+	}
+	if feed.ID != "http://planet.example.com/feed" {
+		t.Errorf("Expected feed id to be the feed URL, got %q", feed.ID)
+	}
+	if feed.Author.Name != "Planet Example" {
+		t.Errorf("Expected feed author to default to the title, got %q", feed.Author.Name)
+	}
+	if len(feed.Entries) != 2 || feed.Entries[0].Source == nil {
+		t.Fatalf("Expected entries with sources, got %+v", feed.Entries)
+	}
+	if feed.Entries[0].Source.Title != "Food Blog" || feed.Entries[0].Source.ID != "http://food.example.com/feed" {
+		t.Errorf("Unexpected source for first entry: %+v", feed.Entries[0].Source)
+	}
+}
+
+func TestWriteJSONFeed_RoundTrip(t *testing.T) {
+	items, doc := roundTrip(t, WriteJSONFeed, "application/feed+json")
+	assertRoundTripItems(t, items)
+
+	var feed jsonFeed
+	if err := json.Unmarshal([]byte(doc), &feed); err != nil {
+		t.Fatalf("Failed to decode JSON Feed output: %v", err)
+	}
+	if feed.Version != "https://jsonfeed.org/version/1.1" {
+		t.Errorf("Expected JSON Feed 1.1 version, got %q", feed.Version)
+	}
+	if feed.Items[1].Source == nil || feed.Items[1].Source.FeedURL != "http://dev.example.com/rss" {
+		t.Errorf("Expected _source extension for second item, got %+v", feed.Items[1].Source)
+	}
+	if len(feed.Items[1].Authors) != 1 || feed.Items[1].Authors[0].Name != "Dev Blog" {
+		t.Errorf("Expected source feed as item author, got %+v", feed.Items[1].Authors)
+	}
+	if feed.Items[1].Authors[0].URL != "" {
+		t.Errorf("Expected no author url, got %q", feed.Items[1].Authors[0].URL)
+	}
+}
+
+func TestWriteJSONFeed_AttachmentsWithoutType(t *testing.T) {
+	items := []RssItem{{
+		Title: "Episode",
+		Link:  "http://example.com/episode",
+		Enclosures: []Enclosure{
+			{URL: "http://example.com/episode.bin"},
+			{Type: "audio/mpeg"},
+		},
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteJSONFeed(&buf, FeedInfo{Title: "Podcast"}, items); err != nil {
+		t.Fatalf("WriteJSONFeed failed: %v", err)
+	}
+	var feed jsonFeed
+	if err := json.Unmarshal(buf.Bytes(), &feed); err != nil {
+		t.Fatalf("Failed to decode JSON Feed output: %v", err)
+	}
+	attachments := feed.Items[0].Attachments
+	if len(attachments) != 1 {
+		t.Fatalf("Expected the attachment without url to be skipped, got %+v", attachments)
+	}
+	if attachments[0].MimeType != "application/octet-stream" {
+		t.Errorf("Expected mime_type application/octet-stream, got %q", attachments[0].MimeType)
+	}
+}
+
+func TestWriters_EmptyItems(t *testing.T) {
+	writers := map[string]func(io.Writer, FeedInfo, []RssItem) error{
+		"rss":      WriteRSS,
+		"atom":     WriteAtom,
+		"jsonfeed": WriteJSONFeed,
+	}
+
+	for name, write := range writers {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := write(&buf, FeedInfo{Title: "Empty"}, nil); err != nil {
+				t.Fatalf("Failed to write empty feed: %v", err)
+			}
+
+			server := testServer(buf.String(), "")
+			defer server.Close()
+
+			items, err := Parse(context.Background(), []string{server.URL})
+			if err != nil {
+				t.Fatalf("Expected empty feed to parse, got: %v\n%s", err, buf.String())
+			}
+			if len(items) != 0 {
+				t.Errorf("Expected no items, got %d", len(items))
+			}
+		})
+	}
+}
+
+func TestWriteAtom_ItemWithoutDateOrLink(t *testing.T) {
+	var buf bytes.Buffer
+	info := FeedInfo{Title: "Planet", Updated: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	item := RssItem{Title: "Undated", RssURL: "http://example.com/feed"}
+	if err := WriteAtom(&buf, info, []RssItem{item}); err != nil {
+		t.Fatalf("Failed to write feed: %v", err)
+	}
+
+	doc := buf.String()
+	if !strings.Contains(doc, "<updated>2024-01-01T00:00:00Z</updated>") {
+		t.Errorf("Expected undated entry to fall back to feed updated time, got:\n%s", doc)
+	}
+	if !strings.Contains(doc, "<id>urn:rssreader:http://example.com/feed:") {
+		t.Errorf("Expected generated URN id for entry without link, got:\n%s", doc)
+	}
+}
+
+func TestWriters_ContentAndEnclosures(t *testing.T) {
+	item := RssItem{
+		Title:       "Episode 1",
+		Link:        "http://pod.example.com/1",
+		PublishDate: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Updated:     time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC),
+		Description: "Short notes",
+		Content:     "<p>Full show notes</p>",
+		Enclosures:  []Enclosure{{URL: "http://pod.example.com/1.mp3", Type: "audio/mpeg", Length: 1234}},
+		RssURL:      "http://pod.example.com/feed",
+	}
+	writers := map[string]func(io.Writer, FeedInfo, []RssItem) error{
+		"rss":      WriteRSS,
+		"atom":     WriteAtom,
+		"jsonfeed": WriteJSONFeed,
+	}
+	for name, write := range writers {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := write(&buf, writerTestInfo(), []RssItem{item}); err != nil {
+				t.Fatalf("Failed to write feed: %v", err)
+			}
+			items, err := ParseReader(context.Background(), &buf, "http://planet.example.com/feed")
+			if err != nil {
+				t.Fatalf("Expected written feed to parse, got: %v", err)
+			}
+			if len(items) != 1 {
+				t.Fatalf("Expected 1 item, got %d", len(items))
+			}
+			got := items[0]
+			if len(got.Enclosures) != 1 || got.Enclosures[0] != item.Enclosures[0] {
+				t.Errorf("Expected enclosure %+v, got %+v", item.Enclosures, got.Enclosures)
+			}
+			// RSS has no separate content or modification date
+			if name == "rss" {
+				return
+			}
+			if got.Content != item.Content {
+				t.Errorf("Expected content %q, got %q", item.Content, got.Content)
+			}
+			if !got.Updated.Equal(item.Updated) {
+				t.Errorf("Expected updated %v, got %v", item.Updated, got.Updated)
+			}
+		})
+	}
+}